@@ -1,27 +1,39 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/neverprepared/shell-profile-manager/internal/cli"
+	"github.com/neverprepared/shell-profile-manager/internal/commands"
 	"github.com/neverprepared/shell-profile-manager/internal/config"
 )
 
 func main() {
-	// Load configuration (uses defaults if config file doesn't exist)
-	cfg, err := config.LoadConfig()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
-		fmt.Fprintf(os.Stderr, "Run 'shell-profiler init' to set custom paths\n")
-		os.Exit(1)
+	// Load configuration (uses defaults if config file doesn't exist). The prompt
+	// segment runs on every shell prompt and needs no configuration, so it skips this.
+	var profilesDir string
+	if len(os.Args) < 2 || os.Args[1] != "prompt" {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Run 'shell-profiler init' to set custom paths\n")
+			os.Exit(1)
+		}
+		profilesDir = cfg.ProfilesDir
 	}
 
 	// Create CLI instance
-	app := cli.NewApp(cfg.ProfilesDir)
+	app := cli.NewApp(profilesDir)
 
 	// Run the CLI
 	if err := app.Run(os.Args[1:]); err != nil {
+		// Pass through the exit status of commands run inside a profile
+		var exitErr *commands.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/neverprepared/shell-profile-manager/internal/commands"
@@ -27,7 +28,7 @@ func NewApp(profilesDir string) *App {
 func (a *App) requireDirenv() error {
 	_, err := exec.LookPath("direnv")
 	if err != nil {
-		return fmt.Errorf("direnv is required but not found in PATH\n\n  Install direnv:\n    brew install direnv    # macOS/Linux (Homebrew)\n    apt install direnv     # Debian/Ubuntu\n\n  Then add the shell hook to your shell config:\n    eval \"$(direnv hook bash)\"   # ~/.bashrc\n    eval \"$(direnv hook zsh)\"    # ~/.zshrc\n\n  See https://direnv.net/ for more details")
+		return fmt.Errorf("direnv is required but not found in PATH\n\n  Install direnv:\n    brew install direnv    # macOS/Linux (Homebrew)\n    apt install direnv     # Debian/Ubuntu\n\n  Then add the shell hook to your shell config:\n    eval \"$(direnv hook bash)\"   # ~/.bashrc\n    eval \"$(direnv hook zsh)\"    # ~/.zshrc\n\n  See https://direnv.net/ for more details\n\n  Or load a profile without direnv:\n    eval \"$(shell-profiler activate <profile>)\"")
 	}
 	return nil
 }
@@ -43,8 +44,10 @@ func (a *App) Run(args []string) error {
 
 	// Commands that require direnv to be installed
 	switch command {
-	case "help", "--help", "-h", "init":
-		// These commands don't require direnv
+	case "help", "--help", "-h", "init", "doctor", "exec", "shell", "activate", "hook", "prompt":
+		// These commands don't require direnv (doctor reports it missing,
+		// exec, shell and activate load the profile environment themselves,
+		// hook and prompt run on every shell start or prompt)
 	default:
 		if err := a.requireDirenv(); err != nil {
 			return err
@@ -64,8 +67,40 @@ func (a *App) Run(args []string) error {
 		return a.handleSelect(args)
 	case "delete", "remove", "rm":
 		return a.handleDelete(args)
+	case "rename", "mv":
+		return a.handleRename(args)
+	case "clone", "cp":
+		return a.handleClone(args)
 	case "restore":
 		return a.handleRestore(args)
+	case "snapshot", "snapshots":
+		return a.handleSnapshot(args)
+	case "trash":
+		return a.handleTrash(args)
+	case "archive":
+		return a.handleArchive(args, false)
+	case "unarchive":
+		return a.handleArchive(args, true)
+	case "export":
+		return a.handleExport(args)
+	case "import":
+		return a.handleImport(args)
+	case "doctor":
+		return a.handleDoctor(args)
+	case "exec", "run":
+		return a.handleExec(args)
+	case "shell", "sh":
+		return a.handleShell(args)
+	case "activate":
+		return a.handleActivate(args)
+	case "hook":
+		return a.handleHook(args)
+	case "prompt":
+		return a.handlePrompt(args)
+	case "template", "templates":
+		return a.handleTemplate(args)
+	case "tools", "tool":
+		return a.handleTools(args)
 	case "info", "current", "show":
 		return a.handleInfo(args)
 	case "status":
@@ -101,6 +136,11 @@ func (a *App) handleInit(args []string) error {
 				opts.ProfilesDir = args[i+1]
 				i++
 			}
+		case "--templates-dir":
+			if i+1 < len(args) {
+				opts.TemplatesDir = args[i+1]
+				i++
+			}
 		case "--interactive", "-i":
 			opts.Interactive = true
 		}
@@ -163,6 +203,45 @@ func (a *App) handleCreate(args []string) error {
 				i++
 				hasNonInteractiveFlags = true
 			}
+		case "--tools":
+			if i+1 < len(args) {
+				names, err := commands.ParseToolList(args[i+1])
+				if err != nil {
+					return err
+				}
+				opts.Tools = names
+				i++
+				hasNonInteractiveFlags = true
+			}
+		case "-d", "--description":
+			if i+1 < len(args) {
+				opts.Description = args[i+1]
+				i++
+				hasNonInteractiveFlags = true
+			}
+		case "--tags":
+			if i+1 < len(args) {
+				for _, tag := range strings.Split(args[i+1], ",") {
+					if tag = strings.TrimSpace(tag); tag != "" {
+						opts.Tags = append(opts.Tags, tag)
+					}
+				}
+				i++
+				hasNonInteractiveFlags = true
+			}
+		case "--var":
+			if i+1 < len(args) {
+				name, value, ok := strings.Cut(args[i+1], "=")
+				if !ok || name == "" {
+					return fmt.Errorf("invalid --var value: %s (must be: name=value)", args[i+1])
+				}
+				if opts.Vars == nil {
+					opts.Vars = make(map[string]string)
+				}
+				opts.Vars[name] = value
+				i++
+				hasNonInteractiveFlags = true
+			}
 		default:
 			if opts.ProfileName == "" && !strings.HasPrefix(arg, "-") {
 				opts.ProfileName = arg
@@ -227,6 +306,9 @@ func (a *App) handleList(args []string) error {
 			opts.Interactive = true
 		case "--no-interactive":
 			opts.Interactive = false
+		case "--archived":
+			opts.Archived = true
+			opts.Interactive = false
 		case "-h", "--help":
 			a.showListHelp()
 			return nil
@@ -250,6 +332,12 @@ func (a *App) handleDelete(args []string) error {
 			opts.Force = true
 		case "--dry-run":
 			opts.DryRun = true
+		case "--no-snapshot":
+			opts.NoSnapshot = true
+		case "--permanent":
+			opts.Permanent = true
+		case "--secure":
+			opts.Secure = true
 		case "--no-interactive":
 			// This is handled in DeleteProfile - if profile name is provided, interactive is skipped
 		default:
@@ -262,8 +350,346 @@ func (a *App) handleDelete(args []string) error {
 	return commands.DeleteProfile(a.profilesDir, opts)
 }
 
+func (a *App) handleRename(args []string) error {
+	opts := commands.RenameOptions{}
+
+	// Parse arguments
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-h", "--help":
+			a.showRenameHelp()
+			return nil
+		case "-f", "--force":
+			opts.Force = true
+		case "--dry-run":
+			opts.DryRun = true
+		default:
+			if strings.HasPrefix(arg, "-") {
+				continue
+			}
+			if opts.OldName == "" {
+				opts.OldName = arg
+			} else if opts.NewName == "" {
+				opts.NewName = arg
+			}
+		}
+	}
+
+	if opts.OldName == "" || opts.NewName == "" {
+		a.showRenameHelp()
+		return fmt.Errorf("both the current and the new profile name are required")
+	}
+
+	return commands.RenameProfile(a.profilesDir, opts)
+}
+
+func (a *App) handleClone(args []string) error {
+	opts := commands.CloneOptions{}
+
+	// Parse arguments
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-h", "--help":
+			a.showCloneHelp()
+			return nil
+		case "-f", "--force":
+			opts.Force = true
+		case "--dry-run":
+			opts.DryRun = true
+		case "--with-secrets":
+			opts.WithSecrets = true
+		case "--git-name":
+			if i+1 < len(args) {
+				opts.GitName = args[i+1]
+				i++
+			}
+		case "--git-email":
+			if i+1 < len(args) {
+				opts.GitEmail = args[i+1]
+				i++
+			}
+		default:
+			if strings.HasPrefix(arg, "-") {
+				continue
+			}
+			if opts.SourceName == "" {
+				opts.SourceName = arg
+			} else if opts.TargetName == "" {
+				opts.TargetName = arg
+			}
+		}
+	}
+
+	if opts.SourceName == "" || opts.TargetName == "" {
+		a.showCloneHelp()
+		return fmt.Errorf("both the source and the new profile name are required")
+	}
+
+	return commands.CloneProfile(a.profilesDir, opts)
+}
+
 func (a *App) handleRestore(args []string) error {
-	return fmt.Errorf("restore command is not yet implemented in Go")
+	opts := commands.RestoreOptions{}
+
+	// Parse arguments
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-h", "--help":
+			a.showRestoreHelp()
+			return nil
+		case "-f", "--force":
+			opts.Force = true
+		case "--dry-run":
+			opts.DryRun = true
+		case "--file":
+			if i+1 < len(args) {
+				opts.File = args[i+1]
+				i++
+			}
+		case "--backup-date":
+			if i+1 < len(args) {
+				opts.BackupDate = args[i+1]
+				i++
+			}
+		default:
+			if opts.ProfileName == "" && !strings.HasPrefix(arg, "-") {
+				opts.ProfileName = arg
+			}
+		}
+	}
+
+	return commands.RestoreProfile(a.profilesDir, opts)
+}
+
+func (a *App) handleTrash(args []string) error {
+	if len(args) == 0 {
+		a.showTrashHelp()
+		return nil
+	}
+
+	subcommand := args[0]
+	args = args[1:]
+
+	opts := commands.TrashOptions{}
+
+	// Parse common options
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "--as":
+			if i+1 < len(args) {
+				opts.NewName = args[i+1]
+				i++
+			}
+		case "--older-than":
+			if i+1 < len(args) {
+				age, err := commands.ParseAge(args[i+1])
+				if err != nil {
+					return err
+				}
+				opts.OlderThan = age
+				i++
+			}
+		case "-f", "--force":
+			opts.Force = true
+		case "--dry-run":
+			opts.DryRun = true
+		case "-h", "--help":
+			a.showTrashHelp()
+			return nil
+		default:
+			if opts.ProfileName == "" && !strings.HasPrefix(arg, "-") {
+				opts.ProfileName = arg
+			}
+		}
+	}
+
+	switch subcommand {
+	case "list", "ls":
+		return commands.ListTrash(opts)
+	case "restore":
+		return commands.RestoreFromTrash(a.profilesDir, opts)
+	case "empty":
+		return commands.EmptyTrash(opts)
+	case "help", "-h", "--help":
+		a.showTrashHelp()
+		return nil
+	default:
+		a.showTrashHelp()
+		return fmt.Errorf("unknown trash command: %s", subcommand)
+	}
+}
+
+func (a *App) handleArchive(args []string, unarchive bool) error {
+	opts := commands.ArchiveOptions{}
+
+	// Parse arguments
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-h", "--help":
+			a.showArchiveHelp()
+			return nil
+		case "-f", "--force":
+			opts.Force = true
+		case "--dry-run":
+			opts.DryRun = true
+		case "--exclude-secrets":
+			opts.ExcludeSecrets = true
+		case "--encrypt-secrets":
+			opts.EncryptSecrets = true
+		case "--as":
+			if i+1 < len(args) {
+				opts.NewName = args[i+1]
+				i++
+			}
+		default:
+			if opts.ProfileName == "" && !strings.HasPrefix(arg, "-") {
+				opts.ProfileName = arg
+			}
+		}
+	}
+
+	if unarchive {
+		return commands.UnarchiveProfile(a.profilesDir, opts)
+	}
+	return commands.ArchiveProfile(a.profilesDir, opts)
+}
+
+func (a *App) handleExport(args []string) error {
+	opts := commands.ExportOptions{}
+
+	// Parse arguments
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-h", "--help":
+			a.showExportHelp()
+			return nil
+		case "-o", "--output":
+			if i+1 < len(args) {
+				opts.Output = args[i+1]
+				i++
+			}
+		case "--with-secrets":
+			opts.WithSecrets = true
+		case "-f", "--force":
+			opts.Force = true
+		default:
+			if opts.ProfileName == "" && !strings.HasPrefix(arg, "-") {
+				opts.ProfileName = arg
+			}
+		}
+	}
+
+	return commands.ExportProfile(a.profilesDir, opts)
+}
+
+func (a *App) handleImport(args []string) error {
+	opts := commands.ImportOptions{}
+
+	// Parse arguments
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-h", "--help":
+			a.showExportHelp()
+			return nil
+		case "--as":
+			if i+1 < len(args) {
+				opts.NewName = args[i+1]
+				i++
+			}
+		case "-f", "--force":
+			opts.Force = true
+		case "--dry-run":
+			opts.DryRun = true
+		default:
+			if opts.File == "" && !strings.HasPrefix(arg, "-") {
+				opts.File = arg
+			}
+		}
+	}
+
+	if opts.File == "" {
+		a.showExportHelp()
+		return fmt.Errorf("bundle file is required")
+	}
+
+	return commands.ImportProfile(a.profilesDir, opts)
+}
+
+func (a *App) handleSnapshot(args []string) error {
+	if len(args) == 0 {
+		a.showSnapshotHelp()
+		return nil
+	}
+
+	subcommand := args[0]
+	args = args[1:]
+
+	opts := commands.SnapshotOptions{
+		Keep:     -1,
+		KeepDays: -1,
+	}
+
+	// Parse common options
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "--reason":
+			if i+1 < len(args) {
+				opts.Reason = args[i+1]
+				i++
+			}
+		case "--keep":
+			if i+1 < len(args) {
+				n, err := strconv.Atoi(args[i+1])
+				if err != nil || n < 0 {
+					return fmt.Errorf("invalid --keep value: %s", args[i+1])
+				}
+				opts.Keep = n
+				i++
+			}
+		case "--keep-days":
+			if i+1 < len(args) {
+				n, err := strconv.Atoi(args[i+1])
+				if err != nil || n < 0 {
+					return fmt.Errorf("invalid --keep-days value: %s", args[i+1])
+				}
+				opts.KeepDays = n
+				i++
+			}
+		case "--dry-run":
+			opts.DryRun = true
+		case "-h", "--help":
+			a.showSnapshotHelp()
+			return nil
+		default:
+			if opts.ProfileName == "" && !strings.HasPrefix(arg, "-") {
+				opts.ProfileName = arg
+			}
+		}
+	}
+
+	switch subcommand {
+	case "list", "ls":
+		return commands.ListSnapshots(a.profilesDir, opts)
+	case "create", "new":
+		return commands.CreateSnapshot(a.profilesDir, opts)
+	case "prune":
+		return commands.PruneSnapshots(a.profilesDir, opts)
+	case "help", "-h", "--help":
+		a.showSnapshotHelp()
+		return nil
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown snapshot command: %s\n\n", subcommand)
+		a.showSnapshotHelp()
+		return fmt.Errorf("unknown snapshot command: %s", subcommand)
+	}
 }
 
 func (a *App) handleSync(args []string) error {
@@ -282,6 +708,7 @@ func (a *App) handleSync(args []string) error {
 	}
 
 	opts := commands.GitOptions{}
+	all := false
 
 	// Parse common options
 	for i := 0; i < len(args); i++ {
@@ -294,6 +721,8 @@ func (a *App) handleSync(args []string) error {
 				opts.Remote = args[i+1]
 				i++
 			}
+		case "--all":
+			all = true
 		case "-h", "--help":
 			a.showSyncHelp()
 			return nil
@@ -314,7 +743,8 @@ func (a *App) handleSync(args []string) error {
 	}
 
 	// Status command can work without profile name (shows all profiles)
-	if syncCommand == "status" && opts.ProfileName == "" {
+	if syncCommand == "status" && (opts.ProfileName == "" || all) {
+		opts.ProfileName = ""
 		return commands.GetGitStatus(a.profilesDir, opts)
 	}
 
@@ -413,6 +843,332 @@ func (a *App) handleSelect(args []string) error {
 	return commands.SelectProfile(a.profilesDir, opts)
 }
 
+func (a *App) handleDoctor(args []string) error {
+	opts := commands.DoctorOptions{}
+
+	// Parse arguments
+	for _, arg := range args {
+		switch arg {
+		case "-h", "--help":
+			a.showDoctorHelp()
+			return nil
+		case "--fix":
+			opts.Fix = true
+		default:
+			if opts.ProfileName == "" && !strings.HasPrefix(arg, "-") {
+				opts.ProfileName = arg
+			}
+		}
+	}
+
+	return commands.Doctor(a.profilesDir, opts)
+}
+
+func (a *App) handleExec(args []string) error {
+	opts := commands.ExecOptions{}
+
+	// Parse arguments up to the command; everything after "--" (or after the
+	// first argument following the profile name) belongs to the command
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			opts.Command = args[i+1:]
+			break
+		}
+		switch arg {
+		case "-h", "--help":
+			a.showExecHelp()
+			return nil
+		case "-C", "--cwd":
+			if i+1 < len(args) {
+				opts.Dir = args[i+1]
+				i++
+			}
+		default:
+			if strings.HasPrefix(arg, "-") {
+				continue
+			}
+			if opts.ProfileName == "" {
+				opts.ProfileName = arg
+			} else {
+				opts.Command = args[i:]
+				i = len(args)
+			}
+		}
+	}
+
+	if opts.ProfileName == "" || len(opts.Command) == 0 {
+		a.showExecHelp()
+		return fmt.Errorf("a profile name and a command are required")
+	}
+
+	return commands.ExecInProfile(a.profilesDir, opts)
+}
+
+func (a *App) handleShell(args []string) error {
+	opts := commands.ShellOptions{}
+
+	// Parse arguments
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-h", "--help":
+			a.showShellHelp()
+			return nil
+		case "--shell":
+			if i+1 < len(args) {
+				opts.Shell = args[i+1]
+				i++
+			}
+		default:
+			if opts.ProfileName == "" && !strings.HasPrefix(arg, "-") {
+				opts.ProfileName = arg
+			}
+		}
+	}
+
+	return commands.SpawnShell(a.profilesDir, opts)
+}
+
+func (a *App) handleActivate(args []string) error {
+	opts := commands.ActivateOptions{}
+
+	// Parse arguments
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-h", "--help":
+			a.showActivateHelp()
+			return nil
+		case "--shell":
+			if i+1 < len(args) {
+				opts.Shell = args[i+1]
+				i++
+			}
+		default:
+			if opts.ProfileName == "" && !strings.HasPrefix(arg, "-") {
+				opts.ProfileName = arg
+			}
+		}
+	}
+
+	return commands.ActivateProfile(a.profilesDir, opts)
+}
+
+func (a *App) handleHook(args []string) error {
+	opts := commands.HookOptions{}
+
+	// Parse arguments
+	for _, arg := range args {
+		switch arg {
+		case "-h", "--help":
+			a.showHookHelp()
+			return nil
+		default:
+			if opts.Shell == "" && !strings.HasPrefix(arg, "-") {
+				opts.Shell = arg
+			}
+		}
+	}
+
+	return commands.PrintHook(opts)
+}
+
+func (a *App) handlePrompt(args []string) error {
+	opts := commands.PromptOptions{}
+	subcommand := ""
+
+	// Parse arguments
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-h", "--help":
+			a.showPromptHelp()
+			return nil
+		case "-f", "--format":
+			if i+1 < len(args) {
+				opts.Format = args[i+1]
+				i++
+			}
+		case "--color":
+			if i+1 < len(args) {
+				opts.Color = args[i+1]
+				i++
+			}
+		case "--shell":
+			if i+1 < len(args) {
+				opts.Shell = args[i+1]
+				i++
+			}
+		default:
+			if subcommand == "" && !strings.HasPrefix(arg, "-") {
+				subcommand = arg
+			}
+		}
+	}
+
+	switch opts.Color {
+	case "", "auto", "always", "never":
+	default:
+		return fmt.Errorf("invalid --color value: %s (must be: auto, always, or never)", opts.Color)
+	}
+
+	switch subcommand {
+	case "":
+		return commands.PrintPrompt(opts)
+	case "starship":
+		return commands.PrintStarshipConfig(opts)
+	case "formats":
+		for _, format := range commands.PromptFormatNames() {
+			fmt.Printf("  %s\n", format)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown prompt subcommand: %s", subcommand)
+	}
+}
+
+func (a *App) handleTemplate(args []string) error {
+	if len(args) == 0 {
+		a.showTemplateHelp()
+		return nil
+	}
+
+	subcommand := args[0]
+	args = args[1:]
+
+	opts := commands.TemplateOptions{}
+	sourceOpts := commands.TemplateSourceOptions{}
+	var positional []string
+
+	// Parse common options
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-h", "--help":
+			a.showTemplateHelp()
+			return nil
+		case "-f", "--force":
+			opts.Force = true
+		case "-d", "--description":
+			if i+1 < len(args) {
+				opts.Description = args[i+1]
+				i++
+			}
+		case "--name":
+			if i+1 < len(args) {
+				sourceOpts.Name = args[i+1]
+				i++
+			}
+		case "--rev":
+			if i+1 < len(args) {
+				sourceOpts.Revision = args[i+1]
+				i++
+			}
+		default:
+			if !strings.HasPrefix(arg, "-") {
+				positional = append(positional, arg)
+			}
+		}
+	}
+
+	switch subcommand {
+	case "list", "ls":
+		return commands.ListTemplates()
+	case "show":
+		if len(positional) > 0 {
+			opts.Name = positional[0]
+		}
+		if len(positional) > 1 {
+			opts.File = positional[1]
+		}
+		return commands.ShowTemplate(opts)
+	case "save":
+		if len(positional) > 0 {
+			opts.ProfileName = positional[0]
+		}
+		if len(positional) > 1 {
+			opts.Name = positional[1]
+		}
+		return commands.SaveTemplate(a.profilesDir, opts)
+	case "add-source":
+		if len(positional) > 0 {
+			sourceOpts.URL = positional[0]
+		}
+		return commands.AddTemplateSource(sourceOpts)
+	case "update":
+		if len(positional) > 0 {
+			sourceOpts.Name = positional[0]
+		}
+		return commands.UpdateTemplateSources(a.profilesDir, sourceOpts)
+	case "sources":
+		return commands.ListTemplateSources()
+	case "help", "-h", "--help":
+		a.showTemplateHelp()
+		return nil
+	default:
+		a.showTemplateHelp()
+		return fmt.Errorf("unknown template command: %s", subcommand)
+	}
+}
+
+func (a *App) handleTools(args []string) error {
+	if len(args) == 0 {
+		a.showToolsHelp()
+		return nil
+	}
+
+	subcommand := args[0]
+	args = args[1:]
+
+	opts := commands.ToolsOptions{}
+	var positional []string
+
+	// Parse common options
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-h", "--help":
+			a.showToolsHelp()
+			return nil
+		case "-f", "--force":
+			opts.Force = true
+		case "--dry-run":
+			opts.DryRun = true
+		default:
+			if !strings.HasPrefix(arg, "-") {
+				positional = append(positional, arg)
+			}
+		}
+	}
+
+	switch subcommand {
+	case "list", "ls":
+		if len(positional) > 0 {
+			opts.ProfileName = positional[0]
+		}
+		return commands.ListTools(a.profilesDir, opts)
+	case "enable", "disable":
+		if len(positional) == 0 {
+			return fmt.Errorf("tool name is required: shell-profiler tools %s <tool> [profile-name]", subcommand)
+		}
+		opts.Tool = positional[0]
+		if len(positional) > 1 {
+			opts.ProfileName = positional[1]
+		}
+		if subcommand == "enable" {
+			return commands.EnableTool(a.profilesDir, opts)
+		}
+		return commands.DisableTool(a.profilesDir, opts)
+	case "help", "-h", "--help":
+		a.showToolsHelp()
+		return nil
+	default:
+		a.showToolsHelp()
+		return fmt.Errorf("unknown tools command: %s", subcommand)
+	}
+}
+
 func (a *App) handleStatus(_args []string) error {
 	// Check if direnv is installed and show status
 	return profile.ShowDirenvStatus()
@@ -485,53 +1241,162 @@ Commands:
     init [options]             Initialize the profile manager configuration
         Options:
             --profiles-dir <path>    Set profiles directory path
+            --templates-dir <path>   Set user templates directory path
             --interactive            Interactive setup
             --force                  Overwrite existing configuration
 
     create <name> [options]     Create a new workspace profile
         Options:
-            --template <type>       Use template: personal, work, client, basic
+            --template <name>       Use a template (see 'template list', default: basic)
             --git-name <name>       Set git user name
             --git-email <email>     Set git user email
+            --tools <list>          Tools to isolate, e.g. git,ssh,aws,kube (default: all)
+            --description <text>    Describe the profile (shown by 'list --verbose')
+            --tags <list>           Tag the profile, e.g. client,billing
             --interactive           Interactive setup (default if no flags provided)
             --no-interactive        Disable interactive mode
             --force                 Overwrite existing profile
 
-    update [name] [options]     Update an existing profile with new features
+    update [name] [options]     Update an existing profile with new features
+        Options:
+            --dry-run              Preview changes without applying
+            --force                 Overwrite existing files
+            --no-backup            Skip creating backup
+        Note: Interactive selection by default if name is omitted
+
+    select [name] [options]     Select and switch to a profile
+        Options:
+            --allow-direnv          Automatically allow direnv for selected profile
+        Note: Interactive selection if name is omitted
+
+    list [options]              List all workspace profiles
+        Options:
+            --verbose               Show detailed information (disables interactive)
+            --config                Show git configuration (disables interactive)
+            --no-interactive         Disable interactive mode
+            --archived               List archived profiles
+        Note: Interactive by default unless flags are provided
+
+    delete [name] [options]     Delete a workspace profile
+        Options:
+            --force                 Skip confirmation prompt (disables interactive)
+            --dry-run              Preview deletion without deleting (disables interactive)
+            --permanent            Remove instead of moving to the trash
+            --secure               Overwrite credentials and backups, write a receipt
+            --no-snapshot          Skip the snapshot taken before a permanent delete
+            --no-interactive        Disable interactive mode
+        Note: Interactive selection by default if name is omitted
+
+    rename <old> <new> [options]
+                                Rename a profile and rewrite references to it
+        Options:
+            --force                 Skip confirmation prompt
+            --dry-run              Preview the rename without renaming
+
+    clone <src> <new> [options]
+                                Create a new profile from an existing one
+        Options:
+            --git-name <name>       Set git user name
+            --git-email <email>     Set git user email
+            --with-secrets          Also copy credentials, keys and .env values
+            --force                 Overwrite existing profile
+            --dry-run              Preview the clone without creating it
+
+    restore [name] [options]    Restore a profile from backup
+        Options:
+            --force                 Skip confirmation prompt
+            --dry-run              Preview restore without restoring
+            --file <file>           Restore only a specific file
+            --backup-date <date>    Restore from specific dated backup
+        Note: Interactive backup selection if --backup-date is omitted
+
+    snapshot <command> [name]   Manage full-profile snapshots
+        Commands:
+            list                    List snapshots (all profiles if name is omitted)
+            create [--reason <r>]   Take a snapshot now
+            prune                   Remove snapshots outside the retention policy
+        Options:
+            --keep <n>              Keep the newest n snapshots (prune)
+            --keep-days <d>         Keep snapshots younger than d days (prune)
+            --dry-run               Preview pruning without deleting
+
+    archive [name] [options]    Move a dormant profile into the archive store
+        Options:
+            --encrypt-secrets       Encrypt credentials and keys with a passphrase
+            --exclude-secrets       Destroy credentials and keys instead of archiving them
+            --force                 Skip confirmation, replace an existing archive
+            --dry-run              Preview the archive without archiving
+
+    unarchive [name] [options]  Bring an archived profile back
+        Options:
+            --as <name>             Unarchive under a different name
+            --dry-run              Preview without unarchiving
+
+    export [name] [options]     Export a profile to a portable bundle
+        Options:
+            -o, --output <file>     Bundle file (default: <name>.tar.gz)
+            --with-secrets          Include credentials and keys, encrypted with a passphrase
+            --force                 Overwrite an existing bundle file
+
+    import <file> [options]     Create a profile from an exported bundle
+        Options:
+            --as <name>             Import under a different name
+            --force                 Replace an existing profile with the same name
+            --dry-run              Preview the import without importing
+
+    trash <command> [name]      Manage deleted profiles
+        Commands:
+            list                    List trashed profiles
+            restore [--as <name>]   Move a profile back out of the trash
+            empty                   Permanently delete trashed profiles
         Options:
-            --dry-run              Preview changes without applying
-            --force                 Overwrite existing files
-            --no-backup            Skip creating backup
-        Note: Interactive selection by default if name is omitted
+            --older-than <age>      Only empty profiles older than age (e.g. 30d)
+            --force                 Skip confirmation prompt
 
-    select [name] [options]     Select and switch to a profile
+    shell [name] [options]      Start a subshell in a profile (exit to return)
         Options:
-            --allow-direnv          Automatically allow direnv for selected profile
+            --shell <path>          Shell to start (default: $SHELL)
         Note: Interactive selection if name is omitted
 
-    list [options]              List all workspace profiles
+    activate <name> [options]   Print a script that loads a profile without direnv
         Options:
-            --verbose               Show detailed information (disables interactive)
-            --config                Show git configuration (disables interactive)
-            --no-interactive         Disable interactive mode
-        Note: Interactive by default unless flags are provided
+            --shell <shell>         bash, zsh or fish (default: from $SHELL)
+        Note: Use with eval; run 'deactivate' to restore the previous environment
 
-    delete [name] [options]     Delete a workspace profile
+    hook <shell>                Print shell integration code (bash, zsh, fish)
+        Note: Defines sp use <name>, sp cd <name> [subdir] and sp back
+
+    prompt [options]            Print a prompt segment for the current profile
         Options:
-            --force                 Skip confirmation prompt (disables interactive)
-            --dry-run              Preview deletion without deleting (disables interactive)
-            --no-interactive        Disable interactive mode
+            --format <fmt>          Built-in format name or format string
+            --shell <shell>         Wrap colors for bash or zsh prompts
+        Note: 'prompt starship' prints a starship module, 'prompt formats' lists formats
+
+    template <command> [name]   Manage profile templates
+        Commands:
+            list                    List built-in and user templates
+            show <name> [file]      Show a template's files, or one file's source
+            save <profile> <name>   Save a profile as a new user template
+            add-source <git-url>    Add a git repository of templates
+            update [source]         Pull the latest templates from sources
+            sources                 List template sources
+
+    tools <command> [name]      Choose the tools a profile isolates
+        Commands:
+            list [name]             List tools, and those enabled in a profile
+            enable <tool> [name]    Add a tool's directories, .env variables and .gitignore patterns
+            disable <tool> [name]   Remove them (asks before deleting files)
         Note: Interactive selection by default if name is omitted
 
-    restore <name> [options]    Restore a profile from backup
+    exec <name> -- <cmd...>     Run a command with a profile's environment loaded
         Options:
-            --force                 Skip confirmation prompt
-            --dry-run              Preview restore without restoring
-            --file <file>           Restore only a specific file
-            --backup-date <date>    Restore from specific dated backup
+            --cwd <dir>             Run in dir (relative paths are inside the profile)
 
     info                        Show information about the current profile
     status                      Show direnv status
+    doctor [name] [options]     Check tools, shell setup and profile health
+        Options:
+            --fix                   Repair permissions, directories and the ssh wrapper
     dotfiles <command> [name]    Manage shell-profiler dotfiles
         Commands:
             list                    List all dotfiles in a profile
@@ -646,8 +1511,11 @@ Commands:
             <url>                Remote URL (required)
         Note: If profile-name is omitted, interactive selection will be shown
 
-    status                  Show sync status and remote information
+    status [--all]          Show sync status and remote information
+        Options:
+            --all                Show status for all profiles
         Note: If profile-name is omitted, shows status for all profiles
+        Note: Archived profiles are not included
 
 Examples:
     # Initialize repository
@@ -691,14 +1559,23 @@ Arguments:
 Options:
     -h, --help          Show this help message
     -f, --force         Overwrite existing profile if it exists
-    -t, --template      Use a specific template: personal, work, or client
-                        (default: basic)
+    -t, --template      Use a template: a built-in one or one from your
+                        templates directory (default: basic)
     --git-name NAME     Set git user.name in .gitconfig
     --git-email EMAIL   Set git user.email in .gitconfig
     --interactive       Prompt for all configuration values
     --dry-run          Show what would be created without creating it
     --init-git         Initialize git repository after creation
     --git-remote <url> Initialize git repository with remote URL
+    --var NAME=VALUE    Set a template variable (repeatable); see
+                        'shell-profiler template show <name>'
+    --tools LIST        Tools to isolate, comma-separated, or "none"
+                        (default: all; see 'shell-profiler tools list')
+    --description TEXT  Describe the profile
+    --tags LIST         Tag the profile, comma-separated
+
+The profile's template, tools, description, tags and creation time are
+recorded in .shell-profiler.json in the profile.
 
 Examples:
     # Create a basic profile
@@ -719,11 +1596,20 @@ Examples:
     shell-profiler create my-project --init-git
     shell-profiler create my-project --git-remote https://github.com/user/my-project.git
 
+    # Only isolate git, ssh, AWS and Kubernetes
+    shell-profiler create my-project --tools git,ssh,aws,kube
+
+    # Set the variables a template asks for
+    shell-profiler create acme-api --template client-acme \
+        --var aws_sso_url=https://acme.awsapps.com/start --var aws_region=eu-west-1
+
 Templates:
     personal    - Personal projects with minimal configuration
     work        - Work projects with corporate settings
     client      - Client projects with isolated credentials
     basic       - Minimal configuration (default)
+
+    Run 'shell-profiler template list' to see your own templates as well.
 `
 	fmt.Print(helpText)
 }
@@ -757,6 +1643,9 @@ Examples:
 After selection, you'll see instructions to activate the profile:
     cd <profile-path>
     direnv allow  # (first time only)
+
+With the shell integration installed (see 'shell-profiler hook --help'),
+'sp use <profile>' does this for you.
 `
 	fmt.Print(helpText)
 }
@@ -773,12 +1662,14 @@ Options:
     -v, --verbose       Show detailed information (disables interactive)
     -c, --config        Show git configuration (disables interactive)
     --no-interactive    Disable interactive mode
+    --archived          List archived profiles instead (disables interactive)
 
 Examples:
     shell-profiler list                # Interactive selection menu (default)
     shell-profiler list --verbose      # Show detailed information for all profiles
     shell-profiler list --config       # Show git configuration for all profiles
     shell-profiler list --no-interactive  # List all profiles without interactive menu
+    shell-profiler list --archived     # List archived profiles
 `
 	fmt.Print(helpText)
 }
@@ -786,7 +1677,10 @@ Examples:
 func (a *App) showDeleteHelp() {
 	helpText := `Usage: shell-profiler delete [profile-name] [options]
 
-Delete a workspace profile and all its files.
+Delete a workspace profile.
+
+The profile is moved to the trash and can be brought back with
+'shell-profiler trash restore'. Use --permanent to remove it for good.
 
 Interactive selection is enabled by default if profile name is omitted.
 
@@ -797,6 +1691,9 @@ Options:
     -h, --help          Show this help message
     -f, --force         Skip confirmation prompt (disables interactive)
     --dry-run          Show what would be deleted without deleting (disables interactive)
+    --permanent        Remove the profile instead of moving it to the trash
+    --secure           Overwrite credentials and backups, then remove (implies --permanent)
+    --no-snapshot      Do not take a snapshot before a permanent delete
     --no-interactive    Disable interactive mode
 
 Examples:
@@ -812,10 +1709,92 @@ Examples:
     # Preview what would be deleted
     shell-profiler delete old-project --dry-run
 
+    # Delete for good, skipping the trash
+    shell-profiler delete old-project --permanent
+
+    # Offboard a client: destroy credentials and keep a deletion receipt
+    shell-profiler delete old-client --secure
+
 Safety:
     - You will be prompted for confirmation unless --force is used
-    - The profile directory and all its contents will be deleted
-    - This operation cannot be undone
+    - The profile directory and all its contents are moved to the trash
+    - With --permanent, a snapshot is taken first; use 'shell-profiler restore <name>' to recreate the profile
+
+Secure delete:
+    --secure overwrites every sensitive file (.env, .envrc.local, .ssh/id_*,
+    known_hosts, .aws/credentials, Azure and gcloud token caches, kubeconfig, ...)
+    with random data and zeros before unlinking it. All snapshots and backups
+    of the profile are overwritten the same way and no new snapshot is taken.
+    A receipt listing what was destroyed and when is written to
+    $XDG_STATE_HOME/shell-profiler/receipts/ (default ~/.local/state/shell-profiler/receipts/).
+    Overwriting is best effort on copy-on-write filesystems and SSDs; use
+    full-disk encryption for stronger guarantees.
+`
+	fmt.Print(helpText)
+}
+
+func (a *App) showRenameHelp() {
+	helpText := `Usage: shell-profiler rename <old-name> <new-name> [options]
+
+Rename a workspace profile.
+
+The profile directory is moved and every reference to the old name or path is
+rewritten: WORKSPACE_PROFILE in .envrc, the file headers in .env, .gitconfig
+and README.md, and the absolute paths in the SSH configuration.
+
+Arguments:
+    old-name            Current name of the profile
+    new-name            New name for the profile
+
+Options:
+    -h, --help          Show this help message
+    -f, --force         Skip confirmation prompt
+    --dry-run          Show which files would change without renaming
+
+Examples:
+    # Rename a profile (with confirmation)
+    shell-profiler rename acme acme-corp
+
+    # Preview the rename
+    shell-profiler rename acme acme-corp --dry-run
+
+Notes:
+    - The rename is refused if the new name is taken or the profile is active
+    - Snapshots and backups of the profile are moved to the new name
+    - If the origin remote ends in the profile name, it is updated to the new name
+    - direnv is allowed again for the new location
+`
+	fmt.Print(helpText)
+}
+
+func (a *App) showCloneHelp() {
+	helpText := `Usage: shell-profiler clone <source-profile> <new-profile> [options]
+
+Create a new workspace profile from the configuration of an existing one.
+
+Configuration is copied (.envrc, .gitconfig, SSH config, bin scripts, cloud
+config files) and every reference to the source profile is re-pointed at the
+new one. Credentials, SSH keys, known_hosts and the values in .env are not
+copied; .env is regenerated with the source's variable names left empty.
+
+Arguments:
+    source-profile      Profile to copy from
+    new-profile         Name of the profile to create
+
+Options:
+    -h, --help          Show this help message
+    --git-name <name>   Set git user name in the new profile
+    --git-email <email> Set git user email in the new profile
+    --with-secrets      Also copy credentials, keys and .env values
+    -f, --force         Overwrite an existing profile (a snapshot is taken first)
+    --dry-run          Show what would be copied without creating anything
+
+Examples:
+    # Start a new client profile from an existing one
+    shell-profiler clone acme globex --git-email me@globex.com
+
+    # Preview which files would be copied and skipped
+    shell-profiler clone acme globex --dry-run
 `
 	fmt.Print(helpText)
 }
@@ -893,7 +1872,7 @@ Options:
     -h, --help          Show this help message
     -f, --force         Overwrite existing files without prompting
     --dry-run          Preview changes without applying them
-    --no-backup        Skip creating backup before updating
+    --no-backup        Skip creating a snapshot before updating
 
 Examples:
     # Interactive selection
@@ -909,14 +1888,594 @@ Examples:
     shell-profiler update my-project --no-backup
 
 What gets updated:
-    - Missing directories (.azure, .gcloud, etc.)
-    - Missing environment variables in .envrc
-    - Missing patterns in .gitignore
+    - Migrations the profile has not had yet, in order, reported by name
+    - Missing directories of the profile's tools (.azure, .gcloud, etc.)
+    - The managed sections of .envrc, .env, .gitignore and .ssh/config
+    - Missing tool variables and patterns in files without a managed section
     - SSH directory permissions
 
+Managed sections:
+    Generated content of .envrc, .env, .gitignore and .ssh/config sits between
+    '# >>> shell-profiler managed >>>' and '# <<< shell-profiler managed <<<'.
+    Update regenerates it from the template and the profile's tools. Changes you
+    made inside a section are kept with a three-way merge against the section as
+    it was last generated (kept in .shell-profiler.json). Where both changed the
+    same lines, the file gets git-style conflict markers:
+        <<<<<<< current
+        your lines
+        =======
+        generated lines
+        >>>>>>> generated
+    Edit the file to resolve them; 'shell-profiler doctor' reports files that
+    still have markers. Content outside the markers is never changed.
+
+Migrations:
+    Each profile records a schema version in .shell-profiler.json. Version N
+    means migrations 1 to N have been applied; profiles without the file are
+    at version 0. Commands warn about profiles older than this shell-profiler.
+        1. Split .envrc and .env (tool variables move from .envrc to .env)
+        2. Move backups out of the profile (and out of git)
+        3. Add gemini directory
+        4. Record metadata in .shell-profiler.json
+        5. Mark managed sections (around the generated lines of older files)
+    A declined migration is offered again by the next update.
+
 Backup:
-    By default, a backup is created in .backups/update_<timestamp>/ before making changes.
-    Use --no-backup to skip this.
+    By default, a snapshot of the whole profile is taken before making changes.
+    Use --no-backup to skip this, and 'shell-profiler restore' to roll back.
+    Snapshots are stored outside the profile in
+    $XDG_STATE_HOME/shell-profiler/backups/<profile>/ (default ~/.local/state).
+`
+	fmt.Print(helpText)
+}
+
+func (a *App) showRestoreHelp() {
+	helpText := `Usage: shell-profiler restore [profile-name] [options]
+
+Restore profile files from a snapshot or a legacy .backups/ directory.
+
+Snapshots are taken automatically before update, delete --permanent and
+create --force, or manually with 'shell-profiler snapshot create'. A profile
+deleted with --permanent can be recreated from the snapshot taken before it was
+deleted. A plain delete moves the profile to the trash without a snapshot;
+bring it back with 'shell-profiler trash restore <profile>'.
+
+A diff between the current files and the backup is shown for every file that
+would change. The current state is snapshotted before restoring, so a restore
+can itself be undone.
+
+Arguments:
+    profile-name        Name of the profile to restore (optional - interactive selection if omitted)
+
+Options:
+    -h, --help              Show this help message
+    -f, --force             Skip confirmation prompt
+    --dry-run               Show the diff without restoring anything
+    --file <file>           Restore only a specific file (e.g. .envrc)
+    --backup-date <date>    Restore from a specific backup; a prefix such as
+                            2024-11-29 selects among that day's backups
+
+Examples:
+    # Pick a backup interactively
+    shell-profiler restore my-project
+
+    # Restore from a specific backup
+    shell-profiler restore my-project --backup-date 2024-11-29_14-30-45
+
+    # Restore a single file
+    shell-profiler restore my-project --file .envrc
+
+    # Preview what would change
+    shell-profiler restore my-project --dry-run
+`
+	fmt.Print(helpText)
+}
+
+func (a *App) showArchiveHelp() {
+	helpText := `Usage: shell-profiler archive [profile-name] [options]
+       shell-profiler unarchive [profile-name] [options]
+
+Archive dormant profiles.
+
+archive compresses the whole profile (including .git and code/) into the archive
+store and removes it from the profiles directory, so it no longer shows up in
+list, select or sync status. unarchive brings it back to its original location
+with all paths intact.
+
+The archive store is $XDG_STATE_HOME/shell-profiler/archives/<profile>/
+(default ~/.local/state/shell-profiler/archives/<profile>/).
+
+Interactive selection is used if profile name is omitted.
+
+Options:
+    -h, --help            Show this help message
+    --encrypt-secrets     Store credentials, keys and .env in a separate file encrypted
+                          with a passphrase (AES-256-GCM)
+    --exclude-secrets     Do not archive credentials, keys and .env; they are overwritten
+                          and lost
+    --as <name>           Unarchive under a different name
+    -f, --force           Skip confirmation prompt, replace an existing archive
+    --dry-run             Show what would be archived or unarchived
+
+The passphrase is prompted for, or read from SHELL_PROFILER_PASSPHRASE.
+
+Examples:
+    shell-profiler archive old-client --encrypt-secrets
+    shell-profiler list --archived
+    shell-profiler unarchive old-client
+`
+	fmt.Print(helpText)
+}
+
+func (a *App) showExportHelp() {
+	helpText := `Usage: shell-profiler export [profile-name] [options]
+       shell-profiler import <bundle-file> [options]
+
+Move profiles between machines or hand them to teammates.
+
+export writes a single bundle: a tarball of the profile's files plus a manifest
+with the file list, template, schema version and git remote. The .git, .backups
+and code/ directories are not exported.
+
+Secrets (credentials, SSH keys, known_hosts, .env values) are excluded by
+default; .env is exported with its values blanked. --with-secrets adds them in
+a section encrypted with a passphrase (AES-256-GCM). The passphrase is prompted
+for, or read from SHELL_PROFILER_PASSPHRASE.
+
+import recreates the profile in the configured profiles_dir and re-points the
+absolute paths recorded in it (such as the SSH config) at the new location.
+
+Export options:
+    -o, --output <file>   Bundle file (default: <name>.tar.gz)
+    --with-secrets        Include secrets, encrypted with a passphrase
+    -f, --force           Overwrite an existing bundle file
+
+Import options:
+    --as <name>           Import under a different name
+    -f, --force           Replace an existing profile (a snapshot is taken first)
+    --dry-run             Show what would be imported
+
+Examples:
+    shell-profiler export acme -o ~/acme.tar.gz
+    shell-profiler export acme --with-secrets -o /Volumes/usb/acme.tar.gz
+    shell-profiler import ~/acme.tar.gz
+    shell-profiler import ~/acme.tar.gz --as acme-laptop
+`
+	fmt.Print(helpText)
+}
+
+func (a *App) showShellHelp() {
+	helpText := `Usage: shell-profiler shell [profile-name] [options]
+
+Switch to a profile in a subshell.
+
+Starts your $SHELL in the profile directory with the profile environment
+already loaded and the profile name added to the prompt. Exit the shell
+(exit or Ctrl-D) to return to where you were, with your previous environment.
+
+The environment is loaded the same way as 'shell-profiler exec' loads it, so
+neither direnv nor its shell hook is needed. Your own rc files are still read;
+the profile's variables are applied after them.
+
+Arguments:
+    profile-name        Name of the profile (optional - interactive selection if omitted)
+
+Options:
+    -h, --help          Show this help message
+    --shell <path>      Shell to start instead of $SHELL
+
+Prompt marking is supported for bash, zsh and fish; other shells get PS1 set.
+SHELL_PROFILER_SHELL is set to the profile name inside the subshell.
+
+Examples:
+    shell-profiler shell acme
+    shell-profiler shell acme --shell /bin/zsh
+`
+	fmt.Print(helpText)
+}
+
+func (a *App) showActivateHelp() {
+	helpText := `Usage: shell-profiler activate <profile-name> [options]
+
+Load a profile into the current shell without direnv.
+
+Prints a script that sets the profile environment when evaluated, and defines
+a 'deactivate' function that restores the variables and PATH entries it
+changed. Activating another profile deactivates the current one first.
+
+The profile's .envrc is read natively, so neither direnv nor its shell hook is
+needed. Supported: export and plain assignments, PATH_add, dotenv,
+dotenv_if_exists, source_env and source. Other lines are skipped with a
+warning on stderr.
+
+Arguments:
+    profile-name        Name of the profile
+
+Options:
+    -h, --help          Show this help message
+    --shell <shell>     Shell to generate the script for: bash, zsh or fish
+                        (default: from $SHELL, falling back to bash)
+
+SHELL_PROFILER_ACTIVE is set to the profile name while it is active.
+
+Examples:
+    eval "$(shell-profiler activate acme)"
+    shell-profiler activate acme --shell fish | source
+    deactivate
+`
+	fmt.Print(helpText)
+}
+
+func (a *App) showHookHelp() {
+	helpText := `Usage: shell-profiler hook [bash|zsh|fish]
+
+Print shell integration code to load from your rc file.
+
+The code defines an 'sp' function that can change your shell's directory,
+which shell-profiler itself cannot do:
+
+    sp use [profile]            Change to the profile and allow its .envrc
+    sp cd <profile> [subdir]    Change to the profile, or a directory inside it
+    sp back                     Return to the directory (and profile) you left
+    sp <command> [args...]      Run any other shell-profiler command
+
+After 'sp update' rewrites the .envrc of the profile you are in, direnv is
+reloaded so the changes apply immediately.
+
+Arguments:
+    shell               bash, zsh or fish (default: from $SHELL)
+
+Options:
+    -h, --help          Show this help message
+
+Setup:
+    eval "$(shell-profiler hook bash)"     # ~/.bashrc
+    eval "$(shell-profiler hook zsh)"      # ~/.zshrc
+    shell-profiler hook fish | source      # ~/.config/fish/config.fish
+
+Add it after the direnv hook.
+`
+	fmt.Print(helpText)
+}
+
+func (a *App) showPromptHelp() {
+	helpText := `Usage: shell-profiler prompt [starship|formats] [options]
+
+Print a compact prompt segment for the profile loaded in the current shell.
+
+Prints nothing outside a profile. The segment is built from WORKSPACE_PROFILE
+and WORKSPACE_HOME and colored by the profile's template (work: blue,
+client: yellow, personal: green, others: cyan). It reads no configuration and
+runs no git or direnv, so it is fast enough to run on every prompt.
+
+Subcommands:
+    starship            Print a custom module for ~/.config/starship.toml
+    formats             List the built-in formats
+
+Options:
+    -h, --help          Show this help message
+    -f, --format <fmt>  Built-in format name, or a format string (default: default)
+    --color <when>      auto, always or never (auto honors NO_COLOR)
+    --shell <shell>     Wrap color codes for a bash or zsh prompt
+
+Format strings:
+    {profile}           Profile name
+    {template}          Profile template
+    {home}              Profile directory
+    {email}             Git email from the profile's .gitconfig
+    {kube}              Current context from the profile's kubeconfig
+    {aws}               AWS_PROFILE
+    ( ... )             Left out when a placeholder inside it is empty
+
+Examples:
+    # bash (~/.bashrc)
+    PS1='$(shell-profiler prompt --shell bash) '"$PS1"
+
+    # zsh (~/.zshrc)
+    setopt PROMPT_SUBST
+    PROMPT='$(shell-profiler prompt --shell zsh) '"$PROMPT"
+
+    shell-profiler prompt --format full
+    shell-profiler prompt --format '{profile}( <{email}>)'
+    shell-profiler prompt starship --format git >> ~/.config/starship.toml
+`
+	fmt.Print(helpText)
+}
+
+func (a *App) showTemplateHelp() {
+	helpText := `Usage: shell-profiler template <command> [arguments]
+
+Manage the templates new profiles are created from.
+
+Commands:
+    list, ls                List built-in and user templates
+    show <name> [file]      Show a template's files, or the source of one file
+    save <profile> <name>   Save a profile as a new user template
+
+Options:
+    -h, --help              Show this help message
+    -d, --description TEXT  Description of a saved template
+    -f, --force             Overwrite an existing template when saving
+    --name NAME             Name of an added source (default: repository name)
+    --rev REVISION          Pin a source to a commit, tag or branch
+
+'template save' copies a profile's files into a new template in the templates
+directory. The profile name, path, template, creation time and git identity
+are replaced with the placeholders below, and AWS SSO/region settings and the
+git signing key become template variables. Everything the profile's
+.gitignore marks sensitive (.env, SSH keys, cloud credentials, kubeconfig,
+...), version control, backups and code/ are left out.
+
+Template sources are git repositories of templates shared by a team, with
+one directory per template at the top level or in templates/. They are
+cloned below the templates directory and pinned to a commit until 'template
+update' moves them on. Templates in the templates directory itself take
+precedence over sources, and sources over built-in templates. Profiles record
+the source and revision they were created from in .shell-profiler.json, and
+'template update' and 'shell-profiler update' report profiles built from
+older revisions.
+
+A template is a directory of files that make up a new profile. User templates
+live in the templates directory (templates_dir in ~/.profile-manager, default
+~/.config/shell-profiler/templates), one directory per template, and replace
+built-in templates of the same name.
+
+Every file is rendered with Go's text/template, and a trailing .tmpl is
+removed from its name. A template extends a parent template (the built-in
+basic template by default) and only needs the files it changes: a file
+replaces the parent's file at the same path, unless template.json sets it to
+be appended or prepended to the parent's file instead (useful for line-based
+files such as .gitignore, .env and .gitconfig).
+
+template.json (optional):
+    {
+      "description": "ACME client work",
+      "parent": "work",
+      "files": {
+        ".gitconfig": "append",
+        ".gitignore": "append",
+        ".env": "prepend"
+      }
+    }
+
+'template show' and 'create --dry-run' list the templates each file is built
+from.
+
+Available in template files:
+    {{.Name}}           Profile name
+    {{.Template}}       Template name
+    {{.Path}}           Absolute profile directory
+    {{.DisplayPath}}    Profile directory with ~ for the home directory
+    {{.GitName}}        Git user name (may be empty)
+    {{.GitEmail}}       Git user email (may be empty)
+    {{.Created}}        Creation time (UTC)
+    {{.ToolEnv}}        .env variables of the profile's tools
+    {{.ToolGitignore}}  .gitignore patterns of the profile's tools
+    {{.Vars.<name>}}    Value of a variable the template declares
+
+Lines of .envrc, .env, .gitignore and .ssh/config between
+'# >>> shell-profiler managed >>>' and '# <<< shell-profiler managed <<<' are
+kept up to date in profiles by 'shell-profiler update' (see its help).
+
+Variables are declared in template.json and asked for by
+'create --interactive', or set with 'create --var name=value'. A template
+inherits its parent's variables. Each has a type (string, bool or int), and
+optionally a default, a pattern string values must match, help text, and
+whether it is required:
+    "variables": [
+      {"name": "aws_sso_url", "help": "AWS SSO start URL",
+       "pattern": "^https://", "required": true},
+      {"name": "aws_region", "default": "us-east-1"},
+      {"name": "use_vpn", "type": "bool", "default": false}
+    ]
+
+Examples:
+    shell-profiler template list
+    shell-profiler template show work
+    shell-profiler template show basic .envrc
+
+    # Start a template of your own from a built-in file
+    mkdir -p ~/.config/shell-profiler/templates/oss
+    shell-profiler template show basic .gitconfig \
+        > ~/.config/shell-profiler/templates/oss/.gitconfig.tmpl
+    shell-profiler create my-lib --template oss
+
+    # Share a hand-tuned profile with teammates
+    shell-profiler template save acme-corp acme --description "ACME projects"
+
+    # Use the team's templates, pinned to a release
+    shell-profiler template add-source git@github.com:acme/profile-templates.git \
+        --name acme --rev v1.2.0
+    shell-profiler template update acme
+
+    # Extend the work template for one client
+    mkdir -p ~/.config/shell-profiler/templates/client-acme/.aws
+    cd ~/.config/shell-profiler/templates/client-acme
+    echo '{"parent": "work", "files": {".gitconfig": "append"}}' > template.json
+    printf '[commit]\n    gpgsign = true\n' > .gitconfig.tmpl
+    printf '[profile acme]\nregion = us-east-1\n' > .aws/config
+    shell-profiler create acme-api --template client-acme --dry-run
+`
+	fmt.Print(helpText)
+}
+
+func (a *App) showToolsHelp() {
+	helpText := `Usage: shell-profiler tools <command> [arguments]
+
+Choose the tools a profile isolates. Each tool has its own directories, .env
+variables pointing it into the profile, and .gitignore patterns for its
+credentials. Profiles record their tools in .shell-profiler.json, and
+'shell-profiler update' only adds what those tools need.
+
+Commands:
+    list [profile-name]             List the tools, and which a profile has enabled
+    enable <tool> [profile-name]    Add the tool's directories, template files,
+                                    .env variables and .gitignore patterns
+    disable <tool> [profile-name]   Remove the tool's .env variables and .gitignore
+                                    patterns, and after confirmation its
+                                    directories and files (a snapshot is taken)
+
+Options:
+    -h, --help          Show this help message
+    -f, --force         Delete the tool's files without asking
+    --dry-run           Show what would change without changing it
+
+Tools:
+    git, ssh, xdg, 1password, aws, kube, terraform, azure, gcloud, claude, gemini
+
+Examples:
+    # Create a profile with only some tools
+    shell-profiler create my-project --tools git,ssh,aws,kube
+
+    # See which tools a profile uses
+    shell-profiler tools list my-project
+
+    # Start using Google Cloud in a profile
+    shell-profiler tools enable gcloud my-project
+
+    # Stop isolating Azure (asks before deleting .azure)
+    shell-profiler tools disable azure my-project
+
+Note: Interactive profile selection if profile-name is omitted.
+`
+	fmt.Print(helpText)
+}
+
+func (a *App) showExecHelp() {
+	helpText := `Usage: shell-profiler exec <profile-name> [options] -- <command> [args...]
+
+Run a command inside a profile without changing to its directory.
+
+The profile's environment is loaded the same way direnv loads it: the exports
+in .envrc (WORKSPACE_PROFILE, WORKSPACE_HOME), PATH_add bin, .global/exports.sh,
+.env and .envrc.local. direnv itself is not needed. The exit status of the
+command is passed through.
+
+Only the parts of .envrc that can be evaluated without a shell are applied:
+exports and assignments, PATH_add, dotenv/dotenv_if_exists and source of files
+written the same way. Other lines, such as 'use' or 'layout', are skipped.
+
+Options:
+    -h, --help          Show this help message
+    -C, --cwd <dir>     Run the command in dir instead of the current directory;
+                        relative paths are inside the profile
+
+Examples:
+    shell-profiler exec acme -- git config user.email
+    shell-profiler exec acme -- aws sts get-caller-identity
+    shell-profiler exec acme --cwd code/api -- make deploy
+
+    # In CI
+    shell-profiler exec ci-bot -- ./scripts/release.sh || exit $?
+`
+	fmt.Print(helpText)
+}
+
+func (a *App) showDoctorHelp() {
+	helpText := `Usage: shell-profiler doctor [profile-name] [options]
+
+Check that shell-profiler and your profiles are set up correctly.
+
+Every check is reported as pass (✓), warning (⚠) or failure (✗). The command
+exits with an error if any check fails. All profiles are checked if the
+profile name is omitted.
+
+System checks:
+    - git, ssh and direnv are installed
+    - The direnv hook is present in your shell rc file
+
+Profile checks:
+    - The directories created by 'shell-profiler create' exist
+    - .ssh is 0700 and private keys are 0600
+    - bin/ssh is executable and execs an ssh binary that exists
+    - .envrc is allowed by direnv
+    - SSH_AUTH_SOCK from .env points at an existing socket
+
+Options:
+    -h, --help          Show this help message
+    --fix               Repair what is safe to repair: create missing directories,
+                        fix .ssh and key permissions, recreate or repoint bin/ssh
+
+Examples:
+    shell-profiler doctor
+    shell-profiler doctor my-project
+    shell-profiler doctor --fix
+
+Notes:
+    - --fix never runs 'direnv allow' or edits your shell rc files; review the
+      reported hints and apply them yourself
+`
+	fmt.Print(helpText)
+}
+
+func (a *App) showTrashHelp() {
+	helpText := `Usage: shell-profiler trash <command> [profile-name] [options]
+
+Manage deleted profiles.
+
+'shell-profiler delete' moves a profile to the trash instead of removing it.
+Trashed profiles keep all their files, including SSH keys and credentials,
+until the trash is emptied. The trash is stored outside the profiles directory:
+$XDG_STATE_HOME/shell-profiler/trash/ (default ~/.local/state/shell-profiler/trash/).
+
+Commands:
+    list, ls              List trashed profiles
+    restore [name]        Move a profile back (interactive selection if name is omitted)
+    empty [name]          Permanently delete trashed profiles (all if name is omitted)
+
+Options:
+    -h, --help            Show this help message
+    --as <name>           Restore under a different name
+    --older-than <age>    Only empty profiles deleted longer ago than age (e.g. 30d, 2w, 12h)
+    -f, --force           Skip confirmation prompt
+    --dry-run             Show what would be restored or removed
+
+Examples:
+    shell-profiler trash list
+    shell-profiler trash restore my-project
+    shell-profiler trash restore my-project --as my-project-old
+    shell-profiler trash empty --older-than 30d
+`
+	fmt.Print(helpText)
+}
+
+func (a *App) showSnapshotHelp() {
+	helpText := `Usage: shell-profiler snapshot <command> [profile-name] [options]
+
+Manage full-profile snapshots.
+
+A snapshot is a compressed archive of the whole profile (dotfiles, SSH config,
+agent.toml, bin/ scripts, cloud configs) with a manifest of checksums. The
+.git, .backups and code/ directories are not included. Snapshots are taken
+automatically before update, delete --permanent and create --force.
+
+Snapshots are stored outside the profile, so they are never committed or
+synced: $XDG_STATE_HOME/shell-profiler/backups/<profile>/
+(default ~/.local/state/shell-profiler/backups/<profile>/).
+
+Commands:
+    list, ls              List snapshots (all profiles if name is omitted)
+    create                Take a snapshot now
+    prune                 Remove snapshots outside the retention policy
+
+Options:
+    -h, --help            Show this help message
+    --reason <reason>     Label for a new snapshot (default: manual)
+    --keep <n>            Keep the newest n snapshots (overrides config)
+    --keep-days <d>       Keep snapshots younger than d days (overrides config)
+    --dry-run             Show what prune would delete
+
+Examples:
+    shell-profiler snapshot list
+    shell-profiler snapshot create my-project --reason before-migration
+    shell-profiler snapshot prune --keep 5 --keep-days 0
+    shell-profiler restore my-project            # Restore from a snapshot
+
+Retention:
+    A snapshot is kept if it is one of the newest snapshot_keep snapshots or
+    younger than snapshot_keep_days days. Set these in ~/.profile-manager;
+    0 disables a rule, and if both are 0 nothing is pruned.
+    Defaults: snapshot_keep=10, snapshot_keep_days=30
 `
 	fmt.Print(helpText)
 }
@@ -935,6 +2494,8 @@ Options:
     -f, --force             Overwrite existing configuration
     -i, --interactive       Interactive setup (prompt for paths)
     --profiles-dir <path>   Set profiles directory path
+    --templates-dir <path>  Set user templates directory path
+                            (default: ~/.config/shell-profiler/templates)
 
 Examples:
     # Initialize with default path
@@ -953,6 +2514,9 @@ Configuration:
     The configuration is stored in ~/.profile-manager with the following format:
     
     profiles_dir=<path>
+    templates_dir=<path>
+    snapshot_keep=<n>
+    snapshot_keep_days=<days>
     
     You can edit this file manually if needed. Paths can use ~ for home directory
     and environment variables will be expanded.
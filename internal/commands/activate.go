@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/neverprepared/shell-profile-manager/internal/ui"
+)
+
+// activeProfileVar is set while a profile is activated without direnv
+const activeProfileVar = "SHELL_PROFILER_ACTIVE"
+
+type ActivateOptions struct {
+	ProfileName string
+	Shell       string
+}
+
+// ActivateProfile prints a script that loads the profile environment into the current
+// shell when eval'd, and defines deactivate to restore the previous values
+func ActivateProfile(profilesDir string, opts ActivateOptions) error {
+	if opts.ProfileName == "" {
+		return fmt.Errorf("profile name is required")
+	}
+
+	shell, err := scriptShell(opts.Shell)
+	if err != nil {
+		return err
+	}
+
+	profileDir := filepath.Join(profilesDir, opts.ProfileName)
+	if _, err := os.Stat(filepath.Join(profileDir, ".envrc")); os.IsNotExist(err) {
+		return fmt.Errorf("profile '%s' does not exist at: %s", opts.ProfileName, profileDir)
+	}
+	warnProfileSchema(profileDir)
+
+	env, err := loadProfileEnv(profileDir)
+	if err != nil {
+		return fmt.Errorf("failed to load profile environment: %w", err)
+	}
+	env.set(activeProfileVar, opts.ProfileName)
+
+	// The script goes to stdout for eval; everything else goes to stderr
+	for _, line := range env.skipped {
+		fmt.Fprintf(os.Stderr, "%sWARNING: skipped (needs direnv): %s%s\n", ui.ColorYellow, line, ui.ColorReset)
+	}
+
+	fmt.Print(activationScript(shell, opts.ProfileName, env))
+	return nil
+}
+
+// scriptShell returns the shell to generate a script for: the requested one, or the
+// user's $SHELL
+func scriptShell(requested string) (string, error) {
+	shell := requested
+	if shell == "" {
+		shell = filepath.Base(os.Getenv("SHELL"))
+		switch shell {
+		case "bash", "zsh", "fish":
+		default:
+			shell = "bash"
+		}
+	}
+	switch shell {
+	case "bash", "zsh", "fish":
+		return shell, nil
+	default:
+		return "", fmt.Errorf("unsupported shell: %s (must be: bash, zsh, or fish)", shell)
+	}
+}
+
+// activationScript returns the eval-able activation script for the given shell
+func activationScript(shell, profileName string, env *profileEnv) string {
+	names := make([]string, 0, len(env.order))
+	for _, name := range env.order {
+		if name != "PATH" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	addedPaths := env.addedPaths()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# shell-profiler activation for profile: %s\n", profileName)
+	for _, line := range env.skipped {
+		fmt.Fprintf(&b, "# skipped (needs direnv): %s\n", line)
+	}
+
+	if shell == "fish" {
+		fmt.Fprintf(&b, "if set -q %s; and functions -q deactivate\n    deactivate\nend\n", activeProfileVar)
+		for _, name := range names {
+			fmt.Fprintf(&b, "if set -q %[1]s; set -g _SP_OLD_%[1]s $%[1]s; else; set -e _SP_OLD_%[1]s; end\n", name)
+		}
+		b.WriteString(shellExports("fish", env))
+
+		b.WriteString("function deactivate --description 'Leave the shell-profiler profile'\n")
+		for _, name := range names {
+			fmt.Fprintf(&b, "    if set -q _SP_OLD_%[1]s; set -gx %[1]s $_SP_OLD_%[1]s; else; set -e %[1]s; end\n", name)
+			fmt.Fprintf(&b, "    set -e _SP_OLD_%s\n", name)
+		}
+		if len(addedPaths) > 0 {
+			var dirs []string
+			for _, dir := range addedPaths {
+				dirs = append(dirs, fishQuote(dir))
+			}
+			fmt.Fprintf(&b, "    for dir in %s\n", strings.Join(dirs, " "))
+			b.WriteString("        set -l idx (contains -i -- $dir $PATH); and set -e PATH[$idx]\n")
+			b.WriteString("    end\n")
+		}
+		b.WriteString("    functions -e deactivate\n")
+		b.WriteString("end\n")
+		return b.String()
+	}
+
+	// bash and zsh
+	fmt.Fprintf(&b, "if [ -n \"${%s:-}\" ] && command -v deactivate >/dev/null 2>&1; then\n    deactivate\nfi\n", activeProfileVar)
+	for _, name := range names {
+		fmt.Fprintf(&b, "if [ -n \"${%[1]s+x}\" ]; then _SP_OLD_%[1]s=\"$%[1]s\"; else unset _SP_OLD_%[1]s; fi\n", name)
+	}
+	b.WriteString(shellExports(shell, env))
+
+	b.WriteString("deactivate() {\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "    if [ -n \"${_SP_OLD_%[1]s+x}\" ]; then export %[1]s=\"$_SP_OLD_%[1]s\"; else unset %[1]s; fi\n", name)
+		fmt.Fprintf(&b, "    unset _SP_OLD_%s\n", name)
+	}
+	if len(addedPaths) > 0 {
+		b.WriteString("    _sp_path=\":$PATH:\"\n")
+		for _, dir := range addedPaths {
+			// The pattern is always quoted so that slashes in it are not separators
+			fmt.Fprintf(&b, "    _sp_path=${_sp_path//'%s'/:}\n", strings.ReplaceAll(":"+dir+":", "'", `'\''`))
+		}
+		b.WriteString("    _sp_path=\"${_sp_path#:}\"\n")
+		b.WriteString("    export PATH=\"${_sp_path%:}\"\n")
+		b.WriteString("    unset _sp_path\n")
+	}
+	b.WriteString("    unset -f deactivate\n")
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,507 @@
+package commands
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/neverprepared/shell-profile-manager/internal/config"
+	"github.com/neverprepared/shell-profile-manager/internal/ui"
+)
+
+const (
+	archiveMetadataName = "archive.json"
+	archiveProfileName  = "profile.tar.gz"
+	archiveSecretsName  = "secrets.tar.gz.enc"
+
+	secretsIncluded  = "included"
+	secretsExcluded  = "excluded"
+	secretsEncrypted = "encrypted"
+)
+
+type ArchiveOptions struct {
+	ProfileName    string
+	NewName        string // unarchive under a different name
+	ExcludeSecrets bool
+	EncryptSecrets bool
+	Force          bool
+	DryRun         bool
+}
+
+// archiveMetadata is stored next to an archived profile
+type archiveMetadata struct {
+	Name         string    `json:"name"`
+	OriginalPath string    `json:"original_path"`
+	Archived     time.Time `json:"archived"`
+	GitRemote    string    `json:"git_remote,omitempty"`
+	Secrets      string    `json:"secrets"`
+	SecretFiles  []string  `json:"secret_files,omitempty"`
+	Files        int       `json:"files"`
+	Size         int64     `json:"size"`
+	SHA256       string    `json:"sha256"`
+
+	Path string `json:"-"`
+}
+
+// ArchiveProfile compresses a dormant profile into the archive store and removes it from the profiles directory
+func ArchiveProfile(profilesDir string, opts ArchiveOptions) error {
+	if opts.ExcludeSecrets && opts.EncryptSecrets {
+		return fmt.Errorf("--exclude-secrets and --encrypt-secrets cannot be combined")
+	}
+
+	// If no profile name provided, show interactive selection
+	if opts.ProfileName == "" {
+		selected, err := selectProfile(profilesDir, "Select profile to archive:")
+		if err != nil {
+			return err
+		}
+		opts.ProfileName = selected
+	}
+
+	profileDir := filepath.Join(profilesDir, opts.ProfileName)
+
+	// Check if profile exists
+	if _, err := os.Stat(filepath.Join(profileDir, ".envrc")); os.IsNotExist(err) {
+		return fmt.Errorf("profile '%s' does not exist at: %s", opts.ProfileName, profileDir)
+	}
+	if isProfileActive(profileDir, opts.ProfileName) {
+		return fmt.Errorf("profile '%s' is currently active; leave the profile directory and try again", opts.ProfileName)
+	}
+
+	archiveDir, err := profileArchiveDir(opts.ProfileName)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(archiveDir); err == nil && !opts.Force {
+		return fmt.Errorf("an archive of '%s' already exists (use --force to replace it)", opts.ProfileName)
+	}
+
+	absDir, err := filepath.Abs(profileDir)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	// Split the profile into regular and sensitive files. Project checkouts and history are
+	// archived as they are: the patterns describe profile files, not the user's projects.
+	all, err := collectFiles(profileDir, nil)
+	if err != nil {
+		return fmt.Errorf("failed to read profile: %w", err)
+	}
+	var public, secret []manifestFile
+	var secretPaths []string
+	var size int64
+	for _, f := range all {
+		size += f.Size
+		if !inProjectTree(f.Path) && isSensitivePath(f.Path, f.Dir) {
+			secret = append(secret, f)
+			if !f.Dir {
+				secretPaths = append(secretPaths, f.Path)
+			}
+		} else {
+			public = append(public, f)
+		}
+	}
+
+	mode := secretsIncluded
+	switch {
+	case opts.ExcludeSecrets:
+		mode = secretsExcluded
+	case opts.EncryptSecrets:
+		mode = secretsEncrypted
+	default:
+		public = all
+	}
+
+	ui.PrintInfo(fmt.Sprintf("Profile to archive: %s", opts.ProfileName))
+	fmt.Printf("  Location: %s\n", profileDir)
+	fmt.Printf("  Files: %d (%s)\n", countFiles(all), formatFileSize(size))
+	fmt.Printf("  Archive: %s\n", archiveDir)
+	if len(secretPaths) > 0 {
+		switch mode {
+		case secretsExcluded:
+			fmt.Printf("  %s⚠ %d sensitive file(s) will be destroyed, not archived%s\n", ui.ColorYellow, len(secretPaths), ui.ColorReset)
+		case secretsEncrypted:
+			fmt.Printf("  %d sensitive file(s) will be encrypted with a passphrase\n", len(secretPaths))
+		default:
+			fmt.Printf("  %s⚠ %d sensitive file(s) will be archived unencrypted%s\n", ui.ColorYellow, len(secretPaths), ui.ColorReset)
+		}
+		for _, path := range secretPaths {
+			fmt.Printf("    - %s\n", path)
+		}
+	}
+
+	// Dry run
+	if opts.DryRun {
+		fmt.Println()
+		ui.PrintInfo("DRY RUN - Nothing will be archived")
+		return nil
+	}
+
+	// Confirmation
+	if !opts.Force {
+		confirmed, err := ui.Confirm(fmt.Sprintf("Archive profile '%s'?", opts.ProfileName), true)
+		if err != nil {
+			return fmt.Errorf("failed to get confirmation: %w", err)
+		}
+		if !confirmed {
+			ui.PrintInfo("Archive cancelled")
+			return nil
+		}
+	}
+
+	var passphrase string
+	if mode == secretsEncrypted && len(secret) > 0 {
+		if passphrase, err = readPassphrase("Passphrase for the archived secrets:", true); err != nil {
+			return err
+		}
+	}
+
+	ui.PrintInfo(fmt.Sprintf("Archiving profile: %s", opts.ProfileName))
+
+	// Write into a temporary directory so a failed archive leaves no partial store behind
+	tmpDir := filepath.Join(filepath.Dir(archiveDir), "."+opts.ProfileName+".tmp")
+	if err := os.RemoveAll(tmpDir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(tmpDir, 0700); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	metadata := archiveMetadata{
+		Name:         opts.ProfileName,
+		OriginalPath: absDir,
+		Archived:     time.Now().UTC(),
+		GitRemote:    gitRemoteURL(profileDir),
+		Secrets:      mode,
+		SecretFiles:  secretPaths,
+		Files:        countFiles(all),
+		Size:         size,
+	}
+
+	// The profile, with its project checkouts, is streamed to disk and checksummed on the way
+	profileArchive, err := os.OpenFile(filepath.Join(tmpDir, archiveProfileName), os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	hash := sha256.New()
+	if err := writeTarball(io.MultiWriter(profileArchive, hash), profileDir, public, archiveManifest(opts.ProfileName, "archive", public)); err != nil {
+		profileArchive.Close()
+		return fmt.Errorf("failed to archive profile: %w", err)
+	}
+	if err := profileArchive.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	metadata.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	if passphrase != "" {
+		var secretsArchive bytes.Buffer
+		if err := writeTarball(&secretsArchive, profileDir, secret, archiveManifest(opts.ProfileName, "archive", secret)); err != nil {
+			return fmt.Errorf("failed to archive secrets: %w", err)
+		}
+		encrypted, err := encryptSecrets(secretsArchive.Bytes(), passphrase)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt secrets: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(tmpDir, archiveSecretsName), encrypted, 0600); err != nil {
+			return fmt.Errorf("failed to write secrets: %w", err)
+		}
+	}
+
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, archiveMetadataName), append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write archive metadata: %w", err)
+	}
+
+	if err := os.RemoveAll(archiveDir); err != nil {
+		return fmt.Errorf("failed to replace existing archive: %w", err)
+	}
+	if err := os.Rename(tmpDir, archiveDir); err != nil {
+		return fmt.Errorf("failed to store archive: %w", err)
+	}
+
+	// Secrets that were not archived in the clear are overwritten before the profile is removed
+	if mode != secretsIncluded {
+		if results, complete := wipeFiles(profileDir, secretPaths); !complete {
+			for _, r := range results {
+				if r.Status != "overwritten" {
+					ui.PrintWarning(fmt.Sprintf("Failed to overwrite %s: %s", r.Path, r.Status))
+				}
+			}
+		}
+	}
+	if err := os.RemoveAll(profileDir); err != nil {
+		return fmt.Errorf("failed to remove archived profile: %w", err)
+	}
+
+	ui.PrintSuccess(fmt.Sprintf("Profile archived: %s", opts.ProfileName))
+	fmt.Printf("  Archive: %s\n", archiveDir)
+	fmt.Printf("  Bring it back with: shell-profiler unarchive %s\n", opts.ProfileName)
+	return nil
+}
+
+// UnarchiveProfile restores an archived profile to its original location
+func UnarchiveProfile(profilesDir string, opts ArchiveOptions) error {
+	archives, err := listArchives()
+	if err != nil {
+		return err
+	}
+	if len(archives) == 0 {
+		return fmt.Errorf("no archived profiles found")
+	}
+
+	// If no profile name provided, show interactive selection
+	if opts.ProfileName == "" {
+		names := make([]string, len(archives))
+		for i, a := range archives {
+			names[i] = a.Name
+		}
+		selected, err := ui.SelectProfile(names, "Select profile to unarchive:")
+		if err != nil {
+			return err
+		}
+		opts.ProfileName = selected
+	}
+
+	var metadata archiveMetadata
+	found := false
+	for _, a := range archives {
+		if a.Name == opts.ProfileName {
+			metadata, found = a, true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("profile '%s' is not archived", opts.ProfileName)
+	}
+
+	// Restore to the original location unless a new name is given
+	targetDir := metadata.OriginalPath
+	targetName := metadata.Name
+	if opts.NewName != "" {
+		if err := validateProfileName(opts.NewName); err != nil {
+			return err
+		}
+		targetName = opts.NewName
+		if targetDir, err = filepath.Abs(filepath.Join(profilesDir, opts.NewName)); err != nil {
+			return fmt.Errorf("failed to get absolute path: %w", err)
+		}
+	}
+	if _, err := os.Stat(targetDir); err == nil {
+		return fmt.Errorf("'%s' already exists (use --as <name> to unarchive under another name)", targetDir)
+	}
+
+	ui.PrintInfo(fmt.Sprintf("Unarchiving profile: %s", metadata.Name))
+	fmt.Printf("  Archived: %s\n", metadata.Archived.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("  To:       %s\n", targetDir)
+	if metadata.Secrets == secretsExcluded && len(metadata.SecretFiles) > 0 {
+		fmt.Printf("  %s⚠ %d sensitive file(s) were not archived and must be recreated%s\n", ui.ColorYellow, len(metadata.SecretFiles), ui.ColorReset)
+	}
+
+	if opts.DryRun {
+		fmt.Println()
+		ui.PrintInfo("DRY RUN - Nothing will be unarchived")
+		return nil
+	}
+
+	// Verify the archive before touching the target
+	profileArchive := filepath.Join(metadata.Path, archiveProfileName)
+	sum, err := fileChecksum(profileArchive)
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+	if metadata.SHA256 != "" && sum != metadata.SHA256 {
+		return fmt.Errorf("archive checksum mismatch: %s", profileArchive)
+	}
+
+	// Decrypt secrets first so a wrong passphrase fails early
+	var secretsArchive []byte
+	secretsPath := filepath.Join(metadata.Path, archiveSecretsName)
+	if encrypted, err := os.ReadFile(secretsPath); err == nil {
+		passphrase, err := readPassphrase("Passphrase for the archived secrets:", false)
+		if err != nil {
+			return err
+		}
+		if secretsArchive, err = decryptSecrets(encrypted, passphrase); err != nil {
+			return fmt.Errorf("failed to decrypt secrets: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read secrets: %w", err)
+	}
+
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return fmt.Errorf("failed to create profile directory: %w", err)
+	}
+	archive, err := os.Open(profileArchive)
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+	err = extractTarball(archive, targetDir, nil)
+	archive.Close()
+	if err != nil {
+		os.RemoveAll(targetDir) //nolint:errcheck // Best-effort cleanup, the archive is kept
+		return fmt.Errorf("failed to extract archive: %w", err)
+	}
+	if secretsArchive != nil {
+		if err := extractTarball(bytes.NewReader(secretsArchive), targetDir, nil); err != nil {
+			os.RemoveAll(targetDir) //nolint:errcheck // Best-effort cleanup, the archive is kept
+			return fmt.Errorf("failed to extract secrets: %w", err)
+		}
+	}
+
+	// A profile unarchived under a new name gets its references rewritten, as with rename
+	if targetName != metadata.Name || targetDir != metadata.OriginalPath {
+		rewrites, err := planReferenceRewrites(targetDir, metadata.Name, targetName, metadata.OriginalPath, targetDir)
+		if err != nil {
+			return err
+		}
+		if err := applyRewrites(targetDir, rewrites); err != nil {
+			return err
+		}
+	}
+	if targetName != metadata.Name {
+		if err := editProfileMetadata(targetDir, func(m *profileMetadata) {
+			m.Name = targetName
+			m.Updated = time.Now().UTC().Truncate(time.Second)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := os.RemoveAll(metadata.Path); err != nil {
+		ui.PrintWarning(fmt.Sprintf("Failed to remove archive: %v", err))
+	}
+
+	ui.PrintSuccess(fmt.Sprintf("Profile unarchived: %s", targetName))
+	fmt.Println()
+	ui.PrintInfo("Next steps:")
+	fmt.Printf("  1. cd %s\n", targetDir)
+	fmt.Println("  2. direnv allow")
+	return nil
+}
+
+// listArchivedProfiles prints the archived profiles for list --archived
+func listArchivedProfiles() error {
+	archives, err := listArchives()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s=== Archived Profiles ===%s\n", ui.ColorBlue, ui.ColorReset)
+	fmt.Println()
+
+	if len(archives) == 0 {
+		fmt.Println("No archived profiles")
+		return nil
+	}
+
+	for _, a := range archives {
+		fmt.Printf("%s○ %s%s\n", ui.ColorCyan, a.Name, ui.ColorReset)
+		fmt.Printf("  Archived: %s\n", a.Archived.Local().Format("2006-01-02 15:04:05"))
+		fmt.Printf("  Location: %s\n", a.OriginalPath)
+		if a.GitRemote != "" {
+			fmt.Printf("  Remote:   %s\n", a.GitRemote)
+		}
+		fmt.Printf("  Files:    %d (%s, archive %s)\n", a.Files, formatFileSize(a.Size), formatFileSize(dirSize(a.Path)))
+		fmt.Printf("  Secrets:  %s\n", a.Secrets)
+		fmt.Println()
+	}
+
+	fmt.Printf("%sTotal archived: %d%s\n", ui.ColorBlue, len(archives), ui.ColorReset)
+	fmt.Println("Unarchive with:")
+	fmt.Println("  shell-profiler unarchive <profile>")
+	return nil
+}
+
+// archivesRoot returns the directory holding archived profiles
+func archivesRoot() (string, error) {
+	stateDir, err := config.GetStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "archives"), nil
+}
+
+// profileArchiveDir returns the archive store of one profile
+func profileArchiveDir(profileName string) (string, error) {
+	root, err := archivesRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, profileName), nil
+}
+
+// listArchives returns the metadata of all archived profiles sorted by name
+func listArchives() ([]archiveMetadata, error) {
+	root, err := archivesRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read archives directory: %w", err)
+	}
+
+	var archives []archiveMetadata
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(root, entry.Name())
+		data, err := os.ReadFile(filepath.Join(path, archiveMetadataName))
+		if err != nil {
+			continue
+		}
+		var metadata archiveMetadata
+		if err := json.Unmarshal(data, &metadata); err != nil {
+			continue
+		}
+		metadata.Path = path
+		archives = append(archives, metadata)
+	}
+
+	sort.Slice(archives, func(i, j int) bool { return archives[i].Name < archives[j].Name })
+	return archives, nil
+}
+
+// archiveManifest returns the manifest entry stored inside an archive or bundle tarball
+func archiveManifest(profileName, reason string, files []manifestFile) map[string][]byte {
+	manifest := snapshotManifest{
+		Version: snapshotVersion,
+		Profile: profileName,
+		Reason:  reason,
+		Created: time.Now().UTC(),
+		Files:   files,
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil
+	}
+	return map[string][]byte{snapshotManifestName: data}
+}
+
+// countFiles returns the number of non-directory entries
+func countFiles(files []manifestFile) int {
+	n := 0
+	for _, f := range files {
+		if !f.Dir {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,335 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/neverprepared/shell-profile-manager/internal/ui"
+)
+
+type CloneOptions struct {
+	SourceName  string
+	TargetName  string
+	GitName     string
+	GitEmail    string
+	WithSecrets bool
+	Force       bool
+	DryRun      bool
+}
+
+// CloneProfile creates a new profile from the configuration of an existing one.
+// Credentials, keys and .env values are left behind unless WithSecrets is set.
+func CloneProfile(profilesDir string, opts CloneOptions) error {
+	if opts.SourceName == "" || opts.TargetName == "" {
+		return fmt.Errorf("both the source and the new profile name are required")
+	}
+	if err := validateProfileName(opts.TargetName); err != nil {
+		return err
+	}
+	if opts.SourceName == opts.TargetName {
+		return fmt.Errorf("new profile name is the same as the source")
+	}
+
+	sourceDir := filepath.Join(profilesDir, opts.SourceName)
+	targetDir := filepath.Join(profilesDir, opts.TargetName)
+
+	// Check if source profile exists
+	if _, err := os.Stat(filepath.Join(sourceDir, ".envrc")); os.IsNotExist(err) {
+		return fmt.Errorf("profile '%s' does not exist at: %s", opts.SourceName, sourceDir)
+	}
+
+	// Check if target profile exists
+	if _, err := os.Stat(targetDir); err == nil && !opts.Force {
+		return fmt.Errorf("profile '%s' already exists at: %s (use --force to overwrite)", opts.TargetName, targetDir)
+	}
+
+	sourceAbs, err := filepath.Abs(sourceDir)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	targetAbs, err := filepath.Abs(targetDir)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	createOpts := CreateOptions{
+		ProfileName: opts.TargetName,
+		Template:    profileTemplate(sourceDir),
+		GitName:     opts.GitName,
+		GitEmail:    opts.GitEmail,
+		Tools:       profileToolNames(sourceDir),
+	}
+
+	// Decide what to copy
+	var skipped []string
+	skip := func(relPath string, info os.FileInfo) bool {
+		switch relPath {
+		case ".git", ".backups", "code":
+			// History, legacy backups and checkouts belong to the source profile
+			return true
+		}
+		if !opts.WithSecrets && isSensitivePath(relPath, info.IsDir()) {
+			skipped = append(skipped, relPath)
+			return true
+		}
+		return false
+	}
+
+	files, err := collectFiles(sourceDir, skip)
+	if err != nil {
+		return fmt.Errorf("failed to read source profile: %w", err)
+	}
+
+	// Dry run
+	if opts.DryRun {
+		ui.PrintInfo("DRY RUN - Nothing will be created")
+		fmt.Println()
+		fmt.Printf("Would clone profile '%s' to '%s'\n", opts.SourceName, opts.TargetName)
+		fmt.Printf("  Profile directory: %s\n", targetDir)
+		fmt.Println("  Files to copy:")
+		for _, f := range files {
+			if !f.Dir {
+				fmt.Printf("    - %s\n", f.Path)
+			}
+		}
+		if len(skipped) > 0 {
+			fmt.Println("  Secrets to skip (use --with-secrets to copy):")
+			for _, path := range skipped {
+				fmt.Printf("    - %s\n", path)
+			}
+		}
+		if opts.GitName != "" {
+			fmt.Printf("  Git user.name: %s\n", opts.GitName)
+		}
+		if opts.GitEmail != "" {
+			fmt.Printf("  Git user.email: %s\n", opts.GitEmail)
+		}
+		return nil
+	}
+
+	// Replace an existing profile, keeping a snapshot of it
+	if _, err := os.Stat(targetDir); err == nil {
+		if _, err := createSnapshot(profilesDir, opts.TargetName, "clone"); err != nil {
+			return fmt.Errorf("failed to snapshot existing profile: %w", err)
+		}
+		if err := os.RemoveAll(targetDir); err != nil {
+			return fmt.Errorf("failed to remove existing profile: %w", err)
+		}
+	}
+
+	ui.PrintInfo(fmt.Sprintf("Cloning profile: %s → %s", opts.SourceName, opts.TargetName))
+
+	skipped = nil
+	if err := copyTree(sourceDir, targetDir, skip); err != nil {
+		return fmt.Errorf("failed to copy profile: %w", err)
+	}
+
+	// Make sure the standard directories exist, including those that were skipped
+	if err := createProfileDirs(targetDir, profileTools(targetDir)); err != nil {
+		return err
+	}
+
+	// Point paths, WORKSPACE_PROFILE and headers at the new profile
+	rewrites, err := planReferenceRewrites(targetAbs, opts.SourceName, opts.TargetName, sourceAbs, targetAbs)
+	if err != nil {
+		return err
+	}
+	if err := applyRewrites(targetDir, rewrites); err != nil {
+		return err
+	}
+	if err := stampCreated(filepath.Join(targetDir, ".envrc")); err != nil {
+		return fmt.Errorf("failed to update .envrc: %w", err)
+	}
+	if err := editProfileMetadata(targetDir, func(m *profileMetadata) {
+		now := time.Now().UTC().Truncate(time.Second)
+		m.Name, m.Created, m.Updated = opts.TargetName, now, now
+	}); err != nil {
+		return err
+	}
+
+	// Regenerate .env without the source's secret values
+	envPath := filepath.Join(targetDir, ".env")
+	if _, err := os.Stat(envPath); os.IsNotExist(err) {
+		if err := createProfileFile(targetDir, createOpts, ".env"); err != nil {
+			return fmt.Errorf("failed to create .env: %w", err)
+		}
+		if err := appendEnvPlaceholders(envPath, filepath.Join(sourceDir, ".env"), opts.SourceName); err != nil {
+			return fmt.Errorf("failed to update .env: %w", err)
+		}
+	}
+
+	// Create known_hosts
+	knownHostsPath := filepath.Join(targetDir, ".ssh/known_hosts")
+	if _, err := os.Stat(knownHostsPath); os.IsNotExist(err) {
+		if err := os.WriteFile(knownHostsPath, []byte{}, 0600); err != nil {
+			return fmt.Errorf("failed to create known_hosts: %w", err)
+		}
+	}
+
+	// Fill in any standard files the source profile is missing
+	if err := createMissingProfileFiles(targetDir, createOpts); err != nil {
+		return err
+	}
+
+	// Override the git identity
+	for key, value := range map[string]string{"user.name": opts.GitName, "user.email": opts.GitEmail} {
+		if value == "" {
+			continue
+		}
+		cmd := exec.Command("git", "config", "--file", filepath.Join(targetDir, ".gitconfig"), key, value)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to set %s: %w\n%s", key, err, string(output))
+		}
+	}
+
+	ui.PrintSuccess(fmt.Sprintf("Profile cloned successfully: %s", opts.TargetName))
+	if len(skipped) > 0 {
+		fmt.Println()
+		ui.PrintInfo("Secrets not copied (use --with-secrets to include them):")
+		for _, path := range skipped {
+			fmt.Printf("  - %s\n", path)
+		}
+	}
+	fmt.Println()
+	ui.PrintInfo("Next steps:")
+	fmt.Printf("  1. cd %s\n", targetDir)
+	fmt.Println("  2. Fill in the values in .env")
+	fmt.Println("  3. direnv allow")
+	fmt.Println("  4. Check the git identity in .gitconfig")
+	fmt.Println()
+	ui.PrintInfo(fmt.Sprintf("Profile location: %s", targetDir))
+
+	return nil
+}
+
+// profileTemplate returns the template a profile was created from
+func profileTemplate(profileDir string) string {
+	return profileMeta(profileDir).Template
+}
+
+// stampCreated updates the "# Created:" header of a generated file to the current time
+func stampCreated(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	created := time.Now().UTC().Format(createdLayout)
+	re := regexp.MustCompile(`(?m)^# Created: .*$`)
+	updated := re.ReplaceAllLiteralString(string(content), "# Created: "+created)
+	if updated == string(content) {
+		return nil
+	}
+	return os.WriteFile(path, []byte(updated), info.Mode().Perm())
+}
+
+// appendEnvPlaceholders adds the variables of the source .env that the generated .env lacks.
+// Values are only kept when they are paths inside the workspace; everything else is left empty.
+func appendEnvPlaceholders(envPath, sourceEnvPath, sourceName string) error {
+	sourceVars, err := readEnvVars(sourceEnvPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	existing, err := readEnvVars(envPath)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]bool, len(existing))
+	for _, v := range existing {
+		known[v[0]] = true
+	}
+
+	var lines []string
+	for _, v := range sourceVars {
+		key, value := v[0], v[1]
+		if known[key] {
+			continue
+		}
+		known[key] = true
+		if !isEnvPathValue(value) {
+			value = `""`
+		}
+		lines = append(lines, fmt.Sprintf("%s=%s", key, value))
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	file, err := os.OpenFile(envPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(file, "\n# Variables from profile '%s' (values were not copied)\n", sourceName)
+	for _, line := range lines {
+		fmt.Fprintln(file, line)
+	}
+	return file.Close()
+}
+
+// isEnvPathValue reports whether a dotenv value is a path below the workspace or home directory
+// rather than a secret
+func isEnvPathValue(value string) bool {
+	value = strings.TrimLeft(value, `"'`)
+	for _, prefix := range []string{"$WORKSPACE_HOME", "${WORKSPACE_HOME}", "$HOME", "${HOME}"} {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// readEnvVars returns the KEY=value pairs of a dotenv file in order
+func readEnvVars(path string) ([][2]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var vars [][2]string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		vars = append(vars, [2]string{strings.TrimSpace(key), strings.TrimSpace(value)})
+	}
+	return vars, scanner.Err()
+}
+
+// createMissingProfileFiles writes the standard profile files that do not exist yet
+func createMissingProfileFiles(profileDir string, opts CreateOptions) error {
+	files, err := renderProfileFiles(profileDir, opts)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if _, err := os.Stat(filepath.Join(profileDir, filepath.FromSlash(file.Path))); err == nil {
+			continue
+		}
+		if err := writeProfileFile(profileDir, file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -5,8 +5,13 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/neverprepared/shell-profile-manager/internal/config"
+	"github.com/neverprepared/shell-profile-manager/internal/templates"
+	"github.com/neverprepared/shell-profile-manager/internal/tools"
 	"github.com/neverprepared/shell-profile-manager/internal/ui"
 )
 
@@ -20,30 +25,42 @@ type CreateOptions struct {
 	DryRun      bool
 	InitGit     bool
 	GitRemote   string
+	// Vars holds values for the template's variables, by name
+	Vars map[string]string
+	// Tools names the tools to isolate in the profile; nil means every tool
+	Tools []string
+	// Description and Tags are recorded in the profile's metadata
+	Description string
+	Tags        []string
 }
 
-func CreateProfile(profilesDir string, opts CreateOptions) error {
-	profileDir := filepath.Join(profilesDir, opts.ProfileName)
-
-	// Validate profile name
-	if opts.ProfileName == "" {
+// validateProfileName checks that a profile name is usable as a directory name
+func validateProfileName(name string) error {
+	if name == "" {
 		return fmt.Errorf("profile name is required")
 	}
 
-	matched, err := regexp.MatchString(`^[a-zA-Z0-9_-]+$`, opts.ProfileName)
+	matched, err := regexp.MatchString(`^[a-zA-Z0-9_-]+$`, name)
 	if err != nil {
 		return fmt.Errorf("failed to validate profile name: %w", err)
 	}
 	if !matched {
 		return fmt.Errorf("profile name can only contain letters, numbers, hyphens, and underscores")
 	}
+	return nil
+}
 
-	// Validate template
-	validTemplates := map[string]bool{
-		"basic": true, "personal": true, "work": true, "client": true,
+func CreateProfile(profilesDir string, opts CreateOptions) error {
+	profileDir := filepath.Join(profilesDir, opts.ProfileName)
+
+	// Validate profile name
+	if err := validateProfileName(opts.ProfileName); err != nil {
+		return err
 	}
-	if !validTemplates[opts.Template] {
-		return fmt.Errorf("invalid template: %s (must be: basic, personal, work, or client)", opts.Template)
+
+	// Validate template
+	if _, err := templates.Find(templatesDir(), opts.Template); err != nil {
+		return err
 	}
 
 	// Check if profile exists
@@ -58,104 +75,81 @@ func CreateProfile(profilesDir string, opts CreateOptions) error {
 		}
 	}
 
+	// Check the template's required variables and the tools
+	if err := checkRequiredVars(opts); err != nil {
+		return err
+	}
+	enabled, err := enabledTools(opts.Tools)
+	if err != nil {
+		return err
+	}
+
+	// Render the template first, so that errors in it leave nothing behind
+	files, err := renderProfileFiles(profileDir, opts)
+	if err != nil {
+		return err
+	}
+
 	// Dry run
 	if opts.DryRun {
 		ui.PrintInfo("DRY RUN - Nothing will be created")
 		fmt.Println()
 		fmt.Println("Would create:")
 		fmt.Printf("  Profile directory: %s\n", profileDir)
-		fmt.Printf("  .envrc file with WORKSPACE_PROFILE=%s\n", opts.ProfileName)
-		fmt.Printf("  .gitconfig with template: %s\n", opts.Template)
+		fmt.Printf("  Template: %s\n", opts.Template)
+		fmt.Printf("  Tools: %s\n", strings.Join(tools.ToolNames(enabled), ", "))
 		if opts.GitName != "" {
 			fmt.Printf("  Git user.name: %s\n", opts.GitName)
 		}
 		if opts.GitEmail != "" {
 			fmt.Printf("  Git user.email: %s\n", opts.GitEmail)
 		}
+		if err := printTemplateVars(opts); err != nil {
+			return err
+		}
+		fmt.Println()
+		fmt.Println("Files:")
+		for _, file := range files {
+			fmt.Printf("  %-30s %s\n", file.Path, describeLayers(file.Layers))
+		}
+		fmt.Printf("  %-30s %s\n", metadataFile, "profile metadata")
 		return nil
 	}
 
-	// Create profile
-	ui.PrintInfo(fmt.Sprintf("Creating profile: %s (template: %s)", opts.ProfileName, opts.Template))
-
-	// Create directories
-	dirs := []string{
-		".config/1Password",
-		".config/claude",
-		".config/gemini",
-		".ssh",
-		".aws",
-		".azure",
-		".gcloud",
-		".kube",
-		"bin",
-		"code",
-	}
-
-	for _, dir := range dirs {
-		fullPath := filepath.Join(profileDir, dir)
-		if err := os.MkdirAll(fullPath, 0755); err != nil {
-			return fmt.Errorf("failed to create directory %s: %w", fullPath, err)
+	// Snapshot an existing profile before overwriting it
+	if _, err := os.Stat(profileDir); err == nil && opts.Force {
+		if _, err := createSnapshot(profilesDir, opts.ProfileName, "create"); err != nil {
+			return fmt.Errorf("failed to snapshot existing profile: %w", err)
 		}
 	}
 
-	// Set SSH directory permissions
-	sshDir := filepath.Join(profileDir, ".ssh")
-	if err := os.Chmod(sshDir, 0700); err != nil {
-		return fmt.Errorf("failed to set SSH directory permissions: %w", err)
-	}
-
-	// Create .envrc
-	if err := createEnvrc(profileDir, opts); err != nil {
-		return fmt.Errorf("failed to create .envrc: %w", err)
-	}
-
-	// Create .env with tool-specific environment variables
-	if err := createEnvFile(profileDir, opts); err != nil {
-		return fmt.Errorf("failed to create .env: %w", err)
-	}
-
-	// Create .gitconfig
-	if err := createGitconfig(profileDir, opts); err != nil {
-		return fmt.Errorf("failed to create .gitconfig: %w", err)
-	}
+	// Create profile
+	ui.PrintInfo(fmt.Sprintf("Creating profile: %s (template: %s)", opts.ProfileName, opts.Template))
 
-	// Create SSH config (only if it doesn't exist)
-	if err := createSSHConfig(profileDir, opts); err != nil {
-		return fmt.Errorf("failed to create SSH config: %w", err)
+	// Create directories
+	if err := createProfileDirs(profileDir, enabled); err != nil {
+		return err
 	}
 
-	// Create known_hosts
-	knownHostsPath := filepath.Join(profileDir, ".ssh/known_hosts")
-	if _, err := os.Stat(knownHostsPath); os.IsNotExist(err) {
-		if err := os.WriteFile(knownHostsPath, []byte{}, 0600); err != nil {
-			return fmt.Errorf("failed to create known_hosts: %w", err)
+	// Create the files from the template
+	for _, file := range files {
+		// Keep an existing SSH config, which holds the user's hosts and keys
+		if file.Path == ".ssh/config" {
+			if _, err := os.Stat(filepath.Join(profileDir, ".ssh/config")); err == nil {
+				ui.PrintWarning("SSH config already exists, skipping creation")
+				continue
+			}
+		}
+		if err := writeProfileFile(profileDir, file); err != nil {
+			return err
 		}
 	}
 
-	// Create 1Password config
-	if err := create1PasswordConfig(profileDir, opts); err != nil {
-		return fmt.Errorf("failed to create 1Password config: %w", err)
-	}
-
-	// Create SSH wrapper
-	if err := createSSHWrapper(profileDir); err != nil {
-		return fmt.Errorf("failed to create SSH wrapper: %w", err)
-	}
-
-	// Create .gitignore
-	if err := createGitignore(profileDir); err != nil {
-		return fmt.Errorf("failed to create .gitignore: %w", err)
-	}
-
-	// Create README
-	if err := createREADME(profileDir, opts); err != nil {
-		return fmt.Errorf("failed to create README: %w", err)
-	}
-
-	// Create .env.example
-	if err := createEnvExample(profileDir); err != nil {
-		return fmt.Errorf("failed to create .env.example: %w", err)
+	// Record how the profile was made, and the managed sections to merge updates against
+	meta := newProfileMetadata(opts, enabled)
+	recordManagedSections(profileDir, &meta)
+	if err := saveProfileMetadata(profileDir, meta); err != nil {
+		return err
 	}
 
 	// Initialize git if requested
@@ -184,7 +178,16 @@ func CreateProfile(profilesDir string, opts CreateOptions) error {
 
 func interactiveSetup(opts *CreateOptions) error {
 	// Template selection
-	template, err := ui.SelectTemplate()
+	available, err := templates.Discover(templatesDir())
+	if err != nil {
+		return err
+	}
+	var names, descriptions []string
+	for _, t := range available {
+		names = append(names, t.Name)
+		descriptions = append(descriptions, t.Description)
+	}
+	template, err := ui.SelectTemplate(names, descriptions, opts.Template)
 	if err != nil {
 		return fmt.Errorf("failed to select template: %w", err)
 	}
@@ -207,6 +210,16 @@ func interactiveSetup(opts *CreateOptions) error {
 		opts.GitEmail = gitEmail
 	}
 
+	// Template variables
+	if err := promptTemplateVars(opts); err != nil {
+		return err
+	}
+
+	// Tools
+	if err := promptTools(opts); err != nil {
+		return err
+	}
+
 	// Ask about git initialization
 	initGit, err := ui.Confirm("Initialize git repository after creation?", false)
 	if err != nil {
@@ -227,595 +240,273 @@ func interactiveSetup(opts *CreateOptions) error {
 	return nil
 }
 
-func createEnvrc(profileDir string, opts CreateOptions) error {
-	ui.PrintInfo("Creating .envrc...")
-
-	created := time.Now().UTC().Format("2006-01-02 15:04:05 UTC")
-
-	envrcContent := fmt.Sprintf(`#!/usr/bin/env bash
-# Workspace profile: %s
-# Template: %s
-# Created: %s
-
-# Workspace identification
-export WORKSPACE_PROFILE="%s"
-export WORKSPACE_HOME="$PWD"
-
-# Add custom bin directory to PATH (before system paths)
-# The bin/ssh wrapper uses the profile-specific SSH config
-# Git will automatically use bin/ssh since it's first in PATH
-PATH_add bin
-
-# Load global profile settings (exports only)
-# Environment variables work with direnv, aliases and functions do not
-GLOBAL_DIR="$(cd "$(dirname "$PWD")/.global" 2>/dev/null && pwd)"
-if [[ -d "$GLOBAL_DIR" ]]; then
-    # Source exports (environment variables work with direnv)
-    if [[ -f "$GLOBAL_DIR/exports.sh" && -r "$GLOBAL_DIR/exports.sh" ]]; then
-        source "$GLOBAL_DIR/exports.sh"
-    fi
-fi
-
-# Load environment variables from .env file
-# Tool-specific paths and secrets belong in .env, not here
-dotenv_if_exists .env
-
-# Load local overrides
-dotenv_if_exists .envrc.local
-
-# Welcome message
-log_status "Loaded workspace profile: $WORKSPACE_PROFILE"
-`, opts.ProfileName, opts.Template, created, opts.ProfileName)
+// promptTools asks which tools to isolate in the profile, starting from opts.Tools
+func promptTools(opts *CreateOptions) error {
+	current, err := enabledTools(opts.Tools)
+	if err != nil {
+		return err
+	}
+	var options, defaults []string
+	for _, t := range tools.All() {
+		option := t.Name + " - " + t.Description
+		options = append(options, option)
+		if hasTool(current, t.Name) {
+			defaults = append(defaults, option)
+		}
+	}
 
-	envrcPath := filepath.Join(profileDir, ".envrc")
-	return os.WriteFile(envrcPath, []byte(envrcContent), 0644)
+	selected, err := ui.MultiSelect("Tools to isolate in this profile:", options, defaults)
+	if err != nil {
+		return fmt.Errorf("failed to select tools: %w", err)
+	}
+	opts.Tools = []string{}
+	for _, option := range selected {
+		name, _, _ := strings.Cut(option, " - ")
+		opts.Tools = append(opts.Tools, name)
+	}
+	return nil
 }
 
-func createEnvFile(profileDir string, opts CreateOptions) error {
-	ui.PrintInfo("Creating .env...")
-
-	envContent := fmt.Sprintf(`# Environment variables for workspace profile: %s
-# Template: %s
-#
-# This file is loaded by direnv via dotenv_if_exists in .envrc
-# Add tool-specific paths and secrets here (not in .envrc)
-
-# Git configuration
-GIT_CONFIG_GLOBAL="$WORKSPACE_HOME/.gitconfig"
-
-# SSH configuration
-# Use workspace-specific SSH config instead of $HOME/.ssh/config
-GIT_SSH_COMMAND="ssh -F $WORKSPACE_HOME/.ssh/config"
-
-# XDG Base Directory specification
-# Point all XDG-compliant tools to workspace-specific config
-XDG_CONFIG_HOME="$WORKSPACE_HOME/.config"
-
-# 1Password SSH Agent
-# Point to 1Password SSH agent socket for SSH key management
-SSH_AUTH_SOCK="$HOME/Library/Group Containers/2BUA8C4S2C.com.1password/t/agent.sock"
-
-# AWS configuration
-# Point AWS CLI and SDKs to workspace-specific config and credentials
-AWS_CONFIG_FILE="$WORKSPACE_HOME/.aws/config"
-AWS_SHARED_CREDENTIALS_FILE="$WORKSPACE_HOME/.aws/credentials"
-
-# Kubernetes configuration
-# Point kubectl to workspace-specific kubeconfig
-KUBECONFIG="$WORKSPACE_HOME/.kube/config"
-
-# Terraform configuration
-# Use workspace-specific Terraform CLI config
-TF_CLI_CONFIG_FILE="$WORKSPACE_HOME/.terraformrc"
-# Optionally set workspace-specific plugin cache
-# TF_PLUGIN_CACHE_DIR="$WORKSPACE_HOME/.terraform.d/plugin-cache"
-
-# Azure CLI configuration
-# Point Azure CLI to workspace-specific config directory
-AZURE_CONFIG_DIR="$WORKSPACE_HOME/.azure"
-
-# Google Cloud SDK configuration
-# Point gcloud CLI to workspace-specific config directory
-CLOUDSDK_CONFIG="$WORKSPACE_HOME/.gcloud"
-
-# Claude Code configuration
-# Point Claude Code to workspace-specific config directory
-CLAUDE_CONFIG_DIR="$WORKSPACE_HOME/.config/claude"
-
-# Gemini CLI configuration
-# Point Gemini CLI to workspace-specific config directory
-GEMINI_CONFIG_DIR="$WORKSPACE_HOME/.config/gemini"
-`, opts.ProfileName, opts.Template)
-
-	envPath := filepath.Join(profileDir, ".env")
-	return os.WriteFile(envPath, []byte(envContent), 0644)
+// templatesDir returns the configured directory of user-defined templates
+func templatesDir() string {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return ""
+	}
+	return cfg.TemplatesDir
 }
 
-func createGitconfig(profileDir string, opts CreateOptions) error {
-	ui.PrintInfo("Creating .gitconfig...")
-
-	gitName := opts.GitName
-	if gitName == "" {
-		gitName = "Your Name"
-	}
-
-	gitEmail := opts.GitEmail
-	if gitEmail == "" {
-		gitEmail = "your.email@example.com"
-	}
-
-	gitconfigContent := fmt.Sprintf(`# Git configuration for workspace profile: %s
-# Template: %s
-
-[user]
-    name = %s
-    email = %s
-
-[core]
-    editor = vim
-    autocrlf = input
-    whitespace = trailing-space,space-before-tab
-
-[init]
-    defaultBranch = main
-
-[push]
-    default = current
-    autoSetupRemote = true
-
-[pull]
-    rebase = false
-
-[fetch]
-    prune = true
-
-[merge]
-    conflictstyle = diff3
-
-[rebase]
-    autoStash = true
-    autoSquash = true
-
-[diff]
-    algorithm = histogram
-    colorMoved = default
-
-[log]
-    abbrevCommit = true
-    date = iso
-
-[color]
-    ui = auto
-
-[alias]
-    st = status -sb
-    lg = log --graph --pretty=format:'%%Cred%%h%%Creset -%%C(yellow)%%d%%Creset %%s %%Cgreen(%%cr) %%C(bold blue)<%%an>%%Creset' --abbrev-commit
-    br = branch -v
-    co = checkout
-    ci = commit
-    cm = commit -m
-    amend = commit --amend --no-edit
-    last = log -1 HEAD --stat
-    undo = reset HEAD~1 --mixed
-    aliases = config --get-regexp alias
-`, opts.ProfileName, opts.Template, gitName, gitEmail)
-
-	// Add template-specific configuration
-	switch opts.Template {
-	case "personal":
-		gitconfigContent += `
-# Personal project settings
-[commit]
-    verbose = true
-
-[credential]
-    helper = cache --timeout=3600
-`
-	case "work":
-		gitconfigContent += `
-# Work project settings
-[commit]
-    verbose = true
-    # Uncomment to enable GPG signing
-    # gpgsign = true
-
-[credential]
-    helper = cache --timeout=7200
-`
-	case "client":
-		gitconfigContent += `
-# Client project settings
-[commit]
-    verbose = true
-    # gpgsign = true
-
-[credential]
-    helper = cache --timeout=3600
-`
-	}
-
-	gitconfigPath := filepath.Join(profileDir, ".gitconfig")
-	return os.WriteFile(gitconfigPath, []byte(gitconfigContent), 0644)
+// findProfileTemplate returns the template a profile is created from. A profile from
+// another machine may use a template that is not installed here; the base template is
+// used then.
+func findProfileTemplate(name string) (*templates.Template, error) {
+	tmpl, err := templates.Find(templatesDir(), name)
+	if err != nil {
+		ui.PrintWarning(fmt.Sprintf("%v; using the %s template", err, templates.BaseTemplate))
+		return templates.Find(templatesDir(), templates.BaseTemplate)
+	}
+	return tmpl, nil
 }
 
-func createSSHConfig(profileDir string, opts CreateOptions) error {
-	sshConfigPath := filepath.Join(profileDir, ".ssh/config")
-
-	// Check if .ssh/config already exists - if so, skip creation
-	if _, err := os.Stat(sshConfigPath); err == nil {
-		ui.PrintWarning("SSH config already exists, skipping creation")
-		return nil
+// promptTemplateVars asks for the variables of the selected template, offering values
+// given with --var or the variable's default
+func promptTemplateVars(opts *CreateOptions) error {
+	tmpl, err := findProfileTemplate(opts.Template)
+	if err != nil {
+		return err
 	}
-
-	ui.PrintInfo("Creating SSH config...")
-	profileAbsPath, err := filepath.Abs(profileDir)
+	vars, err := tmpl.Variables()
 	if err != nil {
-		return fmt.Errorf("failed to get absolute path: %w", err)
-	}
-
-	sshConfigContent := fmt.Sprintf(`# SSH configuration for workspace profile: %s
-# This config is used instead of ~/.ssh/config when this profile is active
-#
-# Note: SSH config files don't support environment variable expansion.
-# All paths are absolute paths to ensure they work regardless of current directory.
-
-# Default settings for all hosts
-Host *
-    # Use workspace-specific known_hosts file
-    UserKnownHostsFile %s/.ssh/known_hosts
-
-    # Security settings
-    AddKeysToAgent yes
-    IdentitiesOnly yes
-
-    # 1Password SSH Agent (commented out by default)
-    # IdentityAgent "~/Library/Group Containers/2BUA8C4S2C.com.1password/t/agent.sock"
-
-    # Connection settings
-    ServerAliveInterval 60
-    ServerAliveCountMax 3
-
-    # Compression
-    Compression yes
-
-# Example: GitHub with profile-specific key
-# Host github.com
-#     HostName github.com
-#     User git
-#     IdentityFile %s/.ssh/id_ed25519_github
-#     IdentitiesOnly yes
-
-# Example: GitLab with profile-specific key
-# Host gitlab.com
-#     HostName gitlab.com
-#     User git
-#     IdentityFile %s/.ssh/id_ed25519_gitlab
-#     IdentitiesOnly yes
-
-# Example: Personal server
-# Host myserver
-#     HostName example.com
-#     User myuser
-#     Port 22
-#     IdentityFile %s/.ssh/id_ed25519_server
-
-# Example: Jump host (bastion)
-# Host bastion
-#     HostName bastion.example.com
-#     User admin
-#     IdentityFile %s/.ssh/id_ed25519_bastion
-#
-# Host internal-server
-#     HostName internal.example.com
-#     User admin
-#     ProxyJump bastion
-#     IdentityFile %s/.ssh/id_ed25519_internal
-`, opts.ProfileName, profileAbsPath, profileAbsPath, profileAbsPath, profileAbsPath, profileAbsPath, profileAbsPath)
-
-	if err := os.WriteFile(sshConfigPath, []byte(sshConfigContent), 0600); err != nil {
 		return err
 	}
+	if len(vars) > 0 && opts.Vars == nil {
+		opts.Vars = make(map[string]string)
+	}
 
-	ui.PrintSuccess("Created SSH config")
-	return nil
-}
-
-func create1PasswordConfig(profileDir string, opts CreateOptions) error {
-	ui.PrintInfo("Creating 1Password agent configuration...")
-
-	configContent := fmt.Sprintf(`# 1Password SSH Agent configuration for workspace profile: %s
-# This config is used when this profile is active
-
-# SSH Agent configuration
-[[ssh-keys]]
-# Example: Add your SSH keys from 1Password
-# vault = "Private"
-# item = "GitHub SSH Key"
-# account = "my.1password.com"
-
-# Multiple keys can be configured
-# [[ssh-keys]]
-# vault = "Work"
-# item = "Work GitHub Key"
-
-# CLI configuration
-# [cli]
-# Uncomment to configure CLI authentication
-# account = "my.1password.com"
-
-# Notes:
-# - SSH keys stored in 1Password can be used for Git operations
-# - The SSH agent will automatically load keys when profile is active
-# - Use 'op item list' to find vault and item names
-# - See: https://developer.1password.com/docs/ssh/agent/
-`, opts.ProfileName)
-
-	configPath := filepath.Join(profileDir, ".config/1Password/agent.toml")
-	return os.WriteFile(configPath, []byte(configContent), 0600)
-}
-
-func createSSHWrapper(profileDir string) error {
-	ui.PrintInfo("Creating SSH wrapper script...")
-
-	wrapperContent := `#!/usr/bin/env bash
-# SSH wrapper that uses workspace-specific SSH config
-# This script is in PATH before system ssh, ensuring profile isolation
+	for _, v := range vars {
+		current := opts.Vars[v.Name]
+		if current == "" {
+			current = v.Default
+		}
 
-# Get the directory where this script is located
-SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
-WORKSPACE_HOME="$(dirname "$SCRIPT_DIR")"
+		if v.Type == templates.TypeBool {
+			defaultVal, _ := strconv.ParseBool(current)
+			answer, err := ui.Confirm(v.Prompt(), defaultVal)
+			if err != nil {
+				return fmt.Errorf("failed to get %s: %w", v.Name, err)
+			}
+			opts.Vars[v.Name] = strconv.FormatBool(answer)
+			continue
+		}
 
-# Use workspace-specific SSH config
-exec /usr/bin/ssh -F "$WORKSPACE_HOME/.ssh/config" "$@"
-`
+		for {
+			answer, err := ui.Input(v.Prompt(), current)
+			if err != nil {
+				return fmt.Errorf("failed to get %s: %w", v.Name, err)
+			}
+			if answer == "" && v.Required {
+				ui.PrintWarning(fmt.Sprintf("%s is required", v.Name))
+				continue
+			}
+			if answer != "" {
+				if _, err := v.Parse(answer); err != nil {
+					ui.PrintWarning(err.Error())
+					continue
+				}
+			}
+			opts.Vars[v.Name] = answer
+			break
+		}
+	}
+	return nil
+}
 
-	wrapperPath := filepath.Join(profileDir, "bin/ssh")
-	if err := os.WriteFile(wrapperPath, []byte(wrapperContent), 0755); err != nil {
+// checkRequiredVars fails when required template variables have no value
+func checkRequiredVars(opts CreateOptions) error {
+	tmpl, err := templates.Find(templatesDir(), opts.Template)
+	if err != nil {
+		return err
+	}
+	missing, err := tmpl.Missing(opts.Vars)
+	if err != nil {
 		return err
 	}
+	if len(missing) == 0 {
+		return nil
+	}
 
-	return nil
+	msg := fmt.Sprintf("template %s requires variables that were not set:\n", tmpl.Name)
+	for _, v := range missing {
+		if v.Help != "" {
+			msg += fmt.Sprintf("  %s - %s\n", v.Name, v.Help)
+		} else {
+			msg += fmt.Sprintf("  %s\n", v.Name)
+		}
+	}
+	msg += "\nSet them with --var <name>=<value>, or use --interactive to be asked"
+	return fmt.Errorf("%s", msg)
 }
 
-func createGitignore(profileDir string) error {
-	ui.PrintInfo("Creating .gitignore...")
-
-	gitignoreContent := `# Workspace profile gitignore
-
-# Environment files with secrets
-.env
-.envrc.local
-
-# SSH keys and sensitive files
-.ssh/id_*
-.ssh/*.pem
-.ssh/*.key
-.ssh/known_hosts
-
-# AWS credentials and sensitive config
-.aws/credentials
-.aws/cli/cache
-.aws/sso/cache
-
-# Azure CLI credentials and sensitive config
-.azure/config
-.azure/clouds.config
-.azure/accessTokens.json
-.azure/msal_token_cache.json
-.azure/azureProfile.json
-
-# Google Cloud SDK credentials and sensitive config
-.gcloud/configurations/
-.gcloud/credentials
-.gcloud/access_tokens.db
-.gcloud/legacy_credentials/
-.gcloud/logs/
-
-# Claude Code configuration (may contain API keys and sensitive data)
-.config/claude/
-
-# Gemini CLI configuration (may contain API keys and sensitive data)
-.config/gemini/
-
-# Terraform
-.terraform/
-.terraform.lock.hcl
-*.tfstate
-*.tfstate.*
-*.tfvars
-.terraform.d/plugin-cache/
-.terraform.d/checkpoint_cache
-.terraform.d/checkpoint_signature
-
-# Terragrunt
-.terragrunt-cache/
-*.tfplan
-
-# Kubernetes
-.kube/cache
-.kube/http-cache
-
-# OS files
-.DS_Store
-Thumbs.db
-
-# Editor files
-.vscode/
-.idea/
-*.swp
-*.swo
-*~
-
-# Build artifacts
-bin/
-dist/
-build/
-*.log
-`
-
-	gitignorePath := filepath.Join(profileDir, ".gitignore")
-	return os.WriteFile(gitignorePath, []byte(gitignoreContent), 0644)
+// printTemplateVars prints the variable values a profile would be created with
+func printTemplateVars(opts CreateOptions) error {
+	tmpl, err := findProfileTemplate(opts.Template)
+	if err != nil {
+		return err
+	}
+	vars, err := tmpl.Variables()
+	if err != nil {
+		return err
+	}
+	values, err := tmpl.Values(opts.Vars)
+	if err != nil {
+		return err
+	}
+	for _, v := range vars {
+		fmt.Printf("  Variable %s: %v\n", v.Name, values[v.Name])
+	}
+	return nil
 }
 
-func createREADME(profileDir string, opts CreateOptions) error {
-	ui.PrintInfo("Creating README.md...")
-
-	created := time.Now().UTC().Format("2006-01-02 15:04:05 UTC")
-	homeDir, err := os.UserHomeDir()
+// templateData returns the profile variables template files are rendered with
+func templateData(profileDir string, opts CreateOptions, tmpl *templates.Template) (templates.Data, error) {
+	profileAbsPath, err := filepath.Abs(profileDir)
+	if err != nil {
+		return templates.Data{}, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	vars, err := tmpl.Values(opts.Vars)
+	if err != nil {
+		return templates.Data{}, err
+	}
+	enabled, err := enabledTools(opts.Tools)
 	if err != nil {
-		homeDir = "" // Fall back to not abbreviating path
-	}
-	displayPath := profileDir
-	if homeDir != "" && len(profileDir) > len(homeDir) && profileDir[:len(homeDir)] == homeDir {
-		displayPath = "~" + profileDir[len(homeDir):]
-	}
-
-	readmeContent := "# Workspace Profile: " + opts.ProfileName + "\n\n" +
-		"Template: " + opts.Template + "\n" +
-		"Created: " + created + "\n\n" +
-		"## Setup\n\n" +
-		"1. Navigate to this directory:\n" +
-		"   ```bash\n" +
-		"   cd \"" + displayPath + "\"\n" +
-		"   ```\n\n" +
-		"2. Allow direnv (first time only):\n" +
-		"   ```bash\n" +
-		"   direnv allow\n" +
-		"   ```\n\n" +
-		"3. Verify the profile is loaded:\n" +
-		"   ```bash\n" +
-		"   echo $WORKSPACE_PROFILE\n" +
-		"   git config user.email\n" +
-		"   ```\n\n" +
-		"## Customization\n\n" +
-		"- Edit .gitconfig for git settings\n" +
-		"- Edit .ssh/config for SSH configuration\n" +
-		"- Edit .envrc for environment variables\n" +
-		"- Add scripts to bin/ directory (automatically in PATH)\n" +
-		"- Add secrets to .env file (gitignored)\n" +
-		"- Add SSH keys to .ssh/ directory\n\n" +
-		"## Environment Variables\n\n" +
-		"### Workspace\n" +
-		"- WORKSPACE_PROFILE: " + opts.ProfileName + "\n" +
-		"- WORKSPACE_HOME: Path to this directory\n" +
-		"- XDG_CONFIG_HOME: Path to profile-specific XDG config directory (.config)\n\n" +
-		"### Git\n" +
-		"- GIT_CONFIG_GLOBAL: Path to custom .gitconfig\n" +
-		"- Git automatically uses bin/ssh wrapper (first in PATH) for SSH operations\n\n" +
-		"### AWS\n" +
-		"- AWS_CONFIG_FILE: Path to profile-specific AWS config\n" +
-		"- AWS_SHARED_CREDENTIALS_FILE: Path to profile-specific AWS credentials\n\n" +
-		"### Kubernetes\n" +
-		"- KUBECONFIG: Path to profile-specific kubeconfig file\n\n" +
-		"### Terraform\n" +
-		"- TF_CLI_CONFIG_FILE: Path to profile-specific Terraform CLI config\n" +
-		"- TF_PLUGIN_CACHE_DIR: (Optional) Path to Terraform plugin cache\n\n" +
-		"### Azure\n" +
-		"- AZURE_CONFIG_DIR: Path to profile-specific Azure CLI config directory\n" +
-		"- Azure CLI will automatically use profile-specific settings and credentials\n\n" +
-		"### Google Cloud\n" +
-		"- CLOUDSDK_CONFIG: Path to profile-specific Google Cloud SDK config directory\n" +
-		"- gcloud CLI will automatically use profile-specific settings and credentials\n\n" +
-		"### Claude Code\n" +
-		"- CLAUDE_CONFIG_DIR: Path to profile-specific Claude Code config directory\n" +
-		"- Claude Code will automatically use profile-specific settings\n\n" +
-		"### Gemini CLI\n" +
-		"- GEMINI_CONFIG_DIR: Path to profile-specific Gemini CLI config directory\n" +
-		"- Gemini CLI will automatically use profile-specific settings\n\n" +
-		"## Next Steps\n\n" +
-		"1. Update git configuration in .gitconfig:\n" +
-		"   - Set your name and email\n" +
-		"   - Configure GPG signing if needed\n" +
-		"   - Add custom aliases\n\n" +
-		"2. Configure SSH in .ssh/config:\n" +
-		"   - Add host-specific settings\n" +
-		"   - Configure SSH keys for this profile\n" +
-		"   - Set up jump hosts if needed\n\n" +
-		"3. Add SSH keys (optional):\n" +
-		"   ```bash\n" +
-		"   ssh-keygen -t ed25519 -f .ssh/id_ed25519_" + opts.ProfileName + " -C \"email@example.com\"\n" +
-		"   ```\n\n" +
-		"4. Configure 1Password SSH Agent in .config/1Password/agent.toml:\n" +
-		"   - Uncomment and configure SSH keys from your 1Password vaults\n" +
-		"   - Use 'op item list' to find vault and item names\n" +
-		"   - Keys will be automatically loaded when profile is active\n\n" +
-		"5. Configure AWS credentials in .aws/:\n" +
-		"   - Edit .aws/config for AWS profiles\n" +
-		"   - Add credentials to .env or .aws/credentials\n" +
-		"   - AWS CLI will automatically use profile-specific settings\n\n" +
-		"6. Configure Azure CLI in .azure/:\n" +
-		"   - Run 'az login' to authenticate (credentials stored in .azure/)\n" +
-		"   - Azure CLI will automatically use profile-specific settings\n" +
-		"   - Use 'az account list' to see available subscriptions\n" +
-		"   - Use 'az account set --subscription <name>' to set active subscription\n\n" +
-		"7. Configure Google Cloud SDK in .gcloud/:\n" +
-		"   - Run 'gcloud auth login' to authenticate (credentials stored in .gcloud/)\n" +
-		"   - Run 'gcloud config set project <project-id>' to set active project\n" +
-		"   - gcloud CLI will automatically use profile-specific settings\n" +
-		"   - Use 'gcloud config list' to see current configuration\n" +
-		"   - Use 'gcloud config configurations list' to see available configurations\n\n" +
-		"8. Configure Claude Code in .config/claude/:\n" +
-		"   - Claude Code will automatically use profile-specific settings\n" +
-		"   - Settings, extensions, and preferences are isolated per profile\n" +
-		"   - Configuration files are stored in .config/claude/\n\n" +
-		"9. Configure Gemini CLI in .config/gemini/:\n" +
-		"   - Gemini CLI will automatically use profile-specific settings\n" +
-		"   - API keys and preferences are isolated per profile\n" +
-		"   - Configuration files are stored in .config/gemini/\n\n" +
-		"10. Configure Kubernetes in .kube/:\n" +
-		"   - Copy or generate kubeconfig to .kube/config\n" +
-		"   - kubectl will automatically use profile-specific kubeconfig\n\n" +
-		"11. XDG-compliant tools (optional):\n" +
-		"   - Many tools respect XDG_CONFIG_HOME (neovim, tmux, bat, etc.)\n" +
-		"   - Add configs to .config/<tool>/\n" +
-		"   - Example: .config/nvim/init.vim\n\n" +
-		"12. Add project-specific environment variables to .envrc\n\n" +
-		"13. Create .env for secrets (AWS keys, API tokens, Azure credentials, GCP credentials, Claude API keys, Gemini API keys, etc.)\n\n" +
-		"14. Add custom scripts to bin/ directory\n"
-
-	readmePath := filepath.Join(profileDir, "README.md")
-	return os.WriteFile(readmePath, []byte(readmeContent), 0644)
+		return templates.Data{}, err
+	}
+
+	return templates.Data{
+		Name:          opts.ProfileName,
+		Template:      opts.Template,
+		Path:          profileAbsPath,
+		DisplayPath:   displayPath(profileAbsPath),
+		GitName:       opts.GitName,
+		GitEmail:      opts.GitEmail,
+		Created:       time.Now().UTC().Format(createdLayout),
+		ToolEnv:       tools.RenderEnv(enabled),
+		ToolGitignore: tools.RenderGitignore(enabled),
+		Vars:          vars,
+	}, nil
 }
 
-func createEnvExample(profileDir string) error {
-	ui.PrintInfo("Creating .env.example...")
-
-	envExampleContent := `# Example environment variables
-# Copy this to .env and fill in your secrets
-
-# AWS credentials
-# AWS_ACCESS_KEY_ID=your-access-key
-# AWS_SECRET_ACCESS_KEY=your-secret-key
-# AWS_DEFAULT_REGION=us-east-1
-
-# Azure credentials (optional - can also use 'az login')
-# AZURE_CLIENT_ID=your-client-id
-# AZURE_CLIENT_SECRET=your-client-secret
-# AZURE_TENANT_ID=your-tenant-id
-# AZURE_SUBSCRIPTION_ID=your-subscription-id
+// renderProfileFiles renders the files of the profile's template
+func renderProfileFiles(profileDir string, opts CreateOptions) ([]templates.File, error) {
+	tmpl, err := findProfileTemplate(opts.Template)
+	if err != nil {
+		return nil, err
+	}
+	data, err := templateData(profileDir, opts, tmpl)
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := tmpl.Render(data)
+	if err != nil {
+		return nil, err
+	}
+	enabled, err := enabledTools(opts.Tools)
+	if err != nil {
+		return nil, err
+	}
 
-# Google Cloud credentials (optional - can also use 'gcloud auth login')
-# GOOGLE_APPLICATION_CREDENTIALS=/path/to/service-account-key.json
-# GCP_PROJECT=your-project-id
-# GCP_REGION=us-central1
-# GCP_ZONE=us-central1-a
+	// Leave out the files of tools the profile does not use
+	var files []templates.File
+	for _, file := range rendered {
+		if !ownedByDisabledTool(file.Path, enabled) {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
 
-# Claude Code / Anthropic API credentials
-# ANTHROPIC_API_KEY=your-anthropic-api-key
+// newProfileMetadata returns the metadata of a profile created with opts
+func newProfileMetadata(opts CreateOptions, enabled []tools.Tool) profileMetadata {
+	now := time.Now().UTC().Truncate(time.Second)
+	m := profileMetadata{
+		SchemaVersion: profileSchemaVersion,
+		Name:          opts.ProfileName,
+		Template:      opts.Template,
+		Created:       now,
+		Updated:       now,
+		Tools:         tools.ToolNames(enabled),
+		Description:   opts.Description,
+		Tags:          opts.Tags,
+		Vars:          opts.Vars,
+	}
+	if tmpl, err := templates.Find(templatesDir(), opts.Template); err == nil && tmpl.SourceName != "" {
+		m.TemplateSource, m.TemplateRevision = tmpl.SourceName, tmpl.Revision
+	}
+	return m
+}
 
-# Gemini CLI / Google AI API credentials
-# GEMINI_API_KEY=your-gemini-api-key
-# GOOGLE_AI_API_KEY=your-google-ai-api-key
+// ownedByDisabledTool reports whether a profile file belongs to a registered tool that is
+// not among the enabled ones
+func ownedByDisabledTool(path string, enabled []tools.Tool) bool {
+	for _, t := range tools.All() {
+		if t.Owns(path) && !hasTool(enabled, t.Name) {
+			return true
+		}
+	}
+	return false
+}
 
-# API keys
-# API_KEY=your-api-key
-# API_SECRET=your-api-secret
+// writeProfileFile writes a rendered template file into the profile
+func writeProfileFile(profileDir string, file templates.File) error {
+	ui.PrintInfo(fmt.Sprintf("Creating %s...", file.Path))
 
-# Database
-# DATABASE_URL=postgresql://localhost:5432/mydb
-# REDIS_URL=redis://localhost:6379
-`
+	path := filepath.Join(profileDir, filepath.FromSlash(file.Path))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", file.Path, err)
+	}
+	if err := os.WriteFile(path, file.Content, file.Mode); err != nil {
+		return fmt.Errorf("failed to create %s: %w", file.Path, err)
+	}
+	// WriteFile keeps the permissions of a file that already exists
+	if err := os.Chmod(path, file.Mode); err != nil {
+		return fmt.Errorf("failed to set permissions on %s: %w", file.Path, err)
+	}
+	return nil
+}
 
-	envExamplePath := filepath.Join(profileDir, ".env.example")
-	return os.WriteFile(envExamplePath, []byte(envExampleContent), 0644)
+// createProfileFile renders and writes only the file at path from the profile's template
+func createProfileFile(profileDir string, opts CreateOptions, path string) error {
+	tmpl, err := findProfileTemplate(opts.Template)
+	if err != nil {
+		return err
+	}
+	data, err := templateData(profileDir, opts, tmpl)
+	if err != nil {
+		return err
+	}
+	file, err := tmpl.RenderFile(path, data)
+	if err != nil {
+		return err
+	}
+	return writeProfileFile(profileDir, file)
 }
@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"os"
+
+	"github.com/neverprepared/shell-profile-manager/internal/ui"
+)
+
+const (
+	// encryptedMagic identifies files written by encryptSecrets
+	encryptedMagic = "SPENC1\n"
+	// pbkdf2Iterations follows the OWASP recommendation for PBKDF2-HMAC-SHA256
+	pbkdf2Iterations = 600000
+	saltSize         = 16
+	keySize          = 32
+
+	// passphraseEnv lets scripts provide the passphrase without a prompt
+	passphraseEnv = "SHELL_PROFILER_PASSPHRASE"
+)
+
+// encryptSecrets encrypts data with AES-256-GCM using a key derived from the passphrase.
+// The output is magic | salt | nonce | ciphertext.
+func encryptSecrets(data []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	out.WriteString(encryptedMagic)
+	out.Write(salt)
+	out.Write(nonce)
+	out.Write(gcm.Seal(nil, nonce, data, []byte(encryptedMagic)))
+	return out.Bytes(), nil
+}
+
+// decryptSecrets reverses encryptSecrets
+func decryptSecrets(data []byte, passphrase string) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(encryptedMagic)) {
+		return nil, fmt.Errorf("not an encrypted shell-profiler file")
+	}
+	data = data[len(encryptedMagic):]
+	if len(data) < saltSize {
+		return nil, fmt.Errorf("encrypted file is truncated")
+	}
+	salt, data := data[:saltSize], data[saltSize:]
+
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted file is truncated")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(encryptedMagic))
+	if err != nil {
+		return nil, fmt.Errorf("wrong passphrase or corrupted data")
+	}
+	return plaintext, nil
+}
+
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key := pbkdf2SHA256([]byte(passphrase), salt, pbkdf2Iterations, keySize)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2SHA256 derives a keyLen key as specified in RFC 8018 with HMAC-SHA256 as the PRF
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLen := prf.Size()
+	blocks := (keyLen + hashLen - 1) / hashLen
+
+	key := make([]byte, 0, blocks*hashLen)
+	var counter [4]byte
+	for block := 1; block <= blocks; block++ {
+		binary.BigEndian.PutUint32(counter[:], uint32(block))
+
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(counter[:])
+		u := prf.Sum(nil)
+		t := make([]byte, len(u))
+		copy(t, u)
+
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
+
+// readPassphrase returns the passphrase from the environment or prompts for it.
+// When confirm is set the user has to enter it twice.
+func readPassphrase(message string, confirm bool) (string, error) {
+	if passphrase := os.Getenv(passphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+
+	passphrase, err := ui.Password(message)
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("passphrase must not be empty")
+	}
+
+	if confirm {
+		again, err := ui.Password("Repeat passphrase:")
+		if err != nil {
+			return "", fmt.Errorf("failed to read passphrase: %w", err)
+		}
+		if again != passphrase {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
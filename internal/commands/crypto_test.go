@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// TestPBKDF2SHA256 checks the key derivation against the PBKDF2-HMAC-SHA256 test vectors
+// of RFC 7914, section 11
+func TestPBKDF2SHA256(t *testing.T) {
+	tests := []struct {
+		password   string
+		salt       string
+		iterations int
+		keyLen     int
+		want       string
+	}{
+		{
+			password:   "passwd",
+			salt:       "salt",
+			iterations: 1,
+			keyLen:     64,
+			want: "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc" +
+				"49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783",
+		},
+		{
+			password:   "Password",
+			salt:       "NaCl",
+			iterations: 80000,
+			keyLen:     64,
+			want: "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56" +
+				"a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d",
+		},
+	}
+
+	for _, tt := range tests {
+		got := pbkdf2SHA256([]byte(tt.password), []byte(tt.salt), tt.iterations, tt.keyLen)
+		if hex.EncodeToString(got) != tt.want {
+			t.Errorf("pbkdf2SHA256(%q, %q, %d, %d) = %x, want %s", tt.password, tt.salt, tt.iterations, tt.keyLen, got, tt.want)
+		}
+	}
+}
+
+func TestEncryptSecretsRoundTrip(t *testing.T) {
+	plaintext := []byte("AWS_SECRET_ACCESS_KEY=secret\n")
+	encrypted, err := encryptSecrets(plaintext, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decrypted, err := decryptSecrets(encrypted, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("decrypted %q, want %q", decrypted, plaintext)
+	}
+
+	if _, err := decryptSecrets(encrypted, "wrong"); err == nil {
+		t.Error("decrypting with the wrong passphrase succeeded")
+	}
+}
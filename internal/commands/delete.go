@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/neverprepared/shell-profile-manager/internal/ui"
 )
@@ -12,6 +13,9 @@ type DeleteOptions struct {
 	ProfileName string
 	Force       bool
 	DryRun      bool
+	NoSnapshot  bool
+	Permanent   bool
+	Secure      bool
 }
 
 func DeleteProfile(profilesDir string, opts DeleteOptions) error {
@@ -106,11 +110,40 @@ func DeleteProfile(profilesDir string, opts DeleteOptions) error {
 		}
 	}
 
+	// Inventory the credentials a secure delete will destroy
+	var sensitive []string
+	var snapshots []snapshotInfo
+	if opts.Secure {
+		var err error
+		sensitive, err = sensitiveFiles(profileDir)
+		if err != nil {
+			return fmt.Errorf("failed to inventory sensitive files: %w", err)
+		}
+		snapshots, err = listSnapshots(opts.ProfileName)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("  %s⚠ Secure delete: %d sensitive file(s) will be overwritten%s\n", ui.ColorYellow, len(sensitive), ui.ColorReset)
+		for _, path := range sensitive {
+			fmt.Printf("    - %s\n", path)
+		}
+		if len(snapshots) > 0 {
+			fmt.Printf("  %s⚠ %d snapshot(s) and all backups of this profile will be overwritten%s\n", ui.ColorYellow, len(snapshots), ui.ColorReset)
+		}
+	}
+
 	// Dry run
 	if opts.DryRun {
 		ui.PrintInfo("DRY RUN - Nothing will be deleted")
 		fmt.Println()
-		fmt.Println("Would delete:")
+		if opts.Secure {
+			fmt.Println("Would securely delete:")
+		} else if opts.Permanent {
+			fmt.Println("Would delete:")
+		} else {
+			fmt.Println("Would move to trash:")
+		}
 		count := 0
 		filepath.Walk(profileDir, func(path string, info os.FileInfo, err error) error { //nolint:errcheck // Listing files for preview, errors are not critical
 			if err != nil {
@@ -130,10 +163,13 @@ func DeleteProfile(profilesDir string, opts DeleteOptions) error {
 
 	// Confirmation
 	if !opts.Force {
-		confirmed, err := ui.Confirm(
-			fmt.Sprintf("This will permanently delete the profile '%s' and all its files! Are you sure?", opts.ProfileName),
-			false,
-		)
+		message := fmt.Sprintf("Move the profile '%s' to the trash?", opts.ProfileName)
+		if opts.Secure {
+			message = fmt.Sprintf("This will destroy the profile '%s', its credentials and its backups. This cannot be undone! Are you sure?", opts.ProfileName)
+		} else if opts.Permanent {
+			message = fmt.Sprintf("This will permanently delete the profile '%s' and all its files! Are you sure?", opts.ProfileName)
+		}
+		confirmed, err := ui.Confirm(message, false)
 		if err != nil {
 			return fmt.Errorf("failed to get confirmation: %w", err)
 		}
@@ -144,6 +180,36 @@ func DeleteProfile(profilesDir string, opts DeleteOptions) error {
 		}
 	}
 
+	// Overwrite credentials and backups, without taking a snapshot that would keep copies
+	if opts.Secure {
+		return secureDeleteProfile(profileDir, opts.ProfileName, sensitive)
+	}
+
+	// Move the profile to the trash unless a permanent delete was requested
+	if !opts.Permanent {
+		entry, err := moveToTrash(profilesDir, opts.ProfileName)
+		if err != nil {
+			return err
+		}
+		ui.PrintSuccess(fmt.Sprintf("Profile moved to trash: %s", opts.ProfileName))
+		fmt.Printf("  Trash: %s\n", entry.Path)
+		fmt.Printf("  Restore with: shell-profiler trash restore %s\n", opts.ProfileName)
+		return nil
+	}
+
+	// Snapshot the profile so it can be restored later
+	if !opts.NoSnapshot {
+		if _, err := createSnapshot(profilesDir, opts.ProfileName, "delete"); err != nil {
+			ui.PrintWarning(fmt.Sprintf("Failed to create snapshot: %v", err))
+			if !opts.Force {
+				confirmed, err := ui.Confirm("Delete without snapshot?", false)
+				if err != nil || !confirmed {
+					return fmt.Errorf("deletion cancelled")
+				}
+			}
+		}
+	}
+
 	// Delete profile
 	ui.PrintInfo(fmt.Sprintf("Deleting profile: %s", opts.ProfileName))
 
@@ -169,3 +235,83 @@ func DeleteProfile(profilesDir string, opts DeleteOptions) error {
 
 	return nil
 }
+
+// secureDeleteProfile overwrites the sensitive files of a profile and all of its backups,
+// removes the profile and writes a deletion receipt
+func secureDeleteProfile(profileDir, profileName string, sensitive []string) error {
+	ui.PrintInfo(fmt.Sprintf("Securely deleting profile: %s", profileName))
+
+	absDir, err := filepath.Abs(profileDir)
+	if err != nil {
+		absDir = profileDir
+	}
+	receipt := deletionReceipt{
+		Profile: profileName,
+		Path:    absDir,
+		Deleted: time.Now().UTC(),
+	}
+
+	var complete bool
+	receipt.SensitiveFiles, complete = wipeFiles(profileDir, sensitive)
+	receipt.Complete = complete
+
+	// Snapshots and legacy backups hold copies of the same secrets
+	if backupsDir, err := profileBackupsDir(profileName); err == nil {
+		files, backupsComplete, err := wipeTree(backupsDir)
+		if err != nil {
+			ui.PrintWarning(fmt.Sprintf("Failed to wipe backups: %v", err))
+			backupsComplete = false
+		}
+		receipt.SnapshotFiles = files
+		receipt.Complete = receipt.Complete && backupsComplete
+	}
+
+	// Remove everything else
+	filepath.Walk(profileDir, func(_ string, info os.FileInfo, err error) error { //nolint:errcheck // Counting files, errors are not critical
+		if err == nil && !info.IsDir() {
+			receipt.OtherFilesRemoved++
+		}
+		return nil
+	})
+	if err := os.RemoveAll(profileDir); err != nil {
+		receipt.Complete = false
+		ui.PrintWarning(fmt.Sprintf("Failed to remove profile directory: %v", err))
+	}
+
+	receiptPath, err := writeDeletionReceipt(receipt)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range append(receipt.SensitiveFiles, receipt.SnapshotFiles...) {
+		if f.Status != "overwritten" {
+			ui.PrintWarning(fmt.Sprintf("Failed to overwrite %s: %s", f.Path, f.Status))
+		}
+	}
+
+	// Copies in the trash are not touched by this delete
+	if entries, err := listTrash(); err == nil {
+		trashed := 0
+		for _, e := range entries {
+			if e.Name == profileName {
+				trashed++
+			}
+		}
+		if trashed > 0 {
+			ui.PrintWarning(fmt.Sprintf("%d copy(ies) of '%s' remain in the trash", trashed, profileName))
+			fmt.Printf("  Remove them with: shell-profiler trash empty %s\n", profileName)
+		}
+	}
+
+	if !receipt.Complete {
+		return fmt.Errorf("secure delete incomplete, see receipt: %s", receiptPath)
+	}
+
+	ui.PrintSuccess(fmt.Sprintf("Profile securely deleted: %s", profileName))
+	fmt.Printf("  Sensitive files overwritten: %d\n", len(receipt.SensitiveFiles))
+	if len(receipt.SnapshotFiles) > 0 {
+		fmt.Printf("  Backup files overwritten: %d\n", len(receipt.SnapshotFiles))
+	}
+	fmt.Printf("  Receipt: %s\n", receiptPath)
+	return nil
+}
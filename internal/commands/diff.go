@@ -0,0 +1,161 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/neverprepared/shell-profile-manager/internal/ui"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// splitLines splits file content into lines without the trailing newline
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// diffLines computes a line-based edit script turning a into b
+func diffLines(a, b []string) []diffOp {
+	// Longest common subsequence table
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// unifiedDiff returns a unified diff between two file contents, or nil if they are equal
+func unifiedDiff(oldName, newName, oldContent, newContent string) []string {
+	if oldContent == newContent {
+		return nil
+	}
+
+	if strings.ContainsRune(oldContent, 0) || strings.ContainsRune(newContent, 0) {
+		return []string{fmt.Sprintf("Binary files %s and %s differ", oldName, newName)}
+	}
+
+	ops := diffLines(splitLines(oldContent), splitLines(newContent))
+
+	out := []string{
+		"--- " + oldName,
+		"+++ " + newName,
+	}
+
+	// Group changes into hunks with surrounding context
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start++
+			continue
+		}
+
+		hunkStart := start - diffContext
+		if hunkStart < 0 {
+			hunkStart = 0
+		}
+
+		hunkEnd := start
+		for k := start; k < len(ops); k++ {
+			if ops[k].kind != ' ' {
+				hunkEnd = k + 1
+				continue
+			}
+			if k-hunkEnd >= 2*diffContext {
+				break
+			}
+		}
+		hunkEnd += diffContext
+		if hunkEnd > len(ops) {
+			hunkEnd = len(ops)
+		}
+
+		// Line numbers of the hunk in both files
+		oldLine, newLine := 1, 1
+		for _, op := range ops[:hunkStart] {
+			if op.kind != '+' {
+				oldLine++
+			}
+			if op.kind != '-' {
+				newLine++
+			}
+		}
+		oldCount, newCount := 0, 0
+		for _, op := range ops[hunkStart:hunkEnd] {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+
+		out = append(out, fmt.Sprintf("@@ -%d,%d +%d,%d @@", oldLine, oldCount, newLine, newCount))
+		for _, op := range ops[hunkStart:hunkEnd] {
+			out = append(out, string(op.kind)+op.line)
+		}
+
+		start = hunkEnd
+	}
+
+	return out
+}
+
+// printDiff prints a unified diff with colors
+func printDiff(lines []string) {
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+			fmt.Printf("  %s%s%s\n", ui.ColorBlue, line, ui.ColorReset)
+		case strings.HasPrefix(line, "@@"):
+			fmt.Printf("  %s%s%s\n", ui.ColorCyan, line, ui.ColorReset)
+		case strings.HasPrefix(line, "-"):
+			fmt.Printf("  %s%s%s\n", ui.ColorRed, line, ui.ColorReset)
+		case strings.HasPrefix(line, "+"):
+			fmt.Printf("  %s%s%s\n", ui.ColorGreen, line, ui.ColorReset)
+		default:
+			fmt.Printf("  %s\n", line)
+		}
+	}
+}
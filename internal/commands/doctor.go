@@ -0,0 +1,451 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/neverprepared/shell-profile-manager/internal/tools"
+	"github.com/neverprepared/shell-profile-manager/internal/ui"
+)
+
+type DoctorOptions struct {
+	ProfileName string
+	Fix         bool
+}
+
+type checkStatus int
+
+const (
+	checkPass checkStatus = iota
+	checkWarn
+	checkFail
+	checkFixed
+)
+
+// doctorReport prints check results as they come in and counts them
+type doctorReport struct {
+	counts map[checkStatus]int
+}
+
+func (r *doctorReport) section(title string) {
+	if len(r.counts) > 0 {
+		fmt.Println()
+	}
+	fmt.Printf("%s=== %s ===%s\n", ui.ColorBlue, title, ui.ColorReset)
+	if r.counts == nil {
+		r.counts = make(map[checkStatus]int)
+	}
+}
+
+// add records a check result. hint is shown below warnings and failures.
+func (r *doctorReport) add(status checkStatus, message, hint string) {
+	r.counts[status]++
+	switch status {
+	case checkPass:
+		fmt.Printf("  %s✓%s %s\n", ui.ColorGreen, ui.ColorReset, message)
+	case checkFixed:
+		fmt.Printf("  %s✓ %s (fixed)%s\n", ui.ColorGreen, message, ui.ColorReset)
+	case checkWarn:
+		fmt.Printf("  %s⚠ %s%s\n", ui.ColorYellow, message, ui.ColorReset)
+	case checkFail:
+		fmt.Printf("  %s✗ %s%s\n", ui.ColorRed, message, ui.ColorReset)
+	}
+	if hint != "" && (status == checkWarn || status == checkFail) {
+		for _, line := range strings.Split(hint, "\n") {
+			fmt.Printf("      %s\n", line)
+		}
+	}
+}
+
+// fixOrReport runs fix if fixing is enabled and records the outcome. Without --fix,
+// or if the fix fails, the check is reported with the given status.
+func (r *doctorReport) fixOrReport(opts DoctorOptions, status checkStatus, message string, fix func() error) {
+	if !opts.Fix {
+		r.add(status, message, "Run 'shell-profiler doctor --fix' to repair")
+		return
+	}
+	if err := fix(); err != nil {
+		r.add(status, message, fmt.Sprintf("Fix failed: %v", err))
+		return
+	}
+	r.add(checkFixed, message, "")
+}
+
+// Doctor checks the tools shell-profiler relies on and the health of one or all profiles
+func Doctor(profilesDir string, opts DoctorOptions) error {
+	var profiles []string
+	if opts.ProfileName != "" {
+		profileDir := filepath.Join(profilesDir, opts.ProfileName)
+		if _, err := os.Stat(filepath.Join(profileDir, ".envrc")); os.IsNotExist(err) {
+			return fmt.Errorf("profile '%s' does not exist at: %s", opts.ProfileName, profileDir)
+		}
+		profiles = []string{opts.ProfileName}
+	} else if _, err := os.Stat(profilesDir); err == nil {
+		found, err := findProfiles(profilesDir)
+		if err != nil {
+			return err
+		}
+		profiles = found
+	}
+
+	report := &doctorReport{}
+
+	report.section("System")
+	checkSystem(report)
+
+	if opts.ProfileName == "" {
+		if _, err := os.Stat(profilesDir); os.IsNotExist(err) {
+			report.add(checkWarn, fmt.Sprintf("Profiles directory does not exist: %s", profilesDir), "Create your first profile with: shell-profiler create my-profile")
+		} else {
+			report.add(checkPass, fmt.Sprintf("Profiles directory: %s (%d profile(s))", profilesDir, len(profiles)), "")
+		}
+	}
+
+	for _, name := range profiles {
+		report.section(fmt.Sprintf("Profile: %s", name))
+		checkProfile(report, profilesDir, name, opts)
+	}
+
+	fmt.Println()
+	summary := fmt.Sprintf("%d passed, %d warning(s), %d failed", report.counts[checkPass], report.counts[checkWarn], report.counts[checkFail])
+	if report.counts[checkFixed] > 0 {
+		summary += fmt.Sprintf(", %d fixed", report.counts[checkFixed])
+	}
+	switch {
+	case report.counts[checkFail] > 0:
+		ui.PrintError(summary)
+		return fmt.Errorf("%d check(s) failed", report.counts[checkFail])
+	case report.counts[checkWarn] > 0:
+		ui.PrintWarning(summary)
+	default:
+		ui.PrintSuccess(summary)
+	}
+	return nil
+}
+
+// checkSystem checks the external tools and the direnv shell hook
+func checkSystem(report *doctorReport) {
+	for _, tool := range []struct {
+		name string
+		hint string
+	}{
+		{"git", "Install git: brew install git / apt install git"},
+		{"ssh", "Install OpenSSH: apt install openssh-client"},
+		{"direnv", "Install direnv: brew install direnv / apt install direnv\nSee https://direnv.net/"},
+	} {
+		if path, err := exec.LookPath(tool.name); err == nil {
+			report.add(checkPass, fmt.Sprintf("%s: %s", tool.name, path), "")
+		} else {
+			report.add(checkFail, fmt.Sprintf("%s: not found in PATH", tool.name), tool.hint)
+		}
+	}
+
+	shell, rcFiles := shellRCFiles()
+	for _, rc := range rcFiles {
+		content, err := os.ReadFile(rc)
+		if err == nil && strings.Contains(string(content), "direnv hook") {
+			report.add(checkPass, fmt.Sprintf("direnv hook: %s", displayPath(rc)), "")
+			return
+		}
+	}
+	var shown []string
+	for _, rc := range rcFiles {
+		shown = append(shown, displayPath(rc))
+	}
+	hint := fmt.Sprintf("Add to your shell config: eval \"$(direnv hook %s)\"", shell)
+	if shell == "fish" {
+		hint = "Add to your shell config: direnv hook fish | source"
+	}
+	report.add(checkWarn, fmt.Sprintf("direnv hook: not found in %s", strings.Join(shown, ", ")), hint)
+}
+
+// shellRCFiles returns the user's shell name and the rc files that would hold the direnv hook
+func shellRCFiles() (string, []string) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = ""
+	}
+	zdotdir := os.Getenv("ZDOTDIR")
+	if zdotdir == "" {
+		zdotdir = homeDir
+	}
+
+	bash := []string{filepath.Join(homeDir, ".bashrc"), filepath.Join(homeDir, ".bash_profile"), filepath.Join(homeDir, ".profile")}
+	zsh := []string{filepath.Join(zdotdir, ".zshrc"), filepath.Join(zdotdir, ".zprofile")}
+	fish := []string{filepath.Join(homeDir, ".config/fish/config.fish")}
+
+	switch shell := filepath.Base(os.Getenv("SHELL")); shell {
+	case "zsh":
+		return shell, zsh
+	case "fish":
+		return shell, fish
+	case "bash":
+		return shell, bash
+	default:
+		return "bash", append(append(bash, zsh...), fish...)
+	}
+}
+
+// checkProfile checks the layout, permissions and environment of a single profile
+func checkProfile(report *doctorReport, profilesDir, profileName string, opts DoctorOptions) {
+	profileDir := filepath.Join(profilesDir, profileName)
+
+	// Directories created by CreateProfile for the profile's tools
+	enabled := profileTools(profileDir)
+	var missing []string
+	for _, dir := range tools.Dirs(enabled) {
+		if info, err := os.Stat(filepath.Join(profileDir, dir)); err != nil || !info.IsDir() {
+			missing = append(missing, dir)
+		}
+	}
+	if len(missing) == 0 {
+		report.add(checkPass, "Directories present", "")
+	} else {
+		report.fixOrReport(opts, checkWarn, fmt.Sprintf("Missing directories: %s", strings.Join(missing, ", ")), func() error {
+			for _, dir := range missing {
+				if err := os.MkdirAll(filepath.Join(profileDir, dir), 0755); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	checkMetadata(report, profileDir, opts)
+	checkConflicts(report, profileDir)
+	checkSSHPermissions(report, profileDir, opts)
+	if hasTool(enabled, "ssh") {
+		checkSSHWrapper(report, profilesDir, profileDir, opts)
+	}
+	checkDirenvAllowed(report, profileDir)
+	checkSSHAuthSock(report, profileDir)
+}
+
+// checkMetadata checks that the profile has a readable metadata file and the current schema version
+func checkMetadata(report *doctorReport, profileDir string, opts DoctorOptions) {
+	m, err := loadProfileMetadata(profileDir)
+	if err != nil {
+		report.add(checkFail, err.Error(), fmt.Sprintf("Fix or remove %s, then run 'shell-profiler update'", metadataFile))
+		return
+	}
+	if m.legacy {
+		report.fixOrReport(opts, checkWarn, fmt.Sprintf("%s is missing", metadataFile), func() error {
+			_, err := updateMetadata(profileDir, false)
+			return err
+		})
+	} else {
+		report.add(checkPass, fmt.Sprintf("Metadata: template %s, %d tool(s)", m.Template, len(m.Tools)), "")
+	}
+
+	switch {
+	case m.SchemaVersion < profileSchemaVersion:
+		var names []string
+		for _, pending := range pendingMigrations(m.SchemaVersion) {
+			names = append(names, pending.name)
+		}
+		report.add(checkWarn, fmt.Sprintf("Schema version %d, current is %d (pending: %s)", m.SchemaVersion, profileSchemaVersion, strings.Join(names, ", ")),
+			fmt.Sprintf("Run 'shell-profiler update %s'", m.Name))
+	case m.SchemaVersion > profileSchemaVersion:
+		report.add(checkWarn, fmt.Sprintf("Schema version %d is newer than this shell-profiler (%d)", m.SchemaVersion, profileSchemaVersion),
+			"Upgrade shell-profiler")
+	default:
+		report.add(checkPass, fmt.Sprintf("Schema version %d", m.SchemaVersion), "")
+	}
+}
+
+// checkConflicts checks that no managed file was left with conflict markers by a merge
+func checkConflicts(report *doctorReport, profileDir string) {
+	for _, path := range managedFiles {
+		content, err := os.ReadFile(filepath.Join(profileDir, filepath.FromSlash(path)))
+		if err != nil {
+			continue
+		}
+		if hasConflictMarkers(string(content)) {
+			report.add(checkFail, fmt.Sprintf("Unresolved merge conflict in %s", path),
+				"Edit the file to resolve the conflict and remove the conflict markers")
+		}
+	}
+}
+
+// checkSSHPermissions checks that .ssh is private and that keys are readable only by the owner
+func checkSSHPermissions(report *doctorReport, profileDir string, opts DoctorOptions) {
+	sshDir := filepath.Join(profileDir, ".ssh")
+	info, err := os.Stat(sshDir)
+	if err != nil {
+		// Reported as a missing directory
+		return
+	}
+	if mode := info.Mode().Perm(); mode != 0700 {
+		report.fixOrReport(opts, checkFail, fmt.Sprintf(".ssh permissions are %s, expected 0700", formatMode(mode)), func() error {
+			return os.Chmod(sshDir, 0700)
+		})
+	} else {
+		report.add(checkPass, ".ssh permissions 0700", "")
+	}
+
+	entries, err := os.ReadDir(sshDir)
+	if err != nil {
+		report.add(checkFail, fmt.Sprintf("Cannot read .ssh: %v", err), "")
+		return
+	}
+	keys := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !isSSHKeyFile(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(sshDir, entry.Name())
+		keyInfo, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if mode := keyInfo.Mode().Perm(); mode&0077 != 0 {
+			report.fixOrReport(opts, checkFail, fmt.Sprintf(".ssh/%s permissions are %s, expected 0600", entry.Name(), formatMode(mode)), func() error {
+				return os.Chmod(path, 0600)
+			})
+			continue
+		}
+		keys++
+	}
+	if keys > 0 {
+		report.add(checkPass, fmt.Sprintf("%d SSH key(s) with permissions 0600", keys), "")
+	}
+}
+
+// isSSHKeyFile reports whether a file in .ssh is a private key
+func isSSHKeyFile(name string) bool {
+	if strings.HasSuffix(name, ".pub") {
+		return false
+	}
+	return strings.HasPrefix(name, "id_") || strings.HasSuffix(name, ".pem") || strings.HasSuffix(name, ".key")
+}
+
+// checkSSHWrapper checks that bin/ssh is executable and execs an ssh binary that exists
+func checkSSHWrapper(report *doctorReport, profilesDir, profileDir string, opts DoctorOptions) {
+	wrapperPath := filepath.Join(profileDir, "bin/ssh")
+	info, err := os.Stat(wrapperPath)
+	if os.IsNotExist(err) {
+		report.fixOrReport(opts, checkFail, "bin/ssh wrapper is missing", func() error {
+			return createProfileFile(profileDir, profileCreateOptions(profileDir), "bin/ssh")
+		})
+		return
+	}
+	if err != nil {
+		report.add(checkFail, fmt.Sprintf("Cannot read bin/ssh: %v", err), "")
+		return
+	}
+
+	if info.Mode().Perm()&0100 == 0 {
+		report.fixOrReport(opts, checkFail, "bin/ssh is not executable", func() error {
+			return os.Chmod(wrapperPath, info.Mode().Perm()|0755)
+		})
+	}
+
+	target, err := sshWrapperTarget(wrapperPath)
+	if err != nil {
+		report.add(checkWarn, fmt.Sprintf("Cannot read bin/ssh: %v", err), "")
+		return
+	}
+	if target == "" {
+		report.add(checkWarn, "bin/ssh does not exec an absolute ssh path", "The wrapper was modified; make sure it runs the system ssh with -F")
+		return
+	}
+	if targetInfo, err := os.Stat(target); err == nil && !targetInfo.IsDir() && targetInfo.Mode().Perm()&0111 != 0 {
+		report.add(checkPass, fmt.Sprintf("bin/ssh → %s", target), "")
+		return
+	}
+
+	systemSSH := findSystemSSH(profilesDir)
+	if systemSSH == "" {
+		report.add(checkFail, fmt.Sprintf("bin/ssh points at %s, which does not exist", target), "Install OpenSSH and update the exec line in bin/ssh")
+		return
+	}
+	report.fixOrReport(opts, checkFail, fmt.Sprintf("bin/ssh points at %s, which does not exist (found %s)", target, systemSSH), func() error {
+		content, err := os.ReadFile(wrapperPath)
+		if err != nil {
+			return err
+		}
+		updated := strings.Replace(string(content), "exec "+target+" ", "exec "+systemSSH+" ", 1)
+		return os.WriteFile(wrapperPath, []byte(updated), info.Mode().Perm()|0755)
+	})
+}
+
+// sshWrapperTarget returns the absolute path the SSH wrapper execs, or "" if it does not exec one
+func sshWrapperTarget(wrapperPath string) (string, error) {
+	content, err := os.ReadFile(wrapperPath)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "exec" && filepath.IsAbs(fields[1]) {
+			return fields[1], nil
+		}
+	}
+	return "", nil
+}
+
+// findSystemSSH returns the first ssh binary on PATH that is not a profile's wrapper
+func findSystemSSH(profilesDir string) string {
+	profilesAbs, err := filepath.Abs(profilesDir)
+	if err != nil {
+		profilesAbs = profilesDir
+	}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" || strings.HasPrefix(dir, profilesAbs+string(os.PathSeparator)) {
+			continue
+		}
+		path := filepath.Join(dir, "ssh")
+		if info, err := os.Stat(path); err == nil && !info.IsDir() && info.Mode().Perm()&0111 != 0 {
+			return path
+		}
+	}
+	return ""
+}
+
+// checkDirenvAllowed checks that direnv has been allowed to load the profile's .envrc
+func checkDirenvAllowed(report *doctorReport, profileDir string) {
+	if _, err := exec.LookPath("direnv"); err != nil {
+		// Reported in the system checks
+		return
+	}
+	cmd := exec.Command("direnv", "status")
+	cmd.Dir = profileDir
+	output, err := cmd.Output()
+	if err != nil {
+		report.add(checkWarn, fmt.Sprintf("Could not get direnv status: %v", err), "")
+		return
+	}
+	if strings.Contains(string(output), "Found RC allowed true") || strings.Contains(string(output), "Found RC allowed 0") {
+		report.add(checkPass, ".envrc allowed", "")
+		return
+	}
+	report.add(checkWarn, ".envrc is not allowed", fmt.Sprintf("Review .envrc, then run: cd %s && direnv allow", profileDir))
+}
+
+// checkSSHAuthSock checks that the agent socket configured in .env exists
+func checkSSHAuthSock(report *doctorReport, profileDir string) {
+	if _, err := os.Stat(filepath.Join(profileDir, ".env")); os.IsNotExist(err) {
+		report.add(checkWarn, ".env is missing", "Run 'shell-profiler update' to recreate it")
+		return
+	}
+	env, err := loadProfileEnv(profileDir)
+	if err != nil {
+		report.add(checkWarn, fmt.Sprintf("Cannot load the profile environment: %v", err), "")
+		return
+	}
+
+	sock, ok := env.vars["SSH_AUTH_SOCK"]
+	if !ok || sock == "" {
+		return
+	}
+	if info, err := os.Stat(sock); err == nil && info.Mode()&os.ModeSocket != 0 {
+		report.add(checkPass, fmt.Sprintf("SSH_AUTH_SOCK: %s", displayPath(sock)), "")
+	} else if err == nil {
+		report.add(checkWarn, fmt.Sprintf("SSH_AUTH_SOCK is not a socket: %s", displayPath(sock)), "")
+	} else {
+		report.add(checkWarn, fmt.Sprintf("SSH_AUTH_SOCK does not exist: %s", displayPath(sock)), "Start the SSH agent (e.g. 1Password) or change SSH_AUTH_SOCK in .env")
+	}
+}
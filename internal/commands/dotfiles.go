@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/neverprepared/shell-profile-manager/internal/tools"
 	"github.com/neverprepared/shell-profile-manager/internal/ui"
 )
 
@@ -234,36 +235,14 @@ type DotfileInfo struct {
 func findDotfiles(profileDir string) []DotfileInfo {
 	var dotfiles []DotfileInfo
 
-	// Known dotfiles with descriptions
-	knownFiles := map[string]string{
-		".envrc":                       "direnv configuration - environment variables",
-		".gitconfig":                   "Git configuration - user name, email, aliases",
-		".gitignore":                   "Git ignore patterns",
-		".ssh/config":                  "SSH client configuration",
-		".aws/config":                  "AWS CLI configuration",
-		".aws/credentials":             "AWS credentials (secrets)",
-		".azure/config":                "Azure CLI configuration",
-		".azure/clouds.config":         "Azure CLI cloud configuration",
-		".gcloud/configurations":       "Google Cloud SDK configurations",
-		".gcloud/credentials":          "Google Cloud SDK credentials",
-		".config/claude":               "Claude Code configuration",
-		".config/gemini":               "Gemini CLI configuration",
-		".kube/config":                 "Kubernetes configuration",
-		".terraformrc":                 "Terraform CLI configuration",
-		".config/1Password/agent.toml": "1Password SSH agent configuration",
-		".env":                         "Environment variables (secrets)",
-		".env.example":                 "Environment variables template",
-		".envrc.local":                 "Local direnv overrides",
-	}
-
-	// Check for known files and directories
-	for relPath, description := range knownFiles {
-		fullPath := filepath.Join(profileDir, relPath)
+	// Known dotfiles with descriptions, from the tool registry
+	for _, known := range tools.Dotfiles(tools.All()) {
+		fullPath := filepath.Join(profileDir, known.Path)
 		if _, err := os.Stat(fullPath); err == nil {
 			// Include both files and directories
 			dotfiles = append(dotfiles, DotfileInfo{
 				Path:        fullPath,
-				Description: description,
+				Description: known.Description,
 			})
 		}
 	}
@@ -0,0 +1,467 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxSourceDepth limits how deeply .envrc files may source other files
+const maxSourceDepth = 8
+
+// profileEnv is the environment a profile sets up on top of a base environment, loaded
+// natively the way direnv would evaluate the profile's .envrc.
+//
+// Only a subset of .envrc is understood: export and plain assignments, PATH_add,
+// dotenv/dotenv_if_exists, source/source_env of files using the same subset, and
+// if/fi wrappers around those. log_status, echo and function definitions are ignored.
+// Everything else is recorded in skipped.
+type profileEnv struct {
+	dir     string            // absolute profile directory
+	base    map[string]string // environment the profile is loaded on top of
+	vars    map[string]string // variables exported by the profile
+	order   []string          // names in vars, in the order they were first set
+	locals  map[string]string // unexported shell variables
+	skipped []string          // lines outside the supported subset, as "file: text"
+}
+
+var (
+	assignmentRe = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)=(.*)$`)
+	dotenvLineRe = regexp.MustCompile(`^(?:export\s+)?([A-Za-z_][A-Za-z0-9_.]*)\s*[=:]\s*(.*)$`)
+)
+
+// loadProfileEnv evaluates a profile's .envrc on top of the current process environment
+func loadProfileEnv(profileDir string) (*profileEnv, error) {
+	return loadProfileEnvFrom(profileDir, os.Environ())
+}
+
+// loadProfileEnvFrom evaluates a profile's .envrc on top of the given KEY=value environment
+func loadProfileEnvFrom(profileDir string, environ []string) (*profileEnv, error) {
+	absDir, err := filepath.Abs(profileDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	env := &profileEnv{
+		dir:    absDir,
+		base:   make(map[string]string, len(environ)),
+		vars:   make(map[string]string),
+		locals: make(map[string]string),
+	}
+	for _, kv := range environ {
+		if key, value, found := strings.Cut(kv, "="); found {
+			env.base[key] = value
+		}
+	}
+
+	// .envrc is evaluated with the profile as the working directory. The generated .envrc
+	// finds the shared .global directory with a command substitution, which is resolved here.
+	env.locals["PWD"] = absDir
+	env.locals["GLOBAL_DIR"] = ""
+	if info, err := os.Stat(filepath.Join(filepath.Dir(absDir), ".global")); err == nil && info.IsDir() {
+		env.locals["GLOBAL_DIR"] = filepath.Join(filepath.Dir(absDir), ".global")
+	}
+
+	if err := env.sourceFile(filepath.Join(absDir, ".envrc"), 0); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// lookup returns the value of a variable as seen while evaluating the profile
+func (e *profileEnv) lookup(name string) string {
+	if value, ok := e.locals[name]; ok {
+		return value
+	}
+	if value, ok := e.vars[name]; ok {
+		return value
+	}
+	return e.base[name]
+}
+
+// set exports a variable
+func (e *profileEnv) set(name, value string) {
+	if _, ok := e.vars[name]; !ok {
+		e.order = append(e.order, name)
+	}
+	e.vars[name] = value
+	delete(e.locals, name)
+}
+
+// addedPaths returns the PATH entries the profile adds to the base PATH, in order
+func (e *profileEnv) addedPaths() []string {
+	existing := make(map[string]bool)
+	for _, dir := range filepath.SplitList(e.base["PATH"]) {
+		existing[dir] = true
+	}
+	var added []string
+	for _, dir := range filepath.SplitList(e.vars["PATH"]) {
+		if !existing[dir] {
+			added = append(added, dir)
+			existing[dir] = true
+		}
+	}
+	return added
+}
+
+// environ returns the base environment with the profile's variables applied, sorted by name
+func (e *profileEnv) environ() []string {
+	merged := make(map[string]string, len(e.base)+len(e.vars))
+	for key, value := range e.base {
+		merged[key] = value
+	}
+	for key, value := range e.vars {
+		merged[key] = value
+	}
+
+	result := make([]string, 0, len(merged))
+	for key, value := range merged {
+		result = append(result, key+"="+value)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// sourceFile evaluates a shell file written in the supported .envrc subset
+func (e *profileEnv) sourceFile(path string, depth int) error {
+	if depth > maxSourceDepth {
+		return fmt.Errorf("%s: files are sourced too deeply", path)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	inFunction := false
+	lines := strings.Split(string(content), "\n")
+	for i, raw := range lines {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Function definitions only exist in the shell that sources .envrc
+		if inFunction {
+			if strings.HasPrefix(raw, "}") {
+				inFunction = false
+			}
+			continue
+		}
+		if strings.HasSuffix(line, "() {") || strings.HasSuffix(line, "(){") {
+			inFunction = true
+			continue
+		}
+
+		if err := e.evalLine(path, line, depth); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, i+1, err)
+		}
+	}
+	return nil
+}
+
+// evalLine evaluates a single non-empty line of a sourced file
+func (e *profileEnv) evalLine(path, line string, depth int) error {
+	fields := strings.Fields(line)
+	baseDir := filepath.Dir(path)
+
+	switch fields[0] {
+	case "if", "then", "else", "fi":
+		// Conditions only guard the sourcing of files that may not exist, which is
+		// checked when they are sourced
+		return nil
+	case "log_status", "log_error", "echo", "printf", ":":
+		return nil
+	case "export":
+		if len(fields) == 1 {
+			return nil
+		}
+		rest := strings.TrimSpace(strings.TrimPrefix(line, "export"))
+		m := assignmentRe.FindStringSubmatch(rest)
+		if m == nil {
+			// "export NAME" exports an existing shell variable
+			if value, ok := e.locals[rest]; ok {
+				e.set(rest, value)
+				return nil
+			}
+			e.skip(path, line)
+			return nil
+		}
+		value, ok := e.shellValue(m[2])
+		if !ok {
+			e.skip(path, line)
+			return nil
+		}
+		e.set(m[1], value)
+		return nil
+	case "PATH_add", "path_add":
+		args, ok := e.shellWords(fields[1:])
+		if !ok || len(args) == 0 {
+			e.skip(path, line)
+			return nil
+		}
+		if fields[0] == "path_add" {
+			// path_add VAR dir...
+			e.prependPaths(args[0], baseDir, args[1:])
+			return nil
+		}
+		e.prependPaths("PATH", baseDir, args)
+		return nil
+	case "dotenv", "dotenv_if_exists":
+		args, ok := e.shellWords(fields[1:])
+		if !ok {
+			e.skip(path, line)
+			return nil
+		}
+		file := ".env"
+		if len(args) > 0 {
+			file = args[0]
+		}
+		file = resolvePath(baseDir, file)
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			if fields[0] == "dotenv_if_exists" {
+				return nil
+			}
+			return fmt.Errorf("dotenv: %s does not exist", file)
+		}
+		return e.loadDotenv(file)
+	case "source", ".", "source_env", "source_env_if_exists", "source_up":
+		if fields[0] == "source_up" {
+			e.skip(path, line)
+			return nil
+		}
+		args, ok := e.shellWords(fields[1:])
+		if !ok || len(args) == 0 {
+			e.skip(path, line)
+			return nil
+		}
+		file := resolvePath(baseDir, args[0])
+		if info, err := os.Stat(file); err == nil && info.IsDir() && strings.HasPrefix(fields[0], "source_env") {
+			file = filepath.Join(file, ".envrc")
+		}
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			if fields[0] == "source_env" {
+				return fmt.Errorf("source_env: %s does not exist", file)
+			}
+			// source of a missing file is guarded by an if in generated profiles
+			return nil
+		}
+		return e.sourceFile(file, depth+1)
+	}
+
+	// Plain shell variable assignment
+	if m := assignmentRe.FindStringSubmatch(line); m != nil {
+		value, ok := e.shellValue(m[2])
+		if !ok {
+			// Command substitutions cannot be evaluated natively; variables the generated
+			// .envrc computes this way are resolved up front
+			if _, known := e.locals[m[1]]; known {
+				return nil
+			}
+			e.skip(path, line)
+			return nil
+		}
+		if _, exported := e.vars[m[1]]; exported {
+			e.set(m[1], value)
+		} else {
+			e.locals[m[1]] = value
+		}
+		return nil
+	}
+
+	e.skip(path, line)
+	return nil
+}
+
+// skip records a line that is outside the supported subset
+func (e *profileEnv) skip(path, line string) {
+	relPath, err := filepath.Rel(e.dir, path)
+	if err != nil {
+		relPath = path
+	}
+	e.skipped = append(e.skipped, fmt.Sprintf("%s: %s", relPath, line))
+}
+
+// prependPaths prepends directories, relative to baseDir, to a colon-separated variable
+func (e *profileEnv) prependPaths(name, baseDir string, dirs []string) {
+	current := e.lookup(name)
+	for i := len(dirs) - 1; i >= 0; i-- {
+		dir := resolvePath(baseDir, dirs[i])
+		if current == "" {
+			current = dir
+		} else {
+			current = dir + string(os.PathListSeparator) + current
+		}
+	}
+	e.set(name, current)
+}
+
+// loadDotenv exports the variables of a dotenv file
+func (e *profileEnv) loadDotenv(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m := dotenvLineRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		e.set(m[1], e.dotenvValue(m[2]))
+	}
+	return scanner.Err()
+}
+
+// dotenvValue parses a dotenv value: single quotes are literal, double quotes and
+// unquoted values have variables expanded, and unquoted values end at " #"
+func (e *profileEnv) dotenvValue(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return ""
+	}
+	switch value[0] {
+	case '\'':
+		if end := strings.IndexByte(value[1:], '\''); end >= 0 {
+			return value[1 : end+1]
+		}
+		return value[1:]
+	case '"':
+		var b strings.Builder
+		for i := 1; i < len(value); i++ {
+			c := value[i]
+			if c == '"' {
+				break
+			}
+			if c == '\\' && i+1 < len(value) {
+				i++
+				switch value[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				default:
+					b.WriteByte(value[i])
+				}
+				continue
+			}
+			b.WriteByte(c)
+		}
+		return e.expand(b.String())
+	default:
+		if idx := strings.Index(value, " #"); idx >= 0 {
+			value = strings.TrimSpace(value[:idx])
+		}
+		return e.expand(value)
+	}
+}
+
+// shellValue evaluates the right-hand side of a shell assignment. It returns false for
+// values that need a shell, such as command substitutions.
+func (e *profileEnv) shellValue(value string) (string, bool) {
+	if strings.Contains(value, "$(") || strings.Contains(value, "`") {
+		return "", false
+	}
+	words, ok := e.shellWords([]string{value})
+	switch {
+	case !ok || len(words) > 1:
+		return "", false
+	case len(words) == 0:
+		return "", true
+	default:
+		return words[0], true
+	}
+}
+
+// shellWords re-joins whitespace-split fields and evaluates them as shell words with
+// quote removal and variable expansion. It returns false if the words cannot be evaluated.
+func (e *profileEnv) shellWords(fields []string) ([]string, bool) {
+	input := strings.Join(fields, " ")
+	if strings.Contains(input, "$(") || strings.Contains(input, "`") {
+		return nil, false
+	}
+	// Trailing comments are not part of the words
+	if idx := strings.Index(input, " #"); idx >= 0 && !strings.ContainsAny(input[:idx], `"'`) {
+		input = input[:idx]
+	}
+
+	var words []string
+	var b strings.Builder
+	inWord := false
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t':
+			if inWord {
+				words = append(words, b.String())
+				b.Reset()
+				inWord = false
+			}
+		case c == '\'':
+			end := strings.IndexByte(input[i+1:], '\'')
+			if end < 0 {
+				return nil, false
+			}
+			b.WriteString(input[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '"':
+			end := strings.IndexByte(input[i+1:], '"')
+			if end < 0 {
+				return nil, false
+			}
+			b.WriteString(e.expand(input[i+1 : i+1+end]))
+			i += end + 1
+			inWord = true
+		case c == ';' || c == '&' || c == '|' || c == '<' || c == '>':
+			return nil, false
+		default:
+			start := i
+			for i < len(input) && !strings.ContainsRune(" \t'\";&|<>", rune(input[i])) {
+				i++
+			}
+			b.WriteString(e.expand(input[start:i]))
+			i--
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, b.String())
+	}
+	return words, true
+}
+
+// expand replaces $NAME, ${NAME} and ${NAME:-default} with their values
+func (e *profileEnv) expand(s string) string {
+	return os.Expand(s, func(name string) string {
+		if key, def, found := strings.Cut(name, ":-"); found {
+			if value := e.lookup(key); value != "" {
+				return value
+			}
+			return def
+		}
+		return e.lookup(name)
+	})
+}
+
+// resolvePath makes path absolute relative to baseDir, expanding a leading ~
+func resolvePath(baseDir, path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(homeDir, strings.TrimPrefix(path, "~"))
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+	return filepath.Clean(path)
+}
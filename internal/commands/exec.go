@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+type ExecOptions struct {
+	ProfileName string
+	Dir         string
+	Command     []string
+}
+
+// ExitError carries the exit status of a command run inside a profile, so that
+// it can be passed through as the exit status of shell-profiler
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("command exited with status %d", e.Code)
+}
+
+// ExecInProfile runs a command with the profile's environment loaded, without changing
+// the current shell's directory
+func ExecInProfile(profilesDir string, opts ExecOptions) error {
+	if opts.ProfileName == "" {
+		return fmt.Errorf("profile name is required")
+	}
+	if len(opts.Command) == 0 {
+		return fmt.Errorf("command is required")
+	}
+
+	profileDir := filepath.Join(profilesDir, opts.ProfileName)
+	if _, err := os.Stat(filepath.Join(profileDir, ".envrc")); os.IsNotExist(err) {
+		return fmt.Errorf("profile '%s' does not exist at: %s", opts.ProfileName, profileDir)
+	}
+	warnProfileSchema(profileDir)
+
+	env, err := loadProfileEnv(profileDir)
+	if err != nil {
+		return fmt.Errorf("failed to load profile environment: %w", err)
+	}
+
+	// Relative working directories are inside the profile
+	dir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	if opts.Dir != "" {
+		dir = resolvePath(env.dir, opts.Dir)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("working directory does not exist: %s", dir)
+	}
+
+	return runWithEnv(env, dir, opts.Command)
+}
+
+// runWithEnv runs a command with the profile environment attached to the terminal and
+// returns an *ExitError if it exits with a non-zero status
+func runWithEnv(env *profileEnv, dir string, command []string) error {
+	path, err := lookPathIn(command[0], env.lookup("PATH"), dir)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(path, command[1:]...)
+	cmd.Args[0] = command[0]
+	cmd.Dir = dir
+	cmd.Env = env.environ()
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to run %s: %w", command[0], err)
+	}
+
+	// The child shares the terminal and receives Ctrl-C itself, so shell-profiler only
+	// ignores it; termination signals sent to shell-profiler alone are forwarded
+	signal.Ignore(os.Interrupt, syscall.SIGQUIT)
+	defer signal.Reset(os.Interrupt, syscall.SIGQUIT)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+	go func() {
+		for sig := range signals {
+			cmd.Process.Signal(sig) //nolint:errcheck // The process may already have exited
+		}
+	}()
+
+	err = cmd.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		code := exitErr.ExitCode()
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			code = 128 + int(status.Signal())
+		}
+		return &ExitError{Code: code}
+	}
+	return err
+}
+
+// lookPathIn finds an executable like exec.LookPath, but searches the given PATH value
+func lookPathIn(name, pathEnv, dir string) (string, error) {
+	if strings.Contains(name, "/") {
+		path := resolvePath(dir, name)
+		if isExecutable(path) {
+			return path, nil
+		}
+		return "", fmt.Errorf("%s: not an executable file", name)
+	}
+	for _, entry := range filepath.SplitList(pathEnv) {
+		if entry == "" {
+			entry = "."
+		}
+		path := filepath.Join(resolvePath(dir, entry), name)
+		if isExecutable(path) {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%s: command not found in the profile's PATH", name)
+}
+
+// isExecutable reports whether path is a regular file with an execute bit set
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0111 != 0
+}
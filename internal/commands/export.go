@@ -0,0 +1,471 @@
+package commands
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/neverprepared/shell-profile-manager/internal/ui"
+)
+
+const (
+	bundleManifestName = "manifest.json"
+	bundleSecretsName  = "secrets.tar.gz.enc"
+	bundleVersion      = 1
+	bundleExt          = ".tar.gz"
+)
+
+type ExportOptions struct {
+	ProfileName string
+	Output      string
+	WithSecrets bool
+	Force       bool
+}
+
+type ImportOptions struct {
+	File    string
+	NewName string
+	Force   bool
+	DryRun  bool
+}
+
+// bundleManifest is the first entry of an export bundle
+type bundleManifest struct {
+	Version       int            `json:"version"`
+	Profile       string         `json:"profile"`
+	Template      string         `json:"template"`
+	SchemaVersion int            `json:"schema_version"`
+	GitRemote     string         `json:"git_remote,omitempty"`
+	Exported      time.Time      `json:"exported"`
+	OriginalPath  string         `json:"original_path"`
+	Home          string         `json:"home,omitempty"`
+	Secrets       string         `json:"secrets"`
+	SecretFiles   []string       `json:"secret_files,omitempty"`
+	Files         []manifestFile `json:"files"`
+}
+
+// ExportProfile writes a profile into a single portable bundle
+func ExportProfile(profilesDir string, opts ExportOptions) error {
+	// If no profile name provided, show interactive selection
+	if opts.ProfileName == "" {
+		selected, err := selectProfile(profilesDir, "Select profile to export:")
+		if err != nil {
+			return err
+		}
+		opts.ProfileName = selected
+	}
+
+	profileDir := filepath.Join(profilesDir, opts.ProfileName)
+
+	// Check if profile exists
+	if _, err := os.Stat(filepath.Join(profileDir, ".envrc")); os.IsNotExist(err) {
+		return fmt.Errorf("profile '%s' does not exist at: %s", opts.ProfileName, profileDir)
+	}
+
+	if opts.Output == "" {
+		opts.Output = opts.ProfileName + bundleExt
+	}
+	if _, err := os.Stat(opts.Output); err == nil && !opts.Force {
+		return fmt.Errorf("'%s' already exists (use --force to overwrite)", opts.Output)
+	}
+
+	absDir, err := filepath.Abs(profileDir)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	// History, legacy backups and checkouts stay on this machine
+	all, err := collectFiles(profileDir, snapshotSkip)
+	if err != nil {
+		return fmt.Errorf("failed to read profile: %w", err)
+	}
+	var public, secret []manifestFile
+	var secretPaths []string
+	for _, f := range all {
+		if isSensitivePath(f.Path, f.Dir) {
+			secret = append(secret, f)
+			if !f.Dir {
+				secretPaths = append(secretPaths, f.Path)
+			}
+		} else {
+			public = append(public, f)
+		}
+	}
+
+	manifest := bundleManifest{
+		Version:       bundleVersion,
+		Profile:       opts.ProfileName,
+		Template:      profileTemplate(profileDir),
+		SchemaVersion: profileMeta(profileDir).SchemaVersion,
+		GitRemote:     gitRemoteURL(profileDir),
+		Exported:      time.Now().UTC(),
+		OriginalPath:  absDir,
+		Secrets:       secretsExcluded,
+		SecretFiles:   secretPaths,
+		Files:         public,
+	}
+
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		manifest.Home = homeDir
+	}
+
+	extra := map[string][]byte{}
+
+	// Without secrets, .env is shipped with its values blanked out
+	if !opts.WithSecrets {
+		if content, err := os.ReadFile(filepath.Join(profileDir, ".env")); err == nil {
+			extra[tarFilesPrefix+".env"] = []byte(redactEnv(string(content)))
+		}
+	}
+
+	if opts.WithSecrets && len(secret) > 0 {
+		passphrase, err := readPassphrase("Passphrase for the exported secrets:", true)
+		if err != nil {
+			return err
+		}
+		var secretsArchive bytes.Buffer
+		if err := writeTarball(&secretsArchive, profileDir, secret, archiveManifest(opts.ProfileName, "export", secret)); err != nil {
+			return fmt.Errorf("failed to archive secrets: %w", err)
+		}
+		encrypted, err := encryptSecrets(secretsArchive.Bytes(), passphrase)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt secrets: %w", err)
+		}
+		extra[bundleSecretsName] = encrypted
+		manifest.Secrets = secretsEncrypted
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	extra[bundleManifestName] = data
+
+	ui.PrintInfo(fmt.Sprintf("Exporting profile: %s", opts.ProfileName))
+
+	tmpPath := opts.Output + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+	if err := writeTarball(file, profileDir, public, extra); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := os.Rename(tmpPath, opts.Output); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	info, err := os.Stat(opts.Output)
+	if err != nil {
+		return err
+	}
+
+	ui.PrintSuccess(fmt.Sprintf("Profile exported: %s", opts.Output))
+	fmt.Printf("  Files: %d (%s)\n", countFiles(public), formatFileSize(info.Size()))
+	if len(secretPaths) > 0 {
+		if manifest.Secrets == secretsEncrypted {
+			fmt.Printf("  Secrets: %d file(s), encrypted with a passphrase\n", len(secretPaths))
+		} else {
+			fmt.Printf("  Secrets: %d file(s) not included (use --with-secrets to include them)\n", len(secretPaths))
+		}
+	}
+	fmt.Printf("  Import with: shell-profiler import %s\n", opts.Output)
+	return nil
+}
+
+// ImportProfile recreates a profile from an export bundle in profilesDir
+func ImportProfile(profilesDir string, opts ImportOptions) error {
+	if opts.File == "" {
+		return fmt.Errorf("bundle file is required")
+	}
+
+	manifest, err := readBundleManifest(opts.File)
+	if err != nil {
+		return err
+	}
+	if manifest.Version > bundleVersion {
+		return fmt.Errorf("bundle version %d is newer than this shell-profiler supports (%d)", manifest.Version, bundleVersion)
+	}
+	if manifest.SchemaVersion > profileSchemaVersion {
+		ui.PrintWarning(fmt.Sprintf("Profile schema %d is newer than this shell-profiler (%d); some features may not work", manifest.SchemaVersion, profileSchemaVersion))
+	}
+
+	name := manifest.Profile
+	if opts.NewName != "" {
+		name = opts.NewName
+	}
+	if err := validateProfileName(name); err != nil {
+		return err
+	}
+
+	profileDir := filepath.Join(profilesDir, name)
+	absDir, err := filepath.Abs(profileDir)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	// Check for name collisions
+	if _, err := os.Stat(profileDir); err == nil && !opts.Force {
+		return fmt.Errorf("profile '%s' already exists at: %s (use --as <name> to import under another name, or --force to replace it)", name, profileDir)
+	}
+
+	ui.PrintInfo(fmt.Sprintf("Importing profile: %s", manifest.Profile))
+	fmt.Printf("  Bundle:   %s\n", opts.File)
+	fmt.Printf("  Exported: %s from %s\n", manifest.Exported.Local().Format("2006-01-02 15:04:05"), manifest.OriginalPath)
+	fmt.Printf("  Template: %s\n", manifest.Template)
+	fmt.Printf("  Files:    %d\n", countFiles(manifest.Files))
+	fmt.Printf("  To:       %s\n", profileDir)
+	switch {
+	case manifest.Secrets == secretsEncrypted:
+		fmt.Printf("  Secrets:  %d file(s), encrypted\n", len(manifest.SecretFiles))
+	case len(manifest.SecretFiles) > 0:
+		fmt.Printf("  %s⚠ %d sensitive file(s) were not exported and must be recreated%s\n", ui.ColorYellow, len(manifest.SecretFiles), ui.ColorReset)
+	}
+
+	if opts.DryRun {
+		fmt.Println()
+		ui.PrintInfo("DRY RUN - Nothing will be imported")
+		return nil
+	}
+
+	// Decrypt secrets first so a wrong passphrase fails before anything is written
+	var secretsArchive []byte
+	if manifest.Secrets == secretsEncrypted {
+		encrypted, err := readBundleEntry(opts.File, bundleSecretsName)
+		if err != nil {
+			return err
+		}
+		passphrase, err := readPassphrase("Passphrase for the bundled secrets:", false)
+		if err != nil {
+			return err
+		}
+		if secretsArchive, err = decryptSecrets(encrypted, passphrase); err != nil {
+			return fmt.Errorf("failed to decrypt secrets: %w", err)
+		}
+	}
+
+	// Replace an existing profile, keeping a snapshot of it
+	if _, err := os.Stat(profileDir); err == nil {
+		if _, err := createSnapshot(profilesDir, name, "import"); err != nil {
+			return fmt.Errorf("failed to snapshot existing profile: %w", err)
+		}
+		if err := os.RemoveAll(profileDir); err != nil {
+			return fmt.Errorf("failed to remove existing profile: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(profileDir, 0755); err != nil {
+		return fmt.Errorf("failed to create profile directory: %w", err)
+	}
+
+	// Only the files the manifests list are extracted, and only if they match their checksums
+	bundle, err := os.Open(opts.File)
+	if err != nil {
+		return fmt.Errorf("failed to open bundle: %w", err)
+	}
+	err = extractTarball(bundle, profileDir, bundleFiles(manifest))
+	bundle.Close()
+	if err == nil && secretsArchive != nil {
+		var secrets []manifestFile
+		if secrets, err = readSecretsManifest(secretsArchive); err == nil {
+			err = extractTarball(bytes.NewReader(secretsArchive), profileDir, listFiles(secrets))
+		}
+	}
+	if err != nil {
+		os.RemoveAll(profileDir) //nolint:errcheck // Best-effort cleanup of a partial import
+		return fmt.Errorf("failed to extract bundle: %w", err)
+	}
+
+	// Re-render absolute paths and the profile name for this machine
+	if err := rewriteImportedReferences(profileDir, manifest, name, absDir); err != nil {
+		return err
+	}
+	if err := editProfileMetadata(profileDir, func(m *profileMetadata) {
+		m.Name = name
+	}); err != nil {
+		return err
+	}
+
+	// Recreate what was left out of the bundle
+	if err := createProfileDirs(profileDir, profileTools(profileDir)); err != nil {
+		return err
+	}
+	createOpts := CreateOptions{ProfileName: name, Template: manifest.Template, Tools: profileToolNames(profileDir)}
+	if _, err := os.Stat(filepath.Join(profileDir, ".env")); os.IsNotExist(err) {
+		if err := createProfileFile(profileDir, createOpts, ".env"); err != nil {
+			return fmt.Errorf("failed to create .env: %w", err)
+		}
+	}
+	if err := createMissingProfileFiles(profileDir, createOpts); err != nil {
+		return err
+	}
+
+	ui.PrintSuccess(fmt.Sprintf("Profile imported: %s", name))
+	fmt.Println()
+	ui.PrintInfo("Next steps:")
+	fmt.Printf("  1. cd %s\n", profileDir)
+	step := 2
+	if manifest.Secrets != secretsEncrypted && len(manifest.SecretFiles) > 0 {
+		fmt.Printf("  %d. Fill in the values in .env and restore keys and credentials\n", step)
+		step++
+	}
+	if profileMeta(profileDir).SchemaVersion < profileSchemaVersion {
+		fmt.Printf("  %d. shell-profiler update %s (the profile has an older layout)\n", step, name)
+		step++
+	}
+	fmt.Printf("  %d. direnv allow\n", step)
+	step++
+	if manifest.GitRemote != "" {
+		fmt.Printf("  %d. shell-profiler sync init %s --remote %s\n", step, name, manifest.GitRemote)
+	}
+	return nil
+}
+
+// rewriteImportedReferences points the paths and name recorded in a bundle at the imported profile
+func rewriteImportedReferences(profileDir string, manifest bundleManifest, name, absDir string) error {
+	rewrites, err := planReferenceRewrites(profileDir, manifest.Profile, name, manifest.OriginalPath, absDir)
+	if err != nil {
+		return err
+	}
+	if err := applyRewrites(profileDir, rewrites); err != nil {
+		return err
+	}
+
+	// The ~ form depends on the home directory of the exporting machine
+	if manifest.Home != "" && strings.HasPrefix(manifest.OriginalPath, manifest.Home+"/") {
+		oldDisplay := "~" + strings.TrimPrefix(manifest.OriginalPath, manifest.Home)
+		if newDisplay := displayPath(absDir); newDisplay != oldDisplay {
+			rewrites, err := planReferenceRewrites(profileDir, "", "", oldDisplay, newDisplay)
+			if err != nil {
+				return err
+			}
+			if err := applyRewrites(profileDir, rewrites); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applyRewrites writes planned reference rewrites below root
+func applyRewrites(root string, rewrites []fileRewrite) error {
+	for _, r := range rewrites {
+		if err := os.WriteFile(filepath.Join(root, r.RelPath), r.Content, r.Mode); err != nil {
+			return fmt.Errorf("failed to update %s: %w", r.RelPath, err)
+		}
+	}
+	return nil
+}
+
+// readBundleManifest reads the manifest of an export bundle
+func readBundleManifest(path string) (bundleManifest, error) {
+	var manifest bundleManifest
+	data, err := readBundleEntry(path, bundleManifestName)
+	if err != nil {
+		return manifest, err
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("invalid bundle manifest: %w", err)
+	}
+	if manifest.Profile == "" {
+		return manifest, fmt.Errorf("invalid bundle manifest: no profile name")
+	}
+	return manifest, nil
+}
+
+// bundleFiles lists the files a bundle may hold below tarFilesPrefix: those of its manifest
+// and, when its secrets were left out, the .env with its values blanked out
+func bundleFiles(manifest bundleManifest) map[string]manifestFile {
+	files := listFiles(manifest.Files)
+	if manifest.Secrets != secretsEncrypted {
+		for _, path := range manifest.SecretFiles {
+			if path == ".env" {
+				files[path] = manifestFile{Path: path}
+			}
+		}
+	}
+	return files
+}
+
+// listFiles keys manifest files by their path
+func listFiles(files []manifestFile) map[string]manifestFile {
+	listed := make(map[string]manifestFile, len(files))
+	for _, f := range files {
+		listed[f.Path] = f
+	}
+	return listed
+}
+
+// readSecretsManifest returns the files listed by the manifest inside a decrypted secrets tarball
+func readSecretsManifest(secretsArchive []byte) ([]manifestFile, error) {
+	var manifest *snapshotManifest
+	err := readTarballFrom(bytes.NewReader(secretsArchive), func(hdr *tar.Header, r io.Reader) error {
+		if hdr.Name != snapshotManifestName {
+			return nil
+		}
+		manifest = &snapshotManifest{}
+		if err := json.NewDecoder(r).Decode(manifest); err != nil {
+			return err
+		}
+		return io.EOF
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets: %w", err)
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("secrets have no manifest")
+	}
+	return manifest.Files, nil
+}
+
+// readBundleEntry returns the content of a top-level entry of an export bundle
+func readBundleEntry(path, name string) ([]byte, error) {
+	var data []byte
+	err := readTarball(path, func(hdr *tar.Header, r io.Reader) error {
+		if hdr.Name != name {
+			return nil
+		}
+		var err error
+		if data, err = io.ReadAll(r); err != nil {
+			return err
+		}
+		return io.EOF
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle %s: %w", path, err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("%s is not a profile bundle (no %s)", path, name)
+	}
+	return data, nil
+}
+
+// redactEnv blanks every value of a dotenv file that is not a path below the workspace or home directory
+func redactEnv(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found || isEnvPathValue(strings.TrimSpace(value)) {
+			continue
+		}
+		lines[i] = key + `=""`
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// copyTree recursively copies src to dst, preserving permissions and symlinks.
+// skip is called with the path relative to src; skipped directories are not descended into.
+func copyTree(src, dst string, skip func(relPath string, info os.FileInfo) bool) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if relPath != "." && skip != nil && skip(filepath.ToSlash(relPath), info) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		target := filepath.Join(dst, relPath)
+		switch {
+		case info.IsDir():
+			if err := os.MkdirAll(target, info.Mode().Perm()); err != nil {
+				return err
+			}
+			// MkdirAll does not change the mode of an existing directory
+			return os.Chmod(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			// Sockets, devices and pipes are not copied
+			return nil
+		}
+	})
+}
+
+// copyFile copies a single regular file
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// moveTree moves src to dst, falling back to copy and delete when they are on different filesystems
+func moveTree(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	if err := copyTree(src, dst, nil); err != nil {
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	return os.RemoveAll(src)
+}
+
+// dirSize returns the total size of the regular files below root
+func dirSize(root string) int64 {
+	var size int64
+	filepath.Walk(root, func(_ string, info os.FileInfo, err error) error { //nolint:errcheck // Size is informational, errors are not critical
+		if err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -366,3 +366,43 @@ func GetGitStatus(profilesDir string, opts GitOptions) error {
 
 	return nil
 }
+
+// gitRemoteURL returns the origin URL of a profile repository, or "" if there is none
+func gitRemoteURL(profileDir string) string {
+	if _, err := os.Stat(filepath.Join(profileDir, ".git")); err != nil {
+		return ""
+	}
+	cmd := exec.Command("git", "remote", "get-url", "origin")
+	cmd.Dir = profileDir
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// runGit runs git in dir with its output shown to the user
+func runGit(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return nil
+}
+
+// gitOutput runs git in dir and returns its trimmed output
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s failed: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,155 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+)
+
+// hookFileVar names the file the shell integration reads directives from after each
+// command, since a child process cannot change the calling shell's directory itself
+const hookFileVar = "SHELL_PROFILER_HOOK_FILE"
+
+type HookOptions struct {
+	Shell string
+}
+
+// PrintHook prints the shell integration code for the given shell, to be eval'd from an
+// rc file. It defines the sp function: sp use, sp cd and sp back, with every other
+// command passed through to shell-profiler.
+func PrintHook(opts HookOptions) error {
+	shell, err := scriptShell(opts.Shell)
+	if err != nil {
+		return err
+	}
+
+	switch shell {
+	case "fish":
+		fmt.Print(fishHook)
+	default:
+		fmt.Printf(posixHook, shell)
+	}
+	return nil
+}
+
+// hookActive reports whether the command was started by the sp shell function
+func hookActive() bool {
+	return os.Getenv(hookFileVar) != ""
+}
+
+// writeHookDirective asks the sp shell function to run a directive once the command
+// has finished: "cd <dir>" or "reload". It does nothing outside the shell integration.
+func writeHookDirective(directive string) error {
+	path := os.Getenv(hookFileVar)
+	if path == "" {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open hook file: %w", err)
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintln(f, directive); err != nil {
+		return fmt.Errorf("failed to write hook file: %w", err)
+	}
+	return nil
+}
+
+const posixHook = `# shell-profiler shell integration
+# Add to your rc file: eval "$(shell-profiler hook %[1]s)"
+sp() {
+    local _sp_subdir= _sp_file _sp_status _sp_line _sp_dir
+    case "${1:-}" in
+        "")
+            command shell-profiler help
+            return
+            ;;
+        use)
+            shift
+            set -- select "$@" --allow-direnv
+            ;;
+        cd)
+            _sp_subdir=${3:-}
+            set -- select ${2:+"$2"}
+            ;;
+        back)
+            if [ -z "${_SP_PREV_DIR:-}" ]; then
+                echo "sp: no previous directory" >&2
+                return 1
+            fi
+            cd -- "$_SP_PREV_DIR" || return
+            _SP_PREV_DIR=$OLDPWD
+            return
+            ;;
+    esac
+
+    _sp_file=$(mktemp) || return
+    SHELL_PROFILER_HOOK_FILE=$_sp_file command shell-profiler "$@"
+    _sp_status=$?
+    while IFS= read -r _sp_line; do
+        case "$_sp_line" in
+            "cd "*)
+                _sp_dir=$PWD
+                if cd -- "${_sp_line#cd }"; then
+                    _SP_PREV_DIR=$_sp_dir
+                    [ -z "$_sp_subdir" ] || cd -- "$_sp_subdir" || _sp_status=1
+                fi
+                ;;
+            reload)
+                if [ -n "${DIRENV_DIR:-}" ] && command -v direnv >/dev/null 2>&1; then
+                    direnv reload
+                fi
+                ;;
+        esac
+    done < "$_sp_file"
+    rm -f "$_sp_file"
+    return $_sp_status
+}
+`
+
+const fishHook = `# shell-profiler shell integration
+# Add to ~/.config/fish/config.fish: shell-profiler hook fish | source
+function sp --description 'shell-profiler shell integration'
+    set -l subdir
+    switch "$argv[1]"
+        case ''
+            command shell-profiler help
+            return
+        case use
+            set argv select $argv[2..-1] --allow-direnv
+        case cd
+            set subdir $argv[3]
+            set argv select $argv[2]
+        case back
+            if not set -q _sp_prev_dir
+                echo 'sp: no previous directory' >&2
+                return 1
+            end
+            set -l dir $PWD
+            cd $_sp_prev_dir; or return
+            set -g _sp_prev_dir $dir
+            return
+    end
+
+    set -l file (mktemp); or return
+    env SHELL_PROFILER_HOOK_FILE=$file shell-profiler $argv
+    set -l result $status
+    while read -l line
+        switch $line
+            case 'cd *'
+                set -l dir $PWD
+                if cd (string sub -s 4 -- $line)
+                    set -g _sp_prev_dir $dir
+                    if test -n "$subdir"
+                        cd $subdir; or set result 1
+                    end
+                end
+            case reload
+                if set -q DIRENV_DIR; and command -q direnv
+                    direnv reload
+                end
+        end
+    end < $file
+    rm -f $file
+    return $result
+end
+`
@@ -12,9 +12,10 @@ import (
 )
 
 type InitOptions struct {
-	ProfilesDir string
-	Force       bool
-	Interactive bool
+	ProfilesDir  string
+	TemplatesDir string
+	Force        bool
+	Interactive  bool
 }
 
 // InitConfig initializes the profile manager configuration
@@ -68,9 +69,17 @@ func InitConfig(opts InitOptions) error {
 		return fmt.Errorf("failed to create profiles directory: %w", err)
 	}
 
-	// Save config
-	cfg := &config.Config{
-		ProfilesDir: opts.ProfilesDir,
+	// Save config, keeping any other settings from an existing config file
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		cfg, err = config.GetDefaultConfig()
+		if err != nil {
+			return fmt.Errorf("failed to get default config: %w", err)
+		}
+	}
+	cfg.ProfilesDir = opts.ProfilesDir
+	if opts.TemplatesDir != "" {
+		cfg.TemplatesDir = expandPath(opts.TemplatesDir)
 	}
 
 	if err := config.SaveConfig(cfg); err != nil {
@@ -80,6 +89,7 @@ func InitConfig(opts InitOptions) error {
 	ui.PrintSuccess("Profile manager initialized successfully")
 	fmt.Println()
 	fmt.Printf("  Profiles directory: %s\n", opts.ProfilesDir)
+	fmt.Printf("  Templates directory: %s\n", cfg.TemplatesDir)
 	fmt.Printf("  Config file: %s\n", configPath)
 	fmt.Println()
 	ui.PrintInfo("Next steps:")
@@ -14,9 +14,14 @@ type ListOptions struct {
 	Verbose     bool
 	ShowConfig  bool
 	Interactive bool
+	Archived    bool
 }
 
 func ListProfiles(profilesDir string, opts ListOptions) error {
+	// Archived profiles live outside the profiles directory
+	if opts.Archived {
+		return listArchivedProfiles()
+	}
 
 	// Check if profiles directory exists
 	if _, err := os.Stat(profilesDir); os.IsNotExist(err) {
@@ -78,7 +83,6 @@ func ListProfiles(profilesDir string, opts ListOptions) error {
 		profileDir := filepath.Join(profilesDir, profileName)
 		envrcFile := filepath.Join(profileDir, ".envrc")
 		gitconfigFile := filepath.Join(profileDir, ".gitconfig")
-		readmeFile := filepath.Join(profileDir, "README.md")
 
 		// Profile header
 		if currentProfile == profileName {
@@ -130,24 +134,8 @@ func ListProfiles(profilesDir string, opts ListOptions) error {
 
 		// Verbose mode
 		if opts.Verbose {
-			// Check for README
-			if _, err := os.Stat(readmeFile); err == nil {
-				readmeContent, readErr := os.ReadFile(readmeFile)
-				if readErr != nil {
-					continue
-				}
-				lines := strings.Split(string(readmeContent), "\n")
-				for _, line := range lines {
-					if strings.HasPrefix(line, "Template:") {
-						template := strings.TrimSpace(strings.TrimPrefix(line, "Template:"))
-						fmt.Printf("  %sTemplate:%s %s\n", ui.ColorBlue, ui.ColorReset, template)
-					}
-					if strings.HasPrefix(line, "Created:") {
-						created := strings.TrimSpace(strings.TrimPrefix(line, "Created:"))
-						fmt.Printf("  %sCreated:%s %s\n", ui.ColorBlue, ui.ColorReset, created)
-					}
-				}
-			}
+			// Template, tools and times from the profile's metadata
+			printProfileMetadata(profileDir)
 
 			// Check for .env file
 			envFile := filepath.Join(profileDir, ".env")
@@ -218,7 +206,6 @@ func showProfileDetails(profileDir, profileName string, opts ListOptions) error
 
 	envrcFile := filepath.Join(profileDir, ".envrc")
 	gitconfigFile := filepath.Join(profileDir, ".gitconfig")
-	readmeFile := filepath.Join(profileDir, "README.md")
 
 	// Show path
 	fmt.Printf("  %sPath:%s %s\n", ui.ColorBlue, ui.ColorReset, profileDir)
@@ -263,23 +250,8 @@ func showProfileDetails(profileDir, profileName string, opts ListOptions) error
 
 	// Always show verbose info in interactive mode
 	if opts.Verbose || opts.Interactive {
-		// Check for README
-		if _, err := os.Stat(readmeFile); err == nil {
-			readmeContent, readErr := os.ReadFile(readmeFile)
-			if readErr == nil {
-				lines := strings.Split(string(readmeContent), "\n")
-				for _, line := range lines {
-					if strings.HasPrefix(line, "Template:") {
-						template := strings.TrimSpace(strings.TrimPrefix(line, "Template:"))
-						fmt.Printf("  %sTemplate:%s %s\n", ui.ColorBlue, ui.ColorReset, template)
-					}
-					if strings.HasPrefix(line, "Created:") {
-						created := strings.TrimSpace(strings.TrimPrefix(line, "Created:"))
-						fmt.Printf("  %sCreated:%s %s\n", ui.ColorBlue, ui.ColorReset, created)
-					}
-				}
-			}
-		}
+		// Template, tools and times from the profile's metadata
+		printProfileMetadata(profileDir)
 
 		// Check for .env file
 		envFile := filepath.Join(profileDir, ".env")
@@ -0,0 +1,225 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Markers around the content of generated files that shell-profiler keeps up to date
+const (
+	managedBegin = "# >>> shell-profiler managed >>>"
+	managedEnd   = "# <<< shell-profiler managed <<<"
+)
+
+// managedFiles are the generated files with a managed section
+var managedFiles = []string{".envrc", ".env", ".gitignore", ".ssh/config"}
+
+// managedUpdate is the outcome of bringing the managed section of a file up to date
+type managedUpdate struct {
+	changed   bool // the section was rewritten
+	merged    bool // changes made to the section in the profile were kept
+	conflicts [][]string
+}
+
+// splitManaged splits content around its managed section: the text up to and including
+// the begin marker, the lines between the markers, and the text from the end marker on
+func splitManaged(content string) (head, section, tail string, ok bool) {
+	lines := strings.SplitAfter(content, "\n")
+	begin := -1
+	for i, line := range lines {
+		switch strings.TrimRight(line, "\n") {
+		case managedBegin:
+			if begin < 0 {
+				begin = i
+			}
+		case managedEnd:
+			if begin >= 0 {
+				return strings.Join(lines[:begin+1], ""), strings.Join(lines[begin+1:i], ""), strings.Join(lines[i:], ""), true
+			}
+		}
+	}
+	return "", "", "", false
+}
+
+// joinLines joins lines split by splitLines back into content
+func joinLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// hasManagedSection reports whether a profile file has a managed section
+func hasManagedSection(profileDir, path string) bool {
+	content, err := os.ReadFile(filepath.Join(profileDir, filepath.FromSlash(path)))
+	if err != nil {
+		return false
+	}
+	_, _, _, ok := splitManaged(string(content))
+	return ok
+}
+
+// checkManagedConflicts returns an error if a managed file of the profile still holds the
+// conflict markers of an earlier merge
+func checkManagedConflicts(profileDir string) error {
+	for _, path := range managedFiles {
+		content, err := os.ReadFile(filepath.Join(profileDir, filepath.FromSlash(path)))
+		if err == nil && hasConflictMarkers(string(content)) {
+			return unresolvedConflictError(path)
+		}
+	}
+	return nil
+}
+
+// unresolvedConflictError is the error for a file that still holds conflict markers
+func unresolvedConflictError(path string) error {
+	return fmt.Errorf("%s has unresolved merge conflicts; edit it to resolve them and remove the conflict markers", path)
+}
+
+// renderManaged renders a file of the profile's template as it would be generated now
+func renderManaged(profileDir, path string) (string, error) {
+	opts := profileCreateOptions(profileDir)
+	tmpl, err := findProfileTemplate(opts.Template)
+	if err != nil {
+		return "", err
+	}
+	data, err := templateData(profileDir, opts, tmpl)
+	if err != nil {
+		return "", err
+	}
+	file, err := tmpl.RenderFile(path, data)
+	if err != nil {
+		return "", err
+	}
+	return string(file.Content), nil
+}
+
+// updateManagedSection brings the managed section of a profile file up to date with the
+// profile's template. Changes made to the section since it was generated are kept with a
+// three-way merge of the section as last generated (the base, kept in the metadata), as
+// it is in the file, and as the template generates it now. Files without a managed
+// section, in the profile or in its template, are left alone.
+func updateManagedSection(profileDir, path string, dryRun bool) (managedUpdate, error) {
+	var result managedUpdate
+	filePath := filepath.Join(profileDir, filepath.FromSlash(path))
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		// Missing files are recreated whole
+		return result, nil
+	}
+	head, ours, tail, ok := splitManaged(string(content))
+	if !ok {
+		return result, nil
+	}
+	if hasConflictMarkers(ours) {
+		return result, unresolvedConflictError(path)
+	}
+
+	generated, err := renderManaged(profileDir, path)
+	if err != nil {
+		return result, fmt.Errorf("failed to render %s from the profile's template: %w", path, err)
+	}
+	_, theirs, _, ok := splitManaged(generated)
+	if !ok {
+		return result, nil
+	}
+
+	meta, err := loadProfileMetadata(profileDir)
+	if err != nil {
+		return result, err
+	}
+	// Without a base, every difference is a conflict
+	base, hasBase := meta.Managed[path]
+
+	merged := ours
+	if ours != theirs && theirs != base {
+		lines, conflicts := mergeLines(splitLines(base), splitLines(ours), splitLines(theirs), "current", "generated")
+		merged = joinLines(lines)
+		result.changed = merged != ours
+		result.merged = ours != base
+		result.conflicts = conflicts
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	if result.changed {
+		if err := os.WriteFile(filePath, []byte(head+merged+tail), 0644); err != nil {
+			return result, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	if !hasBase || base != theirs {
+		if err := editProfileMetadata(profileDir, func(m *profileMetadata) {
+			if m.Managed == nil {
+				m.Managed = map[string]string{}
+			}
+			m.Managed[path] = theirs
+		}); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// recordManagedSections keeps the managed sections of a new profile's files in its
+// metadata, as the base that later updates are merged against
+func recordManagedSections(profileDir string, m *profileMetadata) {
+	for _, path := range managedFiles {
+		content, err := os.ReadFile(filepath.Join(profileDir, filepath.FromSlash(path)))
+		if err != nil {
+			continue
+		}
+		if _, section, _, ok := splitManaged(string(content)); ok {
+			if m.Managed == nil {
+				m.Managed = map[string]string{}
+			}
+			m.Managed[path] = section
+		}
+	}
+}
+
+// adoptManagedSection puts markers around the generated content of a file written before
+// managed sections: the lines from the first to the last one that only the template's
+// managed section generates. It returns the file with the markers and the base for later
+// merges, which is the adopted lines less those the template never generates, so that
+// they are kept as changes made in the profile. ok is false if the file has none of the
+// section's lines.
+func adoptManagedSection(content, generated string) (adopted, base string, ok bool) {
+	head, section, tail, ok := splitManaged(generated)
+	if !ok {
+		return "", "", false
+	}
+	outside := make(map[string]bool)
+	for _, line := range splitLines(head + tail) {
+		outside[line] = true
+	}
+	inside := make(map[string]bool)
+	for _, line := range splitLines(section) {
+		inside[line] = true
+	}
+
+	lines := splitLines(content)
+	first, last := -1, -1
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" && inside[line] && !outside[line] {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		return "", "", false
+	}
+
+	var baseLines []string
+	for _, line := range lines[first : last+1] {
+		if inside[line] {
+			baseLines = append(baseLines, line)
+		}
+	}
+	adopted = joinLines(lines[:first]) + managedBegin + "\n" + joinLines(lines[first:last+1]) + managedEnd + "\n" + joinLines(lines[last+1:])
+	return adopted, joinLines(baseLines), true
+}
@@ -0,0 +1,120 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/neverprepared/shell-profile-manager/internal/ui"
+)
+
+// Conflict markers, as git writes them
+const (
+	conflictOurs   = "<<<<<<<"
+	conflictSep    = "======="
+	conflictTheirs = ">>>>>>>"
+)
+
+// mergeLines merges the changes from base to ours and from base to theirs, line by line,
+// like diff3. Where both sides changed the same lines differently, the result holds both
+// between git-style conflict markers labelled oursName and theirsName. Returns the merged
+// lines and the conflicts, each as it appears in the result.
+func mergeLines(base, ours, theirs []string, oursName, theirsName string) ([]string, [][]string) {
+	matchOurs := matchBase(base, ours)
+	matchTheirs := matchBase(base, theirs)
+
+	var merged []string
+	var conflicts [][]string
+	b, o, t := 0, 0, 0
+	for i := 0; i <= len(base); i++ {
+		// Base lines kept by both sides split the files into chunks; the end is the last split
+		oi, ti := len(ours), len(theirs)
+		if i < len(base) {
+			if matchOurs[i] < 0 || matchTheirs[i] < 0 {
+				continue
+			}
+			oi, ti = matchOurs[i], matchTheirs[i]
+		}
+
+		baseChunk, oursChunk, theirsChunk := base[b:i], ours[o:oi], theirs[t:ti]
+		switch {
+		case equalLines(oursChunk, baseChunk):
+			merged = append(merged, theirsChunk...)
+		case equalLines(theirsChunk, baseChunk), equalLines(oursChunk, theirsChunk):
+			merged = append(merged, oursChunk...)
+		default:
+			conflict := []string{conflictOurs + " " + oursName}
+			conflict = append(conflict, oursChunk...)
+			conflict = append(conflict, conflictSep)
+			conflict = append(conflict, theirsChunk...)
+			conflict = append(conflict, conflictTheirs+" "+theirsName)
+			merged = append(merged, conflict...)
+			conflicts = append(conflicts, conflict)
+		}
+
+		if i < len(base) {
+			merged = append(merged, base[i])
+		}
+		b, o, t = i+1, oi+1, ti+1
+	}
+	return merged, conflicts
+}
+
+// matchBase returns, for each line of base, the index of the line of other it is kept as,
+// or -1 if other removed or changed it
+func matchBase(base, other []string) []int {
+	match := make([]int, len(base))
+	i, j := 0, 0
+	for _, op := range diffLines(base, other) {
+		switch op.kind {
+		case ' ':
+			match[i] = j
+			i++
+			j++
+		case '-':
+			match[i] = -1
+			i++
+		case '+':
+			j++
+		}
+	}
+	return match
+}
+
+// equalLines reports whether two line slices are the same
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// hasConflictMarkers reports whether content still holds conflict markers from a merge
+func hasConflictMarkers(content string) bool {
+	for _, line := range splitLines(content) {
+		if strings.HasPrefix(line, conflictOurs+" ") || strings.HasPrefix(line, conflictTheirs+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// printConflicts reports the conflicts of a merged file the way git does, with each
+// conflict as it was written to the file
+func printConflicts(path string, conflicts [][]string) {
+	fmt.Printf("%sCONFLICT (content): Merge conflict in %s%s\n", ui.ColorRed, path, ui.ColorReset)
+	for _, conflict := range conflicts {
+		for _, line := range conflict {
+			switch {
+			case strings.HasPrefix(line, conflictOurs), strings.HasPrefix(line, conflictSep), strings.HasPrefix(line, conflictTheirs):
+				fmt.Printf("  %s%s%s\n", ui.ColorYellow, line, ui.ColorReset)
+			default:
+				fmt.Printf("  %s\n", line)
+			}
+		}
+	}
+}
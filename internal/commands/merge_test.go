@@ -0,0 +1,200 @@
+package commands
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeLines(t *testing.T) {
+	conflict := func(ours, theirs string) []string {
+		lines := []string{conflictOurs + " current"}
+		lines = append(lines, splitLines(ours)...)
+		lines = append(lines, conflictSep)
+		lines = append(lines, splitLines(theirs)...)
+		return append(lines, conflictTheirs+" generated")
+	}
+
+	tests := []struct {
+		name      string
+		base      string
+		ours      string
+		theirs    string
+		want      []string
+		conflicts int
+	}{
+		{
+			name:   "no changes",
+			base:   "a\nb\nc\n",
+			ours:   "a\nb\nc\n",
+			theirs: "a\nb\nc\n",
+			want:   []string{"a", "b", "c"},
+		},
+		{
+			name:   "change in ours only",
+			base:   "a\nb\nc\n",
+			ours:   "a\nB\nc\n",
+			theirs: "a\nb\nc\n",
+			want:   []string{"a", "B", "c"},
+		},
+		{
+			name:   "change in theirs only",
+			base:   "a\nb\nc\n",
+			ours:   "a\nb\nc\n",
+			theirs: "a\nb\nC\n",
+			want:   []string{"a", "b", "C"},
+		},
+		{
+			name:   "line added in ours, line removed in theirs",
+			base:   "a\nb\nc\n",
+			ours:   "a\nb\nc\nd\n",
+			theirs: "b\nc\n",
+			want:   []string{"b", "c", "d"},
+		},
+		{
+			name:   "separate changes on both sides",
+			base:   "a\nb\nc\nd\ne\n",
+			ours:   "A\nb\nc\nd\ne\n",
+			theirs: "a\nb\nc\nd\nE\n",
+			want:   []string{"A", "b", "c", "d", "E"},
+		},
+		{
+			name:   "same change on both sides",
+			base:   "a\nb\nc\n",
+			ours:   "a\nX\nc\n",
+			theirs: "a\nX\nc\n",
+			want:   []string{"a", "X", "c"},
+		},
+		{
+			name:      "conflicting changes",
+			base:      "a\nb\nc\n",
+			ours:      "a\nO\nc\n",
+			theirs:    "a\nT\nc\n",
+			want:      append(append([]string{"a"}, conflict("O\n", "T\n")...), "c"),
+			conflicts: 1,
+		},
+		{
+			name:      "change in ours, removal in theirs",
+			base:      "a\nb\nc\n",
+			ours:      "a\nO\nc\n",
+			theirs:    "a\nc\n",
+			want:      append(append([]string{"a"}, conflict("O\n", "")...), "c"),
+			conflicts: 1,
+		},
+		{
+			name:   "empty base, same content",
+			base:   "",
+			ours:   "a\nb\n",
+			theirs: "a\nb\n",
+			want:   []string{"a", "b"},
+		},
+		{
+			name:   "empty base, empty ours",
+			base:   "",
+			ours:   "",
+			theirs: "a\nb\n",
+			want:   []string{"a", "b"},
+		},
+		{
+			name:      "empty base, different content",
+			base:      "",
+			ours:      "a\nb\n",
+			theirs:    "a\nc\n",
+			want:      conflict("a\nb\n", "a\nc\n"),
+			conflicts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, conflicts := mergeLines(splitLines(tt.base), splitLines(tt.ours), splitLines(tt.theirs), "current", "generated")
+			if !equalLines(got, tt.want) {
+				t.Errorf("merged:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			if len(conflicts) != tt.conflicts {
+				t.Errorf("got %d conflicts, want %d", len(conflicts), tt.conflicts)
+			}
+		})
+	}
+}
+
+func TestAdoptManagedSection(t *testing.T) {
+	generated := "# header\n" + managedBegin + "\nexport A=1\nexport B=2\n\nexport C=3\n" + managedEnd + "\n# footer\n"
+
+	tests := []struct {
+		name     string
+		content  string
+		ok       bool
+		adopted  string
+		wantBase string
+	}{
+		{
+			name:     "unchanged file",
+			content:  "# header\nexport A=1\nexport B=2\n\nexport C=3\n# footer\n",
+			ok:       true,
+			adopted:  "# header\n" + managedBegin + "\nexport A=1\nexport B=2\n\nexport C=3\n" + managedEnd + "\n# footer\n",
+			wantBase: "export A=1\nexport B=2\n\nexport C=3\n",
+		},
+		{
+			name:     "line added in the profile",
+			content:  "# header\nexport A=1\nexport MINE=1\nexport C=3\n# footer\n",
+			ok:       true,
+			adopted:  "# header\n" + managedBegin + "\nexport A=1\nexport MINE=1\nexport C=3\n" + managedEnd + "\n# footer\n",
+			wantBase: "export A=1\nexport C=3\n",
+		},
+		{
+			name:     "lines outside the generated ones",
+			content:  "export MINE=1\nexport B=2\nexport MINE=2\n",
+			ok:       true,
+			adopted:  "export MINE=1\n" + managedBegin + "\nexport B=2\n" + managedEnd + "\nexport MINE=2\n",
+			wantBase: "export B=2\n",
+		},
+		{
+			name:    "only lines outside the managed section",
+			content: "# header\n\n# footer\n",
+			ok:      false,
+		},
+		{
+			name:    "empty file",
+			content: "",
+			ok:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adopted, base, ok := adoptManagedSection(tt.content, generated)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if adopted != tt.adopted {
+				t.Errorf("adopted:\n%s\nwant:\n%s", adopted, tt.adopted)
+			}
+			if base != tt.wantBase {
+				t.Errorf("base:\n%s\nwant:\n%s", base, tt.wantBase)
+			}
+		})
+	}
+
+	t.Run("template without a managed section", func(t *testing.T) {
+		if _, _, ok := adoptManagedSection("export A=1\n", "export A=1\n"); ok {
+			t.Error("ok = true, want false")
+		}
+	})
+}
+
+func TestAdoptedSectionMergesTemplateChanges(t *testing.T) {
+	generated := managedBegin + "\nexport A=1\nexport B=2\nexport C=3\n" + managedEnd + "\n"
+	adopted, base, ok := adoptManagedSection("export A=1\nexport MINE=1\nexport B=2\nexport C=3\n", generated)
+	if !ok {
+		t.Fatal("section not adopted")
+	}
+
+	// The template changes C; the line added in the profile is kept
+	_, ours, _, _ := splitManaged(adopted)
+	got, conflicts := mergeLines(splitLines(base), splitLines(ours), splitLines("export A=1\nexport B=2\nexport C=4\n"), "current", "generated")
+	want := []string{"export A=1", "export MINE=1", "export B=2", "export C=4"}
+	if !reflect.DeepEqual(got, want) || len(conflicts) != 0 {
+		t.Errorf("merged %q with %d conflicts, want %q", got, len(conflicts), want)
+	}
+}
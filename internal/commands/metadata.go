@@ -0,0 +1,244 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/neverprepared/shell-profile-manager/internal/templates"
+	"github.com/neverprepared/shell-profile-manager/internal/tools"
+	"github.com/neverprepared/shell-profile-manager/internal/ui"
+)
+
+// metadataFile records how a profile was made, at the top of the profile
+const metadataFile = ".shell-profiler.json"
+
+// createdLayout is the format of the "Created:" headers of generated files
+const createdLayout = "2006-01-02 15:04:05 UTC"
+
+// profileMetadata is the content of a profile's metadata file. Vars are the values given
+// for the template's variables. Managed holds the managed section of each generated file
+// as it was last generated, the base for merging updates with changes made in the profile.
+type profileMetadata struct {
+	SchemaVersion    int               `json:"schema_version"`
+	Name             string            `json:"name"`
+	Template         string            `json:"template"`
+	TemplateSource   string            `json:"template_source,omitempty"`
+	TemplateRevision string            `json:"template_revision,omitempty"`
+	Created          time.Time         `json:"created"`
+	Updated          time.Time         `json:"updated"`
+	Tools            []string          `json:"tools"`
+	Description      string            `json:"description,omitempty"`
+	Tags             []string          `json:"tags,omitempty"`
+	Vars             map[string]string `json:"vars,omitempty"`
+	Managed          map[string]string `json:"managed,omitempty"`
+
+	// legacy is set for metadata derived from a profile without a metadata file
+	legacy bool
+}
+
+// Headers that profiles created before the metadata file kept their metadata in
+var (
+	legacyTemplateRe = regexp.MustCompile(`(?m)^#? ?Template:[ \t]*(\S+)[ \t]*$`)
+	legacyCreatedRe  = regexp.MustCompile(`(?m)^#? ?Created:[ \t]*(.+?)[ \t]*$`)
+	legacySourceRe   = regexp.MustCompile(`(?m)^# Template source: (\S+) (\S+)[ \t]*$`)
+	legacyToolsRe    = regexp.MustCompile(`(?m)^# Tools:[ \t]*(.*?)[ \t]*$`)
+)
+
+// loadProfileMetadata returns a profile's metadata. A profile without a metadata file
+// predates it; its metadata is derived from the headers of its .envrc and README.md.
+func loadProfileMetadata(profileDir string) (profileMetadata, error) {
+	content, err := os.ReadFile(filepath.Join(profileDir, metadataFile))
+	if os.IsNotExist(err) {
+		return legacyProfileMetadata(profileDir), nil
+	}
+	if err != nil {
+		return profileMetadata{}, fmt.Errorf("failed to read %s: %w", metadataFile, err)
+	}
+
+	var m profileMetadata
+	if err := json.Unmarshal(content, &m); err != nil {
+		return profileMetadata{}, fmt.Errorf("invalid %s: %w", metadataFile, err)
+	}
+	if m.Name == "" {
+		m.Name = filepath.Base(profileDir)
+	}
+	if m.Template == "" {
+		m.Template = templates.BaseTemplate
+	}
+	if m.Tools == nil {
+		m.Tools = tools.Names()
+	}
+	return m, nil
+}
+
+// profileMeta returns a profile's metadata for display and defaults, falling back to the
+// derived metadata if the metadata file cannot be read
+func profileMeta(profileDir string) profileMetadata {
+	m, err := loadProfileMetadata(profileDir)
+	if err != nil {
+		return legacyProfileMetadata(profileDir)
+	}
+	return m
+}
+
+// saveProfileMetadata writes a profile's metadata file
+func saveProfileMetadata(profileDir string, m profileMetadata) error {
+	if m.Tools == nil {
+		m.Tools = []string{}
+	}
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(profileDir, metadataFile), append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", metadataFile, err)
+	}
+	return nil
+}
+
+// editProfileMetadata loads a profile's metadata, applies edit and saves it
+func editProfileMetadata(profileDir string, edit func(*profileMetadata)) error {
+	m, err := loadProfileMetadata(profileDir)
+	if err != nil {
+		return err
+	}
+	edit(&m)
+	return saveProfileMetadata(profileDir, m)
+}
+
+// profileCreateOptions returns the options a profile's files are generated with
+func profileCreateOptions(profileDir string) CreateOptions {
+	m := profileMeta(profileDir)
+	return CreateOptions{
+		ProfileName: filepath.Base(profileDir),
+		Template:    m.Template,
+		Tools:       m.Tools,
+		Vars:        m.Vars,
+	}
+}
+
+// printProfileMetadata prints the metadata of a profile for list and its details
+func printProfileMetadata(profileDir string) {
+	m := profileMeta(profileDir)
+
+	template := m.Template
+	if m.TemplateSource != "" {
+		template += fmt.Sprintf(" (%s@%s)", m.TemplateSource, shortRevision(m.TemplateRevision))
+	}
+	fmt.Printf("  %sTemplate:%s %s\n", ui.ColorBlue, ui.ColorReset, template)
+	if m.Description != "" {
+		fmt.Printf("  %sDescription:%s %s\n", ui.ColorBlue, ui.ColorReset, m.Description)
+	}
+	if len(m.Tags) > 0 {
+		fmt.Printf("  %sTags:%s %s\n", ui.ColorBlue, ui.ColorReset, strings.Join(m.Tags, ", "))
+	}
+	toolList := strings.Join(m.Tools, ", ")
+	if toolList == "" {
+		toolList = "none"
+	}
+	fmt.Printf("  %sTools:%s %s\n", ui.ColorBlue, ui.ColorReset, toolList)
+	if !m.Created.IsZero() {
+		fmt.Printf("  %sCreated:%s %s\n", ui.ColorBlue, ui.ColorReset, m.Created.Format(createdLayout))
+	}
+	if !m.Updated.IsZero() && !m.Updated.Equal(m.Created) {
+		fmt.Printf("  %sUpdated:%s %s\n", ui.ColorBlue, ui.ColorReset, m.Updated.Format(createdLayout))
+	}
+	switch {
+	case m.legacy:
+		fmt.Printf("  %s⚠ No %s%s (run: shell-profiler update %s)\n", ui.ColorYellow, metadataFile, ui.ColorReset, m.Name)
+	case m.SchemaVersion < profileSchemaVersion:
+		fmt.Printf("  %s⚠ Schema version %d, current is %d%s (run: shell-profiler update %s)\n",
+			ui.ColorYellow, m.SchemaVersion, profileSchemaVersion, ui.ColorReset, m.Name)
+	case m.SchemaVersion > profileSchemaVersion:
+		fmt.Printf("  %s⚠ Schema version %d is newer than this shell-profiler (%d)%s\n",
+			ui.ColorYellow, m.SchemaVersion, profileSchemaVersion, ui.ColorReset)
+	}
+}
+
+// legacyProfileMetadata derives the metadata of a profile created before the metadata
+// file from the "# Template:", "# Created:", "# Template source:" and "# Tools:" headers
+// of its .envrc, or the "Template:" and "Created:" lines of its README.md
+func legacyProfileMetadata(profileDir string) profileMetadata {
+	m := profileMetadata{
+		Name:     filepath.Base(profileDir),
+		Template: templates.BaseTemplate,
+		Tools:    tools.Names(),
+		legacy:   true,
+	}
+
+	envrc, _ := os.ReadFile(filepath.Join(profileDir, ".envrc"))
+	readme, _ := os.ReadFile(filepath.Join(profileDir, "README.md"))
+	for _, content := range [][]byte{readme, envrc} {
+		if match := legacyTemplateRe.FindSubmatch(content); match != nil {
+			m.Template = string(match[1])
+		}
+		if match := legacyCreatedRe.FindSubmatch(content); match != nil {
+			if created, err := time.Parse(createdLayout, string(match[1])); err == nil {
+				m.Created = created
+			}
+		}
+	}
+	if m.Created.IsZero() {
+		if info, err := os.Stat(filepath.Join(profileDir, ".envrc")); err == nil {
+			m.Created = info.ModTime().UTC().Truncate(time.Second)
+		}
+	}
+	m.Updated = m.Created
+
+	if match := legacySourceRe.FindSubmatch(envrc); match != nil {
+		m.TemplateSource, m.TemplateRevision = string(match[1]), string(match[2])
+	}
+
+	// Tools that are no longer registered are left out
+	if match := legacyToolsRe.FindSubmatch(envrc); match != nil {
+		m.Tools = []string{}
+		for _, name := range splitToolList(string(match[1])) {
+			if _, err := tools.Find(name); err == nil {
+				m.Tools = append(m.Tools, name)
+			}
+		}
+	}
+	return m
+}
+
+// updateMetadata writes the metadata file of a profile created before it, from its
+// headers, and removes the .envrc headers the metadata file replaces
+func updateMetadata(profileDir string, dryRun bool) (bool, error) {
+	m, err := loadProfileMetadata(profileDir)
+	if err != nil {
+		return false, err
+	}
+	if !m.legacy {
+		return false, nil
+	}
+	if dryRun {
+		return true, nil
+	}
+
+	if err := saveProfileMetadata(profileDir, m); err != nil {
+		return false, err
+	}
+
+	envrcPath := filepath.Join(profileDir, ".envrc")
+	content, err := os.ReadFile(envrcPath)
+	if err != nil {
+		return true, nil
+	}
+	var kept []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if !legacySourceRe.MatchString(line) && !legacyToolsRe.MatchString(line) {
+			kept = append(kept, line)
+		}
+	}
+	if cleaned := strings.Join(kept, "\n"); cleaned != string(content) {
+		if err := os.WriteFile(envrcPath, []byte(cleaned), 0644); err != nil {
+			return true, fmt.Errorf("failed to write .envrc: %w", err)
+		}
+	}
+	return true, nil
+}
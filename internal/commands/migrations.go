@@ -0,0 +1,260 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/neverprepared/shell-profile-manager/internal/tools"
+	"github.com/neverprepared/shell-profile-manager/internal/ui"
+)
+
+// migrationContext is the profile a migration is applied to
+type migrationContext struct {
+	profilesDir string
+	profileDir  string
+	profileName string
+	force       bool
+	dryRun      bool
+}
+
+// migration is a numbered change to the profile layout. A profile at schema version N
+// has had migrations 1 to N applied. Migrations are idempotent: applying one to a profile
+// that already has the change does nothing.
+type migration struct {
+	version int
+	name    string
+	// apply makes the change, or with dryRun reports whether it would. It returns false
+	// if the profile already has it, and errMigrationSkipped if the user declined it.
+	apply func(c migrationContext) (bool, error)
+}
+
+// errMigrationSkipped leaves a migration, and the profile's schema version, for a later update
+var errMigrationSkipped = errors.New("migration skipped")
+
+// migrations upgrade profiles to profileSchemaVersion, in order. Add new migrations at the
+// end and raise profileSchemaVersion to the last version.
+var migrations = []migration{
+	{1, "Split .envrc and .env", migrateSplitEnv},
+	{2, "Move backups out of the profile", migrateBackups},
+	{3, "Add gemini directory", migrateGemini},
+	{4, "Record metadata in " + metadataFile, migrateMetadata},
+	{5, "Mark managed sections", migrateManagedSections},
+}
+
+// pendingMigrations returns the migrations a profile at the given schema version is missing
+func pendingMigrations(version int) []migration {
+	var pending []migration
+	for _, m := range migrations {
+		if m.version > version {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
+
+// migrateProfile applies a profile's pending migrations in order and records the schema
+// version reached: the last migration before one that was skipped. It returns what was
+// done for update to report: the migrations that changed the profile and the new version.
+func migrateProfile(c migrationContext) ([]string, error) {
+	meta, err := loadProfileMetadata(c.profileDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []string
+	version := meta.SchemaVersion
+	skipped := false
+	for _, m := range pendingMigrations(meta.SchemaVersion) {
+		changed, err := m.apply(c)
+		if err == errMigrationSkipped {
+			skipped = true
+			continue
+		}
+		if err != nil {
+			return applied, fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+		}
+		if changed {
+			applied = append(applied, fmt.Sprintf("Migration %d: %s", m.version, m.name))
+		}
+		if !skipped {
+			version = m.version
+		}
+	}
+
+	if version == meta.SchemaVersion {
+		return applied, nil
+	}
+	applied = append(applied, fmt.Sprintf("Schema version %d → %d", meta.SchemaVersion, version))
+	if !c.dryRun {
+		if err := editProfileMetadata(c.profileDir, func(m *profileMetadata) {
+			m.SchemaVersion = version
+		}); err != nil {
+			return applied, err
+		}
+	}
+	return applied, nil
+}
+
+// schemaWarning describes how a profile's schema version differs from this binary's, or
+// returns "" if it does not
+func schemaWarning(profileDir string) string {
+	m, err := loadProfileMetadata(profileDir)
+	if err != nil {
+		return ""
+	}
+	switch {
+	case m.SchemaVersion < profileSchemaVersion:
+		return fmt.Sprintf("Profile %s has schema version %d, this shell-profiler uses %d (run: shell-profiler update %s)",
+			m.Name, m.SchemaVersion, profileSchemaVersion, m.Name)
+	case m.SchemaVersion > profileSchemaVersion:
+		return fmt.Sprintf("Profile %s has schema version %d, newer than this shell-profiler (%d); some features may not work",
+			m.Name, m.SchemaVersion, profileSchemaVersion)
+	}
+	return ""
+}
+
+// warnProfileSchema warns on stderr when a profile's schema version differs from this
+// binary's, so that it does not mix with output meant for eval or pipes
+func warnProfileSchema(profileDir string) {
+	if warning := schemaWarning(profileDir); warning != "" {
+		fmt.Fprintf(os.Stderr, "%sWARNING: %s%s\n", ui.ColorYellow, warning, ui.ColorReset)
+	}
+}
+
+// migrateSplitEnv moves tool-specific variables from .envrc to .env, which .envrc loads
+func migrateSplitEnv(c migrationContext) (bool, error) {
+	updated, err := updateEnvrc(c.profileDir, c.profileName, c.dryRun, c.force)
+	if updated && !c.dryRun {
+		reloadIfActive(c.profileName)
+	}
+	return updated, err
+}
+
+// migrateBackups moves backups kept inside the profile to the shell-profiler state
+// directory so they are never committed or synced
+func migrateBackups(c migrationContext) (bool, error) {
+	sources := legacyBackupSources(c.profilesDir, c.profileName)
+	if len(sources) == 0 {
+		return false, nil
+	}
+
+	if !c.force && !c.dryRun {
+		ui.PrintWarning("This profile keeps backups inside its directory, where they can be committed and pushed")
+		confirmed, err := ui.Confirm("Move them to the shell-profiler state directory?", true)
+		if err != nil {
+			return false, fmt.Errorf("failed to get confirmation: %w", err)
+		}
+		if !confirmed {
+			ui.PrintWarning("Backups left in place; they are excluded from .gitignore but may already be committed")
+			return false, errMigrationSkipped
+		}
+	}
+
+	dest, err := migrateLegacyBackups(c.profileDir, c.profileName, sources, c.dryRun)
+	if err != nil {
+		return false, err
+	}
+	if !c.dryRun {
+		ui.PrintInfo(fmt.Sprintf("Moved backups to %s", dest))
+	}
+	return true, nil
+}
+
+// migrateGemini adds the Gemini CLI directory, .env variable and .gitignore patterns to
+// profiles created before gemini was isolated, if they use it
+func migrateGemini(c migrationContext) (bool, error) {
+	enabled := profileTools(c.profileDir)
+	if !hasTool(enabled, "gemini") {
+		return false, nil
+	}
+	gemini, err := tools.Find("gemini")
+	if err != nil {
+		return false, err
+	}
+	selected := []tools.Tool{gemini}
+	updated := false
+
+	for _, dir := range gemini.Dirs {
+		fullPath := filepath.Join(c.profileDir, dir)
+		if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+			if !c.dryRun {
+				if err := os.MkdirAll(fullPath, 0755); err != nil {
+					return false, fmt.Errorf("failed to create directory %s: %w", dir, err)
+				}
+			}
+			updated = true
+		}
+	}
+
+	// Missing files are left to update, which recreates them whole
+	record := func(edited string, changed bool) (string, bool) {
+		updated = updated || changed
+		return edited, changed && !c.dryRun
+	}
+	if err := editProfileFile(c.profileDir, ".env", func(content string) (string, bool) {
+		return record(addEnvVars(content, tools.EnvVars(selected)))
+	}); err != nil {
+		return false, err
+	}
+	if err := editProfileFile(c.profileDir, ".gitignore", func(content string) (string, bool) {
+		return record(addGitignoreGroups(content, tools.Gitignore(selected)))
+	}); err != nil {
+		return false, err
+	}
+	return updated, nil
+}
+
+// migrateMetadata writes the metadata file of a profile created before it and removes the
+// .envrc headers it replaces
+func migrateMetadata(c migrationContext) (bool, error) {
+	return updateMetadata(c.profileDir, c.dryRun)
+}
+
+// migrateManagedSections puts managed section markers around the generated content of
+// files written before them, so that update can merge later template changes into them.
+// Files with none of the generated lines are left as they are.
+func migrateManagedSections(c migrationContext) (bool, error) {
+	updated := false
+	bases := make(map[string]string)
+	for _, path := range managedFiles {
+		filePath := filepath.Join(c.profileDir, filepath.FromSlash(path))
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			continue
+		}
+		if _, _, _, ok := splitManaged(string(content)); ok {
+			continue
+		}
+		generated, err := renderManaged(c.profileDir, path)
+		if err != nil {
+			ui.PrintWarning(fmt.Sprintf("Managed section of %s not marked: failed to render it from the profile's template: %v", path, err))
+			continue
+		}
+		adopted, base, ok := adoptManagedSection(string(content), generated)
+		if !ok {
+			continue
+		}
+		updated = true
+		if c.dryRun {
+			continue
+		}
+		if err := os.WriteFile(filePath, []byte(adopted), 0644); err != nil {
+			return false, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		bases[path] = base
+	}
+
+	if len(bases) == 0 {
+		return updated, nil
+	}
+	return updated, editProfileMetadata(c.profileDir, func(m *profileMetadata) {
+		if m.Managed == nil {
+			m.Managed = map[string]string{}
+		}
+		for path, base := range bases {
+			m.Managed[path] = base
+		}
+	})
+}
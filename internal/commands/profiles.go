@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/neverprepared/shell-profile-manager/internal/ui"
+)
+
+// profileSchemaVersion is the version of the profile layout generated by create and update,
+// the version of the last of the migrations
+const profileSchemaVersion = 5
+
+// findProfiles returns the names of all profiles (directories with an .envrc) in profilesDir
+func findProfiles(profilesDir string) ([]string, error) {
+	entries, err := os.ReadDir(profilesDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles directory: %w", err)
+	}
+
+	var profiles []string
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != ".git" {
+			envrcPath := filepath.Join(profilesDir, entry.Name(), ".envrc")
+			if _, err := os.Stat(envrcPath); err == nil {
+				profiles = append(profiles, entry.Name())
+			}
+		}
+	}
+
+	return profiles, nil
+}
+
+// selectProfile shows an interactive profile selection with the given message
+func selectProfile(profilesDir, message string) (string, error) {
+	profiles, err := findProfiles(profilesDir)
+	if err != nil {
+		return "", err
+	}
+
+	if len(profiles) == 0 {
+		return "", fmt.Errorf("no profiles found")
+	}
+
+	return ui.SelectProfile(profiles, message)
+}
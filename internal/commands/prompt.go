@@ -0,0 +1,258 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/neverprepared/shell-profile-manager/internal/ui"
+)
+
+// promptFormats are the built-in formats for the prompt segment. Groups in parentheses
+// are left out when a placeholder inside them is empty.
+var promptFormats = map[string]string{
+	"default": "[{profile}]",
+	"minimal": "{profile}",
+	"git":     "[{profile}( {email})]",
+	"cloud":   "[{profile}( k8s:{kube})( aws:{aws})]",
+	"full":    "[{profile}( {email})( k8s:{kube})( aws:{aws})]",
+}
+
+// templateColors colors the prompt segment by the profile's template
+var templateColors = map[string]string{
+	"work":     ui.ColorBlue,
+	"client":   ui.ColorYellow,
+	"personal": ui.ColorGreen,
+}
+
+type PromptOptions struct {
+	Format string
+	Color  string
+	Shell  string
+}
+
+// PrintPrompt prints the prompt segment for the profile loaded in the current shell, or
+// nothing outside a profile. It runs on every prompt, so it only reads the environment
+// and a few small files: no configuration, no subprocesses.
+func PrintPrompt(opts PromptOptions) error {
+	format, err := promptFormat(opts.Format)
+	if err != nil {
+		return err
+	}
+
+	profileName := os.Getenv("WORKSPACE_PROFILE")
+	if profileName == "" {
+		return nil
+	}
+	home := os.Getenv("WORKSPACE_HOME")
+
+	// Only look up the values the format uses
+	values := map[string]string{
+		"profile": profileName,
+		"home":    home,
+		"aws":     os.Getenv("AWS_PROFILE"),
+	}
+	var template string
+	if home != "" && (strings.Contains(format, "{template}") || opts.Color != "never") {
+		template = profileTemplate(home)
+		values["template"] = template
+	}
+	if strings.Contains(format, "{email}") {
+		values["email"] = promptGitEmail(home)
+	}
+	if strings.Contains(format, "{kube}") {
+		values["kube"] = promptKubeContext(home)
+	}
+
+	segment := renderPromptFormat(format, values)
+	if segment == "" {
+		return nil
+	}
+
+	useColor := opts.Color == "always" || (opts.Color != "never" && os.Getenv("NO_COLOR") == "")
+	if useColor {
+		color, ok := templateColors[template]
+		if !ok {
+			color = ui.ColorCyan
+		}
+		segment = promptEscape(opts.Shell, color) + segment + promptEscape(opts.Shell, ui.ColorReset)
+	}
+
+	fmt.Print(segment)
+	return nil
+}
+
+// PrintStarshipConfig prints a starship custom module that shows the prompt segment
+func PrintStarshipConfig(opts PromptOptions) error {
+	format := opts.Format
+	if format == "" {
+		format = "minimal"
+	}
+	if _, err := promptFormat(format); err != nil {
+		return err
+	}
+
+	fmt.Print(`# shell-profiler prompt segment for starship
+# Add to ~/.config/starship.toml, and add ${custom.shell_profiler} to your format
+[custom.shell_profiler]
+description = "Active shell-profiler profile"
+`)
+	fmt.Printf("command = %s\n", tomlQuote(fmt.Sprintf("shell-profiler prompt --color never --format %s", shellQuote(format))))
+	fmt.Print(`when = '[ -n "$WORKSPACE_PROFILE" ]'
+shell = ["sh"]
+style = "bold blue"
+format = "[$output]($style) "
+`)
+	return nil
+}
+
+// PromptFormatNames returns the names of the built-in prompt formats with their format strings
+func PromptFormatNames() []string {
+	var names []string
+	for name, format := range promptFormats {
+		names = append(names, fmt.Sprintf("%-8s %s", name, format))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// promptFormat resolves a built-in format name, or returns a custom format string
+func promptFormat(format string) (string, error) {
+	if format == "" {
+		format = "default"
+	}
+	if builtin, ok := promptFormats[format]; ok {
+		return builtin, nil
+	}
+	if !strings.Contains(format, "{") {
+		return "", fmt.Errorf("unknown prompt format: %s (use a built-in name or a string with {placeholders})", format)
+	}
+	return format, nil
+}
+
+// renderPromptFormat substitutes {placeholders} in format. A group in parentheses is
+// dropped when any placeholder inside it is empty.
+func renderPromptFormat(format string, values map[string]string) string {
+	var out strings.Builder
+	var group strings.Builder
+	inGroup, groupEmpty := false, false
+
+	for i := 0; i < len(format); i++ {
+		w := &out
+		if inGroup {
+			w = &group
+		}
+
+		switch c := format[i]; {
+		case c == '(' && !inGroup:
+			inGroup, groupEmpty = true, false
+			group.Reset()
+		case c == ')' && inGroup:
+			if !groupEmpty {
+				out.WriteString(group.String())
+			}
+			inGroup = false
+		case c == '{':
+			end := strings.IndexByte(format[i:], '}')
+			if end < 0 {
+				w.WriteString(format[i:])
+				i = len(format)
+				continue
+			}
+			value := values[format[i+1:i+end]]
+			if value == "" {
+				groupEmpty = true
+			}
+			w.WriteString(value)
+			i += end
+		default:
+			w.WriteByte(c)
+		}
+	}
+	if inGroup && !groupEmpty {
+		out.WriteString(group.String())
+	}
+	return out.String()
+}
+
+// promptEscape wraps a color sequence so the shell does not count it towards the prompt width
+func promptEscape(shell, seq string) string {
+	switch shell {
+	case "bash":
+		return "\001" + seq + "\002"
+	case "zsh":
+		return "%{" + seq + "%}"
+	default:
+		return seq
+	}
+}
+
+// promptGitEmail reads user.email from the profile's global git config without running git
+func promptGitEmail(home string) string {
+	path := os.Getenv("GIT_CONFIG_GLOBAL")
+	if path == "" && home != "" {
+		path = filepath.Join(home, ".gitconfig")
+	}
+	if path == "" {
+		return ""
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	inUser := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inUser = strings.EqualFold(strings.Trim(line, "[] \t"), "user")
+			continue
+		}
+		if !inUser {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok && strings.EqualFold(strings.TrimSpace(key), "email") {
+			return strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	return ""
+}
+
+// promptKubeContext reads current-context from the first kubeconfig without running kubectl
+func promptKubeContext(home string) string {
+	path := ""
+	if kubeconfig := os.Getenv("KUBECONFIG"); kubeconfig != "" {
+		path = filepath.SplitList(kubeconfig)[0]
+	} else if home != "" {
+		path = filepath.Join(home, ".kube", "config")
+	}
+	if path == "" {
+		return ""
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "current-context:"); ok {
+			return strings.Trim(strings.TrimSpace(value), `"'`)
+		}
+	}
+	return ""
+}
+
+// tomlQuote quotes a string as a TOML basic string
+func tomlQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
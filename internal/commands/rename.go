@@ -0,0 +1,291 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/neverprepared/shell-profile-manager/internal/ui"
+)
+
+type RenameOptions struct {
+	OldName string
+	NewName string
+	Force   bool
+	DryRun  bool
+}
+
+// fileRewrite is a pending content change to a profile file
+type fileRewrite struct {
+	RelPath string
+	Content []byte
+	Mode    os.FileMode
+}
+
+// RenameProfile renames a profile and rewrites every reference to its old name and path
+func RenameProfile(profilesDir string, opts RenameOptions) error {
+	if opts.OldName == "" || opts.NewName == "" {
+		return fmt.Errorf("both the current and the new profile name are required")
+	}
+	if err := validateProfileName(opts.NewName); err != nil {
+		return err
+	}
+	if opts.OldName == opts.NewName {
+		return fmt.Errorf("new name is the same as the current name")
+	}
+
+	oldDir := filepath.Join(profilesDir, opts.OldName)
+	newDir := filepath.Join(profilesDir, opts.NewName)
+
+	// Check if profile exists
+	if _, err := os.Stat(filepath.Join(oldDir, ".envrc")); os.IsNotExist(err) {
+		return fmt.Errorf("profile '%s' does not exist at: %s", opts.OldName, oldDir)
+	}
+
+	// Refuse to overwrite another profile
+	if _, err := os.Stat(newDir); err == nil {
+		return fmt.Errorf("profile '%s' already exists at: %s", opts.NewName, newDir)
+	}
+
+	// Refuse to rename the active profile, its environment points at the old path
+	if isProfileActive(oldDir, opts.OldName) {
+		return fmt.Errorf("profile '%s' is currently active; leave the profile directory and try again", opts.OldName)
+	}
+
+	oldAbs, err := filepath.Abs(oldDir)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	newAbs, err := filepath.Abs(newDir)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	rewrites, err := planReferenceRewrites(oldAbs, opts.OldName, opts.NewName, oldAbs, newAbs)
+	if err != nil {
+		return err
+	}
+
+	oldRemote, newRemote := renamedRemote(oldDir, opts.OldName, opts.NewName)
+
+	ui.PrintInfo(fmt.Sprintf("Renaming profile: %s → %s", opts.OldName, opts.NewName))
+	fmt.Printf("  From: %s\n", oldDir)
+	fmt.Printf("  To:   %s\n", newDir)
+	if len(rewrites) > 0 {
+		fmt.Println("  Files with references to update:")
+		for _, r := range rewrites {
+			fmt.Printf("    - %s\n", r.RelPath)
+		}
+	}
+	if newRemote != "" {
+		fmt.Printf("  Git remote: %s → %s\n", oldRemote, newRemote)
+	}
+
+	// Dry run
+	if opts.DryRun {
+		fmt.Println()
+		ui.PrintInfo("DRY RUN - Nothing will be renamed")
+		return nil
+	}
+
+	// Confirmation
+	if !opts.Force {
+		confirmed, err := ui.Confirm(fmt.Sprintf("Rename profile '%s' to '%s'?", opts.OldName, opts.NewName), true)
+		if err != nil {
+			return fmt.Errorf("failed to get confirmation: %w", err)
+		}
+		if !confirmed {
+			ui.PrintInfo("Rename cancelled")
+			return nil
+		}
+	}
+
+	// Move the directory, then rewrite references in place
+	if err := os.Rename(oldDir, newDir); err != nil {
+		return fmt.Errorf("failed to rename profile directory: %w", err)
+	}
+
+	if err := applyRewrites(newDir, rewrites); err != nil {
+		return err
+	}
+	if err := editProfileMetadata(newDir, func(m *profileMetadata) {
+		m.Name = opts.NewName
+		m.Updated = time.Now().UTC().Truncate(time.Second)
+	}); err != nil {
+		return err
+	}
+
+	// Keep the profile's snapshots and backups with it
+	if oldBackups, err := profileBackupsDir(opts.OldName); err == nil {
+		if _, err := os.Stat(oldBackups); err == nil {
+			newBackups, err := profileBackupsDir(opts.NewName)
+			if err == nil {
+				if _, err := os.Stat(newBackups); os.IsNotExist(err) {
+					if err := moveTree(oldBackups, newBackups); err != nil {
+						ui.PrintWarning(fmt.Sprintf("Failed to move backups: %v", err))
+					}
+				} else {
+					ui.PrintWarning(fmt.Sprintf("Backups for '%s' already exist, left backups of '%s' in place", opts.NewName, opts.OldName))
+				}
+			}
+		}
+	}
+
+	// Update the git remote when it follows the profile name
+	if newRemote != "" {
+		cmd := exec.Command("git", "remote", "set-url", "origin", newRemote)
+		cmd.Dir = newDir
+		if err := cmd.Run(); err != nil {
+			ui.PrintWarning(fmt.Sprintf("Failed to update git remote: %v", err))
+		} else {
+			ui.PrintSuccess(fmt.Sprintf("Updated remote to: %s", newRemote))
+			fmt.Println("  Rename the repository on your git host to match")
+		}
+	}
+
+	// .envrc changed and moved, so it must be allowed again
+	allowCmd := exec.Command("direnv", "allow", newDir)
+	if err := allowCmd.Run(); err != nil {
+		ui.PrintWarning(fmt.Sprintf("Failed to allow direnv: %v", err))
+		fmt.Printf("  Run 'direnv allow %s' manually\n", newDir)
+	}
+
+	ui.PrintSuccess(fmt.Sprintf("Profile renamed: %s → %s", opts.OldName, opts.NewName))
+	ui.PrintInfo(fmt.Sprintf("Profile location: %s", newDir))
+
+	return nil
+}
+
+// isProfileActive reports whether the current shell has the profile loaded or is inside its directory
+func isProfileActive(profileDir, profileName string) bool {
+	if os.Getenv("WORKSPACE_PROFILE") == profileName {
+		return true
+	}
+
+	profileAbs, err := filepath.Abs(profileDir)
+	if err != nil {
+		return false
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return false
+	}
+	return cwd == profileAbs || strings.HasPrefix(cwd, profileAbs+string(os.PathSeparator))
+}
+
+// planReferenceRewrites returns the files below root whose content changes when the
+// profile name and absolute path are rewritten
+func planReferenceRewrites(root, oldName, newName, oldPath, newPath string) ([]fileRewrite, error) {
+	var rewrites []fileRewrite
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != root && (info.Name() == ".git" || info.Name() == "code") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || info.Size() > 1<<20 {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if bytes.IndexByte(content, 0) != -1 {
+			return nil
+		}
+
+		updated := rewriteProfileReferences(string(content), oldName, newName, oldPath, newPath)
+		if updated == string(content) {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rewrites = append(rewrites, fileRewrite{RelPath: relPath, Content: []byte(updated), Mode: info.Mode().Perm()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan profile files: %w", err)
+	}
+
+	return rewrites, nil
+}
+
+// rewriteProfileReferences replaces the absolute profile path and the profile name in the
+// places where shell-profiler embeds it (WORKSPACE_PROFILE and file headers)
+func rewriteProfileReferences(content, oldName, newName, oldPath, newPath string) string {
+	if oldPath != "" && newPath != "" {
+		content = replacePath(content, oldPath, newPath)
+		if oldDisplay, newDisplay := displayPath(oldPath), displayPath(newPath); oldDisplay != oldPath {
+			content = replacePath(content, oldDisplay, newDisplay)
+		}
+	}
+
+	if oldName == "" || oldName == newName {
+		return content
+	}
+
+	name := regexp.QuoteMeta(oldName)
+	patterns := []*regexp.Regexp{
+		// export WORKSPACE_PROFILE="name" and README's "- WORKSPACE_PROFILE: name", but not
+		// other names that start with it
+		regexp.MustCompile(`(?m)(WORKSPACE_PROFILE(?:=|: )"?)` + name + `("|\s|$)`),
+		// "# ... workspace profile: name" headers
+		regexp.MustCompile(`(?mi)^(#.*workspace profile: )` + name + `[ \t]*$`),
+	}
+	for _, re := range patterns {
+		content = re.ReplaceAllString(content, "${1}"+newName+"${2}")
+	}
+
+	return content
+}
+
+// replacePath replaces oldPath in content where it is a whole path or the start of one
+// below it: followed by a slash, a quote, whitespace or the end of a line. Paths of profiles
+// whose names start with the same characters are left alone.
+func replacePath(content, oldPath, newPath string) string {
+	re := regexp.MustCompile(regexp.QuoteMeta(oldPath) + `(?m:[/"'\s]|$)`)
+	return re.ReplaceAllStringFunc(content, func(match string) string {
+		return newPath + match[len(oldPath):]
+	})
+}
+
+// displayPath abbreviates the home directory in a path to ~
+func displayPath(path string) string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil || homeDir == "" {
+		return path
+	}
+	if path == homeDir || strings.HasPrefix(path, homeDir+string(os.PathSeparator)) {
+		return "~" + path[len(homeDir):]
+	}
+	return path
+}
+
+// renamedRemote returns the current origin URL and the URL with the profile name replaced,
+// or an empty new URL if the remote does not end in the profile name
+func renamedRemote(profileDir, oldName, newName string) (string, string) {
+	remote := gitRemoteURL(profileDir)
+	if remote == "" {
+		return "", ""
+	}
+
+	// Match the last path segment: .../<name>.git, .../<name>, host:<name>.git
+	re := regexp.MustCompile(`([/:])` + regexp.QuoteMeta(oldName) + `(\.git)?/?$`)
+	if !re.MatchString(remote) {
+		return remote, ""
+	}
+	return remote, re.ReplaceAllString(remote, "${1}"+newName+"${2}")
+}
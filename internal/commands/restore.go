@@ -0,0 +1,376 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/neverprepared/shell-profile-manager/internal/ui"
+)
+
+// backupTimeFormat is the timestamp format used in backup directory names
+const backupTimeFormat = "2006-01-02_15-04-05"
+
+type RestoreOptions struct {
+	ProfileName string
+	BackupDate  string
+	File        string
+	Force       bool
+	DryRun      bool
+}
+
+// backupInfo describes a snapshot archive or a legacy backup directory such as
+// update_2024-11-29_14-30-45
+type backupInfo struct {
+	ID      string
+	Kind    string
+	Date    string
+	Time    time.Time
+	Path    string
+	Archive bool
+}
+
+// backupFile is the content and mode of a file or directory stored in a backup
+type backupFile struct {
+	Content []byte
+	Mode    os.FileMode
+	Dir     bool
+}
+
+// RestoreProfile restores files of a profile from one of its backups
+func RestoreProfile(profilesDir string, opts RestoreOptions) error {
+	// If no profile name provided, show interactive selection
+	if opts.ProfileName == "" {
+		selected, err := selectProfile(profilesDir, "Select profile to restore:")
+		if err != nil {
+			return err
+		}
+		opts.ProfileName = selected
+	}
+
+	profileDir := filepath.Join(profilesDir, opts.ProfileName)
+
+	backups, err := listBackups(profilesDir, opts.ProfileName)
+	if err != nil {
+		return err
+	}
+
+	// A deleted profile can be recreated from its snapshots
+	profileExists := true
+	if _, err := os.Stat(profileDir); os.IsNotExist(err) {
+		if len(backups) == 0 {
+			return fmt.Errorf("profile '%s' does not exist at: %s", opts.ProfileName, profileDir)
+		}
+		profileExists = false
+	}
+
+	if len(backups) == 0 {
+		return fmt.Errorf("no backups found for profile '%s' (snapshots are created by update, delete and create --force)", opts.ProfileName)
+	}
+
+	backup, err := chooseBackup(backups, opts)
+	if err != nil {
+		return err
+	}
+
+	files, err := backup.files()
+	if err != nil {
+		return err
+	}
+
+	// Restrict to a single file if requested
+	if opts.File != "" {
+		relPath := filepath.Clean(opts.File)
+		file, ok := files[relPath]
+		if !ok {
+			return fmt.Errorf("file '%s' not found in backup %s", opts.File, backup.ID)
+		}
+		files = map[string]backupFile{relPath: file}
+	}
+
+	ui.PrintInfo(fmt.Sprintf("Restoring profile: %s", opts.ProfileName))
+	fmt.Printf("  Backup: %s (%s)\n", backup.ID, backup.Time.Format("2006-01-02 15:04:05"))
+	if !profileExists {
+		fmt.Printf("  %s⚠ Profile no longer exists and will be recreated%s\n", ui.ColorYellow, ui.ColorReset)
+	}
+	fmt.Println()
+
+	// Show a diff for every file that differs from the backup
+	var changed, missingDirs []string
+	for _, relPath := range sortedKeys(files) {
+		if files[relPath].Dir {
+			if _, err := os.Stat(filepath.Join(profileDir, relPath)); os.IsNotExist(err) {
+				missingDirs = append(missingDirs, relPath)
+			}
+			continue
+		}
+
+		current := ""
+		if data, err := os.ReadFile(filepath.Join(profileDir, relPath)); err == nil {
+			current = string(data)
+		}
+
+		diff := unifiedDiff("current/"+relPath, "backup/"+relPath, current, string(files[relPath].Content))
+		if diff == nil {
+			continue
+		}
+
+		changed = append(changed, relPath)
+		fmt.Printf("%s%s%s\n", ui.ColorCyan, relPath, ui.ColorReset)
+		printDiff(diff)
+		fmt.Println()
+	}
+
+	if len(changed) == 0 && len(missingDirs) == 0 {
+		ui.PrintInfo("Profile already matches the backup, nothing to restore")
+		return nil
+	}
+
+	// Dry run
+	if opts.DryRun {
+		ui.PrintInfo("DRY RUN - Nothing will be restored")
+		fmt.Println()
+		fmt.Println("Would restore:")
+		for _, relPath := range missingDirs {
+			fmt.Printf("  - %s/\n", relPath)
+		}
+		for _, relPath := range changed {
+			fmt.Printf("  - %s\n", relPath)
+		}
+		return nil
+	}
+
+	// Confirmation
+	if !opts.Force {
+		confirmed, err := ui.Confirm(
+			fmt.Sprintf("Restore %d file(s) from backup %s?", len(changed), backup.ID),
+			false,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to get confirmation: %w", err)
+		}
+
+		if !confirmed {
+			ui.PrintInfo("Restore cancelled")
+			return nil
+		}
+	}
+
+	// Snapshot the current state first so the restore itself can be undone
+	if profileExists {
+		if _, err := createSnapshot(profilesDir, opts.ProfileName, "restore"); err != nil {
+			ui.PrintWarning(fmt.Sprintf("Failed to snapshot current files: %v", err))
+		}
+	}
+
+	// Recreate missing directories first so they get their original permissions
+	for _, relPath := range missingDirs {
+		if err := os.MkdirAll(filepath.Join(profileDir, relPath), files[relPath].Mode); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", relPath, err)
+		}
+	}
+
+	for _, relPath := range changed {
+		target := filepath.Join(profileDir, relPath)
+
+		mode := files[relPath].Mode
+		if info, err := os.Stat(target); err == nil {
+			mode = info.Mode().Perm()
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", relPath, err)
+		}
+		if err := os.WriteFile(target, files[relPath].Content, mode); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", relPath, err)
+		}
+	}
+
+	ui.PrintSuccess(fmt.Sprintf("Restored %d file(s) from backup %s", len(changed), backup.ID))
+	fmt.Println()
+	fmt.Println("Files restored:")
+	for _, relPath := range changed {
+		fmt.Printf("  ✓ %s\n", relPath)
+	}
+
+	if containsString(changed, ".envrc") {
+		fmt.Println()
+		ui.PrintInfo("Run 'direnv allow' in the profile directory to reload .envrc")
+	}
+
+	return nil
+}
+
+// listBackups returns the snapshots and legacy backups of a profile, newest first
+func listBackups(profilesDir, profileName string) ([]backupInfo, error) {
+	snapshots, err := listSnapshots(profileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []backupInfo
+	for _, s := range snapshots {
+		backups = append(backups, backupInfo{
+			ID:      s.ID,
+			Kind:    s.Reason,
+			Date:    s.Time.Format(backupTimeFormat),
+			Time:    s.Time,
+			Path:    s.Path,
+			Archive: true,
+		})
+	}
+
+	// Legacy backup directories live in the state directory once migrated by update,
+	// or still inside the profile before that
+	stateBackupDir, err := profileBackupsDir(profileName)
+	if err != nil {
+		return nil, err
+	}
+	for _, backupDir := range []string{stateBackupDir, filepath.Join(profilesDir, profileName, ".backups")} {
+		legacy, err := listLegacyBackups(backupDir)
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, legacy...)
+	}
+
+	// Stable, so that snapshots taken in the same second stay in sequence order
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].Time.After(backups[j].Time)
+	})
+
+	return backups, nil
+}
+
+// listLegacyBackups returns the <kind>_<timestamp> backup directories in backupDir
+func listLegacyBackups(backupDir string) ([]backupInfo, error) {
+	entries, err := os.ReadDir(backupDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var backups []backupInfo
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		kind, date, found := strings.Cut(entry.Name(), "_")
+		if !found {
+			continue
+		}
+		t, err := time.ParseInLocation(backupTimeFormat, date, time.Local)
+		if err != nil {
+			continue
+		}
+
+		backups = append(backups, backupInfo{
+			ID:   entry.Name(),
+			Kind: kind,
+			Date: date,
+			Time: t,
+			Path: filepath.Join(backupDir, entry.Name()),
+		})
+	}
+
+	return backups, nil
+}
+
+// chooseBackup picks a backup by --backup-date or interactively
+func chooseBackup(backups []backupInfo, opts RestoreOptions) (backupInfo, error) {
+	candidates := backups
+	if opts.BackupDate != "" {
+		candidates = nil
+		for _, b := range backups {
+			if b.ID == opts.BackupDate || strings.HasPrefix(b.Date, opts.BackupDate) {
+				candidates = append(candidates, b)
+			}
+		}
+
+		if len(candidates) == 0 {
+			var available []string
+			for _, b := range backups {
+				available = append(available, b.Date)
+			}
+			return backupInfo{}, fmt.Errorf("no backup matches '%s' (available: %s)", opts.BackupDate, strings.Join(available, ", "))
+		}
+		if len(candidates) == 1 {
+			return candidates[0], nil
+		}
+		if opts.Force {
+			return backupInfo{}, fmt.Errorf("'%s' matches %d backups, use a backup ID such as %s", opts.BackupDate, len(candidates), candidates[0].ID)
+		}
+	}
+
+	var options []string
+	byLabel := make(map[string]backupInfo)
+	for _, b := range candidates {
+		label := fmt.Sprintf("%s (%s)", b.Date, b.Kind)
+		options = append(options, label)
+		byLabel[label] = b
+	}
+
+	selected, err := ui.SelectProfile(options, "Select backup to restore:")
+	if err != nil {
+		return backupInfo{}, err
+	}
+
+	return byLabel[selected], nil
+}
+
+// files reads every file in the backup, keyed by path relative to the profile
+func (b backupInfo) files() (map[string]backupFile, error) {
+	if b.Archive {
+		_, files, err := readSnapshot(b.Path)
+		return files, err
+	}
+
+	files := make(map[string]backupFile)
+	err := filepath.Walk(b.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(b.Path, path)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[relPath] = backupFile{Content: content, Mode: info.Mode().Perm()}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup %s: %w", b.ID, err)
+	}
+
+	return files, nil
+}
+
+// sortedKeys returns the keys of a file map in sorted order
+func sortedKeys(files map[string]backupFile) []string {
+	keys := make([]string, 0, len(files))
+	for k := range files {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
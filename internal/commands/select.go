@@ -59,6 +59,11 @@ func SelectProfile(profilesDir string, opts SelectOptions) error {
 
 	profilePath := profileDetails[selected]
 
+	// Under the shell integration, sp changes to the profile directory itself
+	if err := writeHookDirective("cd " + profilePath); err != nil {
+		return err
+	}
+
 	// Check if currently in this profile
 	currentProfile := os.Getenv("WORKSPACE_PROFILE")
 	if currentProfile == selected {
@@ -71,6 +76,7 @@ func SelectProfile(profilesDir string, opts SelectOptions) error {
 	fmt.Println()
 	ui.PrintSuccess(fmt.Sprintf("Selected profile: %s", selected))
 	fmt.Printf("  Location: %s\n", profilePath)
+	warnProfileSchema(profilePath)
 
 	// Check direnv status
 	envrcPath := filepath.Join(profilePath, ".envrc")
@@ -109,6 +115,10 @@ func SelectProfile(profilesDir string, opts SelectOptions) error {
 		}
 	}
 
+	if hookActive() {
+		return nil
+	}
+
 	// Show instructions
 	fmt.Println()
 	ui.PrintInfo("To activate this profile:")
@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"path"
+	"strings"
+
+	"github.com/neverprepared/shell-profile-manager/internal/tools"
+)
+
+// sensitivePatterns returns the patterns of profile files holding credentials, keys or
+// other secrets: those the generated .gitignore of a profile with every tool lists, so
+// that the tool registry is the one place they are declared. Patterns follow .gitignore
+// rules: a trailing slash matches a directory and everything below it, a pattern
+// containing a slash is anchored at the profile root, and a pattern without one matches
+// the file name at any depth.
+func sensitivePatterns() []string {
+	var patterns []string
+	for _, group := range tools.Gitignore(tools.All()) {
+		patterns = append(patterns, group.Patterns...)
+	}
+	return patterns
+}
+
+// inProjectTree reports whether a slash-separated path relative to the profile root is in
+// code/ or .git, which hold the user's projects and the profile's history rather than
+// profile files, so the sensitive patterns do not apply to them
+func inProjectTree(relPath string) bool {
+	for _, dir := range []string{"code", ".git"} {
+		if relPath == dir || strings.HasPrefix(relPath, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// isSensitivePath reports whether a slash-separated path relative to the profile root
+// matches one of the sensitive patterns
+func isSensitivePath(relPath string, isDir bool) bool {
+	relPath = strings.TrimPrefix(relPath, "./")
+	segments := strings.Split(relPath, "/")
+
+	for _, pattern := range sensitivePatterns() {
+		dirOnly := strings.HasSuffix(pattern, "/")
+		pattern = strings.TrimSuffix(pattern, "/")
+		anchored := strings.Contains(pattern, "/")
+
+		// Check the path itself and every parent directory, so files below a
+		// matching directory are sensitive too
+		for i := len(segments); i > 0; i-- {
+			candidate := strings.Join(segments[:i], "/")
+			candidateIsDir := isDir || i < len(segments)
+			if dirOnly && !candidateIsDir {
+				continue
+			}
+
+			name := candidate
+			if !anchored {
+				name = segments[i-1]
+			}
+			if matched, err := path.Match(pattern, name); err == nil && matched {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,172 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/neverprepared/shell-profile-manager/internal/ui"
+)
+
+// shellMarkerVar is set in subshells started by the shell command
+const shellMarkerVar = "SHELL_PROFILER_SHELL"
+
+type ShellOptions struct {
+	ProfileName string
+	Shell       string
+}
+
+// SpawnShell starts an interactive subshell in the profile directory with the profile
+// environment loaded and the prompt marked. Exiting it returns to the calling shell.
+func SpawnShell(profilesDir string, opts ShellOptions) error {
+	// If no profile name provided, show interactive selection
+	if opts.ProfileName == "" {
+		selected, err := selectProfile(profilesDir, "Select profile to open a shell in:")
+		if err != nil {
+			return err
+		}
+		opts.ProfileName = selected
+	}
+
+	profileDir := filepath.Join(profilesDir, opts.ProfileName)
+	if _, err := os.Stat(filepath.Join(profileDir, ".envrc")); os.IsNotExist(err) {
+		return fmt.Errorf("profile '%s' does not exist at: %s", opts.ProfileName, profileDir)
+	}
+	warnProfileSchema(profileDir)
+
+	if current := os.Getenv(shellMarkerVar); current != "" {
+		ui.PrintWarning(fmt.Sprintf("Already in a shell for profile '%s'; exit it to return to the previous one", current))
+	}
+
+	shell := opts.Shell
+	if shell == "" {
+		shell = os.Getenv("SHELL")
+	}
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+
+	env, err := loadProfileEnv(profileDir)
+	if err != nil {
+		return fmt.Errorf("failed to load profile environment: %w", err)
+	}
+	env.set(shellMarkerVar, opts.ProfileName)
+
+	// The rc files of the user's shell run after the environment is passed in and may
+	// reset the prompt or PATH, so the profile's variables are set again after them
+	tmpDir, err := os.MkdirTemp("", "shell-profiler-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	args, err := shellStartup(shell, opts.ProfileName, env, tmpDir)
+	if err != nil {
+		return err
+	}
+
+	ui.PrintInfo(fmt.Sprintf("Entering profile %s (exit the shell to leave)", opts.ProfileName))
+	err = runWithEnv(env, env.dir, append([]string{shell}, args...))
+	ui.PrintInfo(fmt.Sprintf("Left profile %s", opts.ProfileName))
+	return err
+}
+
+// shellStartup prepares the startup files that load the user's own rc files, re-apply the
+// profile environment and mark the prompt. It returns the arguments for the shell.
+func shellStartup(shell, profileName string, env *profileEnv, tmpDir string) ([]string, error) {
+	marker := fmt.Sprintf("(%s) ", profileName)
+
+	switch filepath.Base(shell) {
+	case "bash":
+		rc := "[ -f ~/.bashrc ] && source ~/.bashrc\n" +
+			shellExports("bash", env) +
+			fmt.Sprintf("PS1=%s\"$PS1\"\n", shellQuote(marker))
+		rcPath := filepath.Join(tmpDir, "bashrc")
+		if err := os.WriteFile(rcPath, []byte(rc), 0600); err != nil {
+			return nil, fmt.Errorf("failed to write shell startup file: %w", err)
+		}
+		return []string{"--rcfile", rcPath, "-i"}, nil
+
+	case "zsh":
+		// zsh reads its rc files from ZDOTDIR; point it at startup files that hand
+		// over to the user's own
+		userDir, hadZdotdir := os.LookupEnv("ZDOTDIR")
+		if !hadZdotdir {
+			userDir = env.lookup("HOME")
+		}
+		restore := "unset ZDOTDIR\n"
+		if hadZdotdir {
+			restore = fmt.Sprintf("ZDOTDIR=%s\n", shellQuote(userDir))
+		}
+		zshenv := fmt.Sprintf("[[ -f %[1]s/.zshenv ]] && source %[1]s/.zshenv\n", shellQuote(userDir))
+		zshrc := restore +
+			fmt.Sprintf("[[ -f %[1]s/.zshrc ]] && source %[1]s/.zshrc\n", shellQuote(userDir)) +
+			shellExports("zsh", env) +
+			fmt.Sprintf("PROMPT=%s\"$PROMPT\"\n", shellQuote(marker))
+		if err := os.WriteFile(filepath.Join(tmpDir, ".zshenv"), []byte(zshenv), 0600); err != nil {
+			return nil, fmt.Errorf("failed to write shell startup file: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(tmpDir, ".zshrc"), []byte(zshrc), 0600); err != nil {
+			return nil, fmt.Errorf("failed to write shell startup file: %w", err)
+		}
+		env.set("ZDOTDIR", tmpDir)
+		return []string{"-i"}, nil
+
+	case "fish":
+		init := shellExports("fish", env) +
+			"functions -c fish_prompt __shell_profiler_prompt\n" +
+			fmt.Sprintf("function fish_prompt; printf '%%s' %s; __shell_profiler_prompt; end\n", fishQuote(marker))
+		return []string{"--init-command", init}, nil
+
+	default:
+		// Other shells get the marker through PS1 only
+		env.set("PS1", marker+"$ ")
+		return []string{"-i"}, nil
+	}
+}
+
+// shellExports returns statements that set the profile's variables in the given shell.
+// Directories the profile adds to PATH are prepended to the shell's current PATH.
+func shellExports(shell string, env *profileEnv) string {
+	names := append([]string(nil), env.order...)
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		if name == "ZDOTDIR" {
+			continue
+		}
+		value := env.vars[name]
+		switch {
+		case name == "PATH" && shell == "fish":
+			var dirs []string
+			for _, dir := range env.addedPaths() {
+				dirs = append(dirs, fishQuote(dir))
+			}
+			fmt.Fprintf(&b, "set -gx PATH %s $PATH\n", strings.Join(dirs, " "))
+		case name == "PATH":
+			fmt.Fprintf(&b, "export PATH=%s\"${PATH:+:$PATH}\"\n", shellQuote(strings.Join(env.addedPaths(), string(os.PathListSeparator))))
+		case shell == "fish":
+			fmt.Fprintf(&b, "set -gx %s %s\n", name, fishQuote(value))
+		default:
+			fmt.Fprintf(&b, "export %s=%s\n", name, shellQuote(value))
+		}
+	}
+	return b.String()
+}
+
+// shellQuote quotes a string for POSIX shells using single quotes
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:@%+=,") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fishQuote quotes a string for fish, where backslashes are escapes inside single quotes
+func fishQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
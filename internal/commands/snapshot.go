@@ -0,0 +1,531 @@
+package commands
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neverprepared/shell-profile-manager/internal/config"
+	"github.com/neverprepared/shell-profile-manager/internal/ui"
+)
+
+const (
+	snapshotManifestName = "manifest.json"
+	snapshotExt          = ".tar.gz"
+	snapshotVersion      = 1
+)
+
+type SnapshotOptions struct {
+	ProfileName string
+	Reason      string
+	Keep        int // -1 uses the configured retention
+	KeepDays    int // -1 uses the configured retention
+	DryRun      bool
+}
+
+// snapshotManifest is stored as the first entry of every snapshot archive
+type snapshotManifest struct {
+	Version int            `json:"version"`
+	Profile string         `json:"profile"`
+	Reason  string         `json:"reason"`
+	Created time.Time      `json:"created"`
+	Files   []manifestFile `json:"files"`
+}
+
+// snapshotInfo describes a snapshot archive such as 2024-11-29_14-30-45_update.tar.gz.
+// Snapshots taken in the same second get a sequence number: 2024-11-29_14-30-45-2_update.tar.gz.
+type snapshotInfo struct {
+	ID      string
+	Profile string
+	Reason  string
+	Time    time.Time
+	Seq     int
+	Path    string
+	Size    int64
+}
+
+// retentionPolicy decides which snapshots are pruned; see config.Config
+type retentionPolicy struct {
+	Keep     int
+	KeepDays int
+}
+
+// ListSnapshots lists the snapshots of one profile, or of all profiles if no name is given
+func ListSnapshots(profilesDir string, opts SnapshotOptions) error {
+	names, err := snapshotProfiles(opts.ProfileName)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s=== Profile Snapshots ===%s\n", ui.ColorBlue, ui.ColorReset)
+	fmt.Println()
+
+	total := 0
+	for _, name := range names {
+		snapshots, err := listSnapshots(name)
+		if err != nil {
+			return err
+		}
+		if len(snapshots) == 0 {
+			continue
+		}
+
+		header := name
+		if _, err := os.Stat(filepath.Join(profilesDir, name)); os.IsNotExist(err) {
+			header += " " + ui.ColorYellow + "(deleted)" + ui.ColorReset
+		}
+		fmt.Printf("%s○ %s%s\n", ui.ColorCyan, header, ui.ColorReset)
+
+		for _, s := range snapshots {
+			details := formatFileSize(s.Size)
+			if manifest, err := snapshotManifestOf(s.Path); err == nil {
+				details = describeSnapshotFiles(manifest) + ", archive " + details
+			}
+			fmt.Printf("  %s  %-8s %s\n", s.Time.Format("2006-01-02 15:04:05"), s.Reason, details)
+		}
+		fmt.Println()
+		total += len(snapshots)
+	}
+
+	if total == 0 {
+		fmt.Println("No snapshots found")
+		fmt.Println("Create one with:")
+		fmt.Println("  shell-profiler snapshot create <profile>")
+		return nil
+	}
+
+	fmt.Printf("%sTotal snapshots: %d%s\n", ui.ColorBlue, total, ui.ColorReset)
+	return nil
+}
+
+// CreateSnapshot takes a manual snapshot of a profile
+func CreateSnapshot(profilesDir string, opts SnapshotOptions) error {
+	// If no profile name provided, show interactive selection
+	if opts.ProfileName == "" {
+		selected, err := selectProfile(profilesDir, "Select profile to snapshot:")
+		if err != nil {
+			return err
+		}
+		opts.ProfileName = selected
+	}
+
+	profileDir := filepath.Join(profilesDir, opts.ProfileName)
+	if _, err := os.Stat(profileDir); os.IsNotExist(err) {
+		return fmt.Errorf("profile '%s' does not exist at: %s", opts.ProfileName, profileDir)
+	}
+
+	reason := opts.Reason
+	if reason == "" {
+		reason = "manual"
+	}
+
+	snapshot, err := createSnapshot(profilesDir, opts.ProfileName, reason)
+	if err != nil {
+		return err
+	}
+
+	ui.PrintSuccess(fmt.Sprintf("Snapshot created for profile: %s", opts.ProfileName))
+	fmt.Printf("  Snapshot: %s (%s)\n", snapshot.ID, formatFileSize(snapshot.Size))
+	return nil
+}
+
+// PruneSnapshots removes snapshots outside the retention policy
+func PruneSnapshots(profilesDir string, opts SnapshotOptions) error {
+	policy, err := loadRetentionPolicy()
+	if err != nil {
+		return err
+	}
+	if opts.Keep >= 0 {
+		policy.Keep = opts.Keep
+	}
+	if opts.KeepDays >= 0 {
+		policy.KeepDays = opts.KeepDays
+	}
+
+	names, err := snapshotProfiles(opts.ProfileName)
+	if err != nil {
+		return err
+	}
+
+	if opts.DryRun {
+		ui.PrintInfo("DRY RUN - Nothing will be deleted")
+		fmt.Println()
+	}
+
+	pruned := 0
+	for _, name := range names {
+		removed, err := pruneSnapshots(name, policy, opts.DryRun)
+		if err != nil {
+			return err
+		}
+		for _, s := range removed {
+			verb := "Pruned"
+			if opts.DryRun {
+				verb = "Would prune"
+			}
+			fmt.Printf("  %s %s/%s\n", verb, name, s.ID)
+		}
+		pruned += len(removed)
+	}
+
+	if pruned == 0 {
+		ui.PrintInfo("No snapshots to prune")
+	} else if !opts.DryRun {
+		ui.PrintSuccess(fmt.Sprintf("Pruned %d snapshot(s)", pruned))
+	}
+	return nil
+}
+
+// backupsRoot returns the directory holding the snapshots and backups of all profiles.
+// It lives in the user's state directory so backups are never committed or synced with a profile.
+func backupsRoot() (string, error) {
+	stateDir, err := config.GetStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "backups"), nil
+}
+
+// profileBackupsDir returns the directory holding the snapshots and backups of one profile
+func profileBackupsDir(profileName string) (string, error) {
+	root, err := backupsRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, profileName), nil
+}
+
+// snapshotProfiles returns the given profile name, or every profile that has backups if it is empty
+func snapshotProfiles(profileName string) ([]string, error) {
+	if profileName != "" {
+		return []string{profileName}, nil
+	}
+
+	root, err := backupsRoot()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read backups directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// snapshotSkip excludes version control, legacy backups and project code from snapshots
+func snapshotSkip(relPath string, info os.FileInfo) bool {
+	switch relPath {
+	case ".git", ".backups", "code":
+		return info.IsDir()
+	}
+	return false
+}
+
+// createSnapshot archives a profile into a timestamped, checksummed snapshot and applies the retention policy
+func createSnapshot(profilesDir, profileName, reason string) (snapshotInfo, error) {
+	profileDir := filepath.Join(profilesDir, profileName)
+
+	files, err := collectFiles(profileDir, snapshotSkip)
+	if err != nil {
+		return snapshotInfo{}, fmt.Errorf("failed to scan profile: %w", err)
+	}
+
+	now := time.Now()
+	manifest := snapshotManifest{
+		Version: snapshotVersion,
+		Profile: profileName,
+		Reason:  reason,
+		Created: now.UTC(),
+		Files:   files,
+	}
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return snapshotInfo{}, fmt.Errorf("failed to encode snapshot manifest: %w", err)
+	}
+
+	dir, err := profileBackupsDir(profileName)
+	if err != nil {
+		return snapshotInfo{}, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return snapshotInfo{}, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	// Write to a temporary file first so a failed snapshot never looks complete
+	tmp, err := os.CreateTemp(dir, ".snapshot-*")
+	if err != nil {
+		return snapshotInfo{}, fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // Already linked to its name on success
+
+	if err := writeTarball(tmp, profileDir, files, map[string][]byte{snapshotManifestName: manifestData}); err != nil {
+		tmp.Close()
+		return snapshotInfo{}, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return snapshotInfo{}, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	// Linking fails rather than replace a snapshot taken in the same second, which then
+	// gets the next sequence number
+	var id, path string
+	seq := 1
+	for ; ; seq++ {
+		id = snapshotID(now, seq, reason)
+		path = filepath.Join(dir, id+snapshotExt)
+		err := os.Link(tmp.Name(), path)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return snapshotInfo{}, fmt.Errorf("failed to save snapshot: %w", err)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return snapshotInfo{}, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	snapshot := snapshotInfo{ID: id, Profile: profileName, Reason: reason, Time: now, Seq: seq, Path: path, Size: info.Size()}
+
+	ui.PrintInfo(fmt.Sprintf("Snapshot created: %s", path))
+
+	// Apply retention policy
+	policy, err := loadRetentionPolicy()
+	if err != nil {
+		ui.PrintWarning(fmt.Sprintf("Skipping snapshot pruning: %v", err))
+		return snapshot, nil
+	}
+	removed, err := pruneSnapshots(profileName, policy, false)
+	if err != nil {
+		ui.PrintWarning(fmt.Sprintf("Failed to prune old snapshots: %v", err))
+	} else if len(removed) > 0 {
+		ui.PrintInfo(fmt.Sprintf("Pruned %d old snapshot(s)", len(removed)))
+	}
+
+	return snapshot, nil
+}
+
+// listSnapshots returns the snapshots of a profile, newest first
+func listSnapshots(profileName string) ([]snapshotInfo, error) {
+	dir, err := profileBackupsDir(profileName)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read snapshot directory: %w", err)
+	}
+
+	var snapshots []snapshotInfo
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, snapshotExt) {
+			continue
+		}
+
+		id := strings.TrimSuffix(name, snapshotExt)
+		t, seq, reason, ok := parseSnapshotID(id)
+		if !ok {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		snapshots = append(snapshots, snapshotInfo{
+			ID:      id,
+			Profile: profileName,
+			Reason:  reason,
+			Time:    t,
+			Seq:     seq,
+			Path:    filepath.Join(dir, name),
+			Size:    info.Size(),
+		})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		if !snapshots[i].Time.Equal(snapshots[j].Time) {
+			return snapshots[i].Time.After(snapshots[j].Time)
+		}
+		return snapshots[i].Seq > snapshots[j].Seq
+	})
+
+	return snapshots, nil
+}
+
+// snapshotID names the seq-th snapshot taken in the second of t for reason
+func snapshotID(t time.Time, seq int, reason string) string {
+	if seq > 1 {
+		return fmt.Sprintf("%s-%d_%s", t.Format(backupTimeFormat), seq, reason)
+	}
+	return fmt.Sprintf("%s_%s", t.Format(backupTimeFormat), reason)
+}
+
+// parseSnapshotID splits a snapshot ID into its time, sequence number and reason
+func parseSnapshotID(id string) (time.Time, int, string, bool) {
+	if len(id) < len(backupTimeFormat)+2 {
+		return time.Time{}, 0, "", false
+	}
+	t, err := time.ParseInLocation(backupTimeFormat, id[:len(backupTimeFormat)], time.Local)
+	if err != nil {
+		return time.Time{}, 0, "", false
+	}
+
+	rest := id[len(backupTimeFormat):]
+	seq := 1
+	if strings.HasPrefix(rest, "-") {
+		number, reason, found := strings.Cut(rest[1:], "_")
+		n, err := strconv.Atoi(number)
+		if !found || err != nil || n < 2 {
+			return time.Time{}, 0, "", false
+		}
+		seq, rest = n, "_"+reason
+	}
+	if len(rest) < 2 || rest[0] != '_' {
+		return time.Time{}, 0, "", false
+	}
+	return t, seq, rest[1:], true
+}
+
+// readSnapshot reads every file of a snapshot and verifies it against the manifest checksums
+func readSnapshot(path string) (snapshotManifest, map[string]backupFile, error) {
+	var manifest snapshotManifest
+	files := make(map[string]backupFile)
+
+	err := readTarball(path, func(hdr *tar.Header, r io.Reader) error {
+		if hdr.Name == snapshotManifestName {
+			return json.NewDecoder(r).Decode(&manifest)
+		}
+		if !strings.HasPrefix(hdr.Name, tarFilesPrefix) {
+			return nil
+		}
+
+		relPath := filepath.FromSlash(strings.TrimSuffix(strings.TrimPrefix(hdr.Name, tarFilesPrefix), "/"))
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			files[relPath] = backupFile{Mode: os.FileMode(hdr.Mode).Perm(), Dir: true}
+			return nil
+		case tar.TypeReg:
+		default:
+			return nil
+		}
+
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		files[relPath] = backupFile{Content: content, Mode: os.FileMode(hdr.Mode).Perm()}
+		return nil
+	})
+	if err != nil {
+		return manifest, nil, fmt.Errorf("failed to read snapshot %s: %w", filepath.Base(path), err)
+	}
+
+	// Verify checksums
+	for _, f := range manifest.Files {
+		if f.Link != "" || f.Dir {
+			continue
+		}
+		file, ok := files[filepath.FromSlash(f.Path)]
+		if !ok {
+			return manifest, nil, fmt.Errorf("snapshot %s is missing %s", filepath.Base(path), f.Path)
+		}
+		sum := sha256.Sum256(file.Content)
+		if hex.EncodeToString(sum[:]) != f.SHA256 {
+			return manifest, nil, fmt.Errorf("snapshot %s is corrupt: checksum mismatch for %s", filepath.Base(path), f.Path)
+		}
+	}
+
+	return manifest, files, nil
+}
+
+// loadRetentionPolicy reads the snapshot retention settings from the configuration
+func loadRetentionPolicy() (retentionPolicy, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return retentionPolicy{}, err
+	}
+	return retentionPolicy{Keep: cfg.SnapshotKeep, KeepDays: cfg.SnapshotKeepDays}, nil
+}
+
+// pruneSnapshots deletes the snapshots of a profile that fall outside the policy and returns them
+func pruneSnapshots(profileName string, policy retentionPolicy, dryRun bool) ([]snapshotInfo, error) {
+	if policy.Keep == 0 && policy.KeepDays == 0 {
+		return nil, nil
+	}
+
+	snapshots, err := listSnapshots(profileName)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -policy.KeepDays)
+
+	var removed []snapshotInfo
+	for i, s := range snapshots {
+		keptByCount := policy.Keep > 0 && i < policy.Keep
+		keptByAge := policy.KeepDays > 0 && s.Time.After(cutoff)
+		if keptByCount || keptByAge {
+			continue
+		}
+
+		if !dryRun {
+			if err := os.Remove(s.Path); err != nil {
+				return removed, fmt.Errorf("failed to remove snapshot %s: %w", s.ID, err)
+			}
+		}
+		removed = append(removed, s)
+	}
+
+	return removed, nil
+}
+
+// describeSnapshotFiles returns a short summary line of the files in a snapshot manifest
+func describeSnapshotFiles(manifest snapshotManifest) string {
+	var size int64
+	count := 0
+	for _, f := range manifest.Files {
+		if !f.Dir {
+			size += f.Size
+			count++
+		}
+	}
+	return fmt.Sprintf("%d files, %s", count, formatFileSize(size))
+}
+
+// snapshotManifestOf reads only the manifest of a snapshot
+func snapshotManifestOf(path string) (snapshotManifest, error) {
+	var manifest snapshotManifest
+	err := readTarball(path, func(hdr *tar.Header, r io.Reader) error {
+		if hdr.Name != snapshotManifestName {
+			return nil
+		}
+		if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+			return err
+		}
+		return io.EOF
+	})
+	return manifest, err
+}
@@ -0,0 +1,336 @@
+package commands
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// tarFilesPrefix is the directory inside archives that holds the profile files
+const tarFilesPrefix = "files/"
+
+// manifestFile describes one file stored in an archive
+type manifestFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Mode   string `json:"mode"`
+	SHA256 string `json:"sha256,omitempty"`
+	Link   string `json:"link,omitempty"`
+	Dir    bool   `json:"dir,omitempty"`
+}
+
+// collectFiles walks root and describes every directory, regular file and symlink for which skip returns false.
+// skip is called with the slash-separated path relative to root.
+func collectFiles(root string, skip func(relPath string, info os.FileInfo) bool) ([]manifestFile, error) {
+	var files []manifestFile
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		if skip != nil && skip(relPath, info) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		switch {
+		case info.IsDir():
+			files = append(files, manifestFile{Path: relPath, Mode: formatMode(info.Mode()), Dir: true})
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			files = append(files, manifestFile{Path: relPath, Mode: formatMode(info.Mode()), Link: link})
+		case info.Mode().IsRegular():
+			sum, err := fileChecksum(path)
+			if err != nil {
+				return err
+			}
+			files = append(files, manifestFile{Path: relPath, Size: info.Size(), Mode: formatMode(info.Mode()), SHA256: sum})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// writeTarball writes a gzip-compressed tarball. The extra entries (such as a manifest) are
+// written first, followed by the given files from root stored under tarFilesPrefix.
+func writeTarball(w io.Writer, root string, files []manifestFile, extra map[string][]byte) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+
+	extraNames := make([]string, 0, len(extra))
+	for name := range extra {
+		extraNames = append(extraNames, name)
+	}
+	sort.Strings(extraNames)
+
+	for _, name := range extraNames {
+		hdr := &tar.Header{Name: name, Mode: 0600, Size: int64(len(extra[name])), ModTime: now}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(extra[name]); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range files {
+		if err := writeTarFile(tw, root, f); err != nil {
+			return fmt.Errorf("failed to archive %s: %w", f.Path, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeTarFile(tw *tar.Writer, root string, f manifestFile) error {
+	path := filepath.Join(root, filepath.FromSlash(f.Path))
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+
+	hdr, err := tar.FileInfoHeader(info, f.Link)
+	if err != nil {
+		return err
+	}
+	hdr.Name = tarFilesPrefix + f.Path
+
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if f.Link != "" || f.Dir {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(tw, file)
+	return err
+}
+
+// readTarball calls fn for every entry of a gzip-compressed tarball.
+// fn may stop the iteration early by returning io.EOF.
+func readTarball(path string, fn func(hdr *tar.Header, r io.Reader) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return readTarballFrom(file, fn)
+}
+
+// readTarballFrom is like readTarball but reads from an open stream
+func readTarballFrom(r io.Reader, fn func(hdr *tar.Header, r io.Reader) error) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(hdr, tr); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// extractTarball writes the entries below tarFilesPrefix of a gzip-compressed tarball to dst,
+// restoring directories, symlinks and permissions. Other entries are ignored. Entries that
+// would end up outside dst are refused: paths with "..", symlinks pointing out of dst,
+// entries written through a symlink, and entries that appear twice.
+//
+// If want is not nil, the tarball must hold exactly the files it lists, keyed by their
+// slash-separated path: each entry is checked against its type, symlink target and SHA-256
+// checksum, where the listing has them, and entries it does not list are refused.
+func extractTarball(r io.Reader, dst string, want map[string]manifestFile) error {
+	seen := make(map[string]bool)
+	err := readTarballFrom(r, func(hdr *tar.Header, r io.Reader) error {
+		if !strings.HasPrefix(hdr.Name, tarFilesPrefix) {
+			return nil
+		}
+		relPath := strings.TrimSuffix(strings.TrimPrefix(hdr.Name, tarFilesPrefix), "/")
+		if relPath == "" {
+			return nil
+		}
+
+		// Refuse entries that would escape the destination
+		cleaned := filepath.Clean(filepath.FromSlash(relPath))
+		if filepath.IsAbs(cleaned) || escapesDir(cleaned) {
+			return fmt.Errorf("invalid path in archive: %s", hdr.Name)
+		}
+		if seen[cleaned] {
+			return fmt.Errorf("duplicate entry in archive: %s", hdr.Name)
+		}
+		seen[cleaned] = true
+		if want != nil {
+			f, ok := want[filepath.ToSlash(cleaned)]
+			if !ok {
+				return fmt.Errorf("unlisted entry in archive: %s", hdr.Name)
+			}
+			if !matchesListing(hdr, f) {
+				return fmt.Errorf("entry does not match the manifest: %s", hdr.Name)
+			}
+		}
+		if err := checkNoSymlinks(dst, cleaned); err != nil {
+			return fmt.Errorf("invalid path in archive: %s: %w", hdr.Name, err)
+		}
+		target := filepath.Join(dst, cleaned)
+		mode := os.FileMode(hdr.Mode).Perm()
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, mode); err != nil {
+				return err
+			}
+			return os.Chmod(target, mode)
+		case tar.TypeSymlink:
+			link := filepath.FromSlash(hdr.Linkname)
+			if filepath.IsAbs(link) || escapesDir(filepath.Join(filepath.Dir(cleaned), link)) {
+				return fmt.Errorf("invalid symlink in archive: %s -> %s", hdr.Name, hdr.Linkname)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			return os.Symlink(hdr.Linkname, target)
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+			if err != nil {
+				return err
+			}
+			hash := sha256.New()
+			if _, err := io.Copy(io.MultiWriter(file, hash), r); err != nil {
+				file.Close()
+				return err
+			}
+			if err := file.Close(); err != nil {
+				return err
+			}
+			if sum := want[filepath.ToSlash(cleaned)].SHA256; sum != "" && hex.EncodeToString(hash.Sum(nil)) != sum {
+				return fmt.Errorf("checksum mismatch for %s", filepath.ToSlash(cleaned))
+			}
+			return os.Chmod(target, mode)
+		default:
+			return nil
+		}
+	})
+	if err != nil || want == nil {
+		return err
+	}
+
+	for path, f := range want {
+		if !seen[filepath.FromSlash(path)] && (f.SHA256 != "" || f.Link != "" || f.Dir) {
+			return fmt.Errorf("archive is missing %s", path)
+		}
+	}
+	return nil
+}
+
+// matchesListing reports whether a tarball entry is the file listed for it. Listings
+// without a checksum, symlink target or directory flag accept any file or symlink.
+func matchesListing(hdr *tar.Header, f manifestFile) bool {
+	switch {
+	case f.Dir:
+		return hdr.Typeflag == tar.TypeDir
+	case f.Link != "":
+		return hdr.Typeflag == tar.TypeSymlink && hdr.Linkname == f.Link
+	case f.SHA256 != "":
+		return hdr.Typeflag == tar.TypeReg
+	default:
+		return hdr.Typeflag == tar.TypeReg || hdr.Typeflag == tar.TypeSymlink
+	}
+}
+
+// escapesDir reports whether a cleaned relative path leads out of the directory it is relative to
+func escapesDir(relPath string) bool {
+	return relPath == ".." || strings.HasPrefix(relPath, ".."+string(os.PathSeparator))
+}
+
+// checkNoSymlinks returns an error if relPath, or any directory on the way to it from dst,
+// is an existing symlink, which writing to relPath would follow
+func checkNoSymlinks(dst, relPath string) error {
+	path := dst
+	for _, part := range strings.Split(relPath, string(os.PathSeparator)) {
+		path = filepath.Join(path, part)
+		info, err := os.Lstat(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%s is a symlink", filepath.ToSlash(strings.TrimPrefix(path, dst+string(os.PathSeparator))))
+		}
+	}
+	return nil
+}
+
+// fileChecksum returns the hex-encoded SHA-256 of a file
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// formatMode formats the permission bits of a file mode as an octal string
+func formatMode(mode os.FileMode) string {
+	return fmt.Sprintf("%04o", mode.Perm())
+}
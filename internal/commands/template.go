@@ -0,0 +1,407 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/neverprepared/shell-profile-manager/internal/templates"
+	"github.com/neverprepared/shell-profile-manager/internal/tools"
+	"github.com/neverprepared/shell-profile-manager/internal/ui"
+)
+
+type TemplateOptions struct {
+	Name        string
+	File        string
+	ProfileName string
+	Description string
+	Force       bool
+}
+
+// ListTemplates prints the built-in and user-defined templates
+func ListTemplates() error {
+	dir := templatesDir()
+	available, err := templates.Discover(dir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s=== Profile Templates ===%s\n", ui.ColorBlue, ui.ColorReset)
+	fmt.Println()
+
+	for _, t := range available {
+		fmt.Printf("%s○ %s%s\n", ui.ColorCyan, t.Name, ui.ColorReset)
+		if t.Description != "" {
+			fmt.Printf("  %s\n", t.Description)
+		}
+		fmt.Printf("  Source: %s\n", templateSourceLabel(t))
+		fmt.Println()
+	}
+
+	fmt.Printf("%sTotal templates: %d%s\n", ui.ColorBlue, len(available), ui.ColorReset)
+	if dir != "" {
+		fmt.Printf("User templates are read from: %s\n", displayPath(dir))
+	}
+	return nil
+}
+
+// ShowTemplate prints a template's details and files, or the unrendered content of one
+// of its files
+func ShowTemplate(opts TemplateOptions) error {
+	if opts.Name == "" {
+		return fmt.Errorf("template name is required")
+	}
+
+	t, err := templates.Find(templatesDir(), opts.Name)
+	if err != nil {
+		return err
+	}
+
+	if opts.File != "" {
+		content, err := t.ReadFile(opts.File)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(content))
+		return nil
+	}
+
+	chain, err := t.Chain()
+	if err != nil {
+		return err
+	}
+	paths, layers, err := t.Layout()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s=== Template: %s ===%s\n", ui.ColorBlue, t.Name, ui.ColorReset)
+	if t.Description != "" {
+		fmt.Printf("  Description: %s\n", t.Description)
+	}
+	fmt.Printf("  Source:      %s\n", templateSourceLabel(t))
+	if len(chain) > 1 {
+		fmt.Printf("  Extends:     %s\n", strings.Join(ancestors(chain), " → "))
+	}
+	fmt.Println()
+
+	fmt.Println("Files:")
+	for _, path := range paths {
+		fmt.Printf("  %-30s %s\n", path, describeLayers(layers[path]))
+	}
+	fmt.Println()
+
+	vars, err := t.Variables()
+	if err != nil {
+		return err
+	}
+	if len(vars) > 0 {
+		fmt.Println("Variables:")
+		for _, v := range vars {
+			var details []string
+			details = append(details, v.Type)
+			if v.Required {
+				details = append(details, "required")
+			}
+			if v.Default != "" {
+				details = append(details, "default: "+v.Default)
+			}
+			if v.Pattern != "" {
+				details = append(details, "pattern: "+v.Pattern)
+			}
+			fmt.Printf("  %-30s %s\n", v.Name, strings.Join(details, ", "))
+			if v.Help != "" {
+				fmt.Printf("  %-30s %s\n", "", v.Help)
+			}
+		}
+		fmt.Println()
+	}
+	fmt.Println("Show a file with:")
+	fmt.Printf("  shell-profiler template show %s <file>\n", t.Name)
+	return nil
+}
+
+// templateSourceLabel describes where a template comes from
+func templateSourceLabel(t *templates.Template) string {
+	if t.SourceName != "" {
+		return fmt.Sprintf("%s@%s (%s)", t.SourceName, shortRevision(t.Revision), t.Source)
+	}
+	return t.Source
+}
+
+// describeLayers lists the templates a file is built from, e.g. "basic + work (append)"
+func describeLayers(layers []templates.Layer) string {
+	parts := make([]string, len(layers))
+	for i, layer := range layers {
+		parts[i] = layer.String()
+	}
+	return strings.Join(parts, " + ")
+}
+
+// ancestors returns the parents in a template chain, nearest first
+func ancestors(chain []string) []string {
+	var names []string
+	for i := len(chain) - 2; i >= 0; i-- {
+		names = append(names, chain[i])
+	}
+	return names
+}
+
+// templateVariableDetectors find per-team settings in a saved profile that become template
+// variables: the first value of key in file is the variable's default
+var templateVariableDetectors = []struct {
+	file, key, name, help string
+	keepDefault           bool
+}{
+	{".aws/config", "sso_start_url", "aws_sso_url", "AWS SSO start URL", true},
+	{".aws/config", "sso_region", "aws_sso_region", "AWS SSO region", true},
+	{".aws/config", "region", "aws_region", "Default AWS region", true},
+	{".gitconfig", "signingkey", "git_signing_key", "Git signing key", false},
+}
+
+// SaveTemplate turns a profile into a user template: its non-secret files are copied with
+// the profile name, path and git identity replaced by template placeholders
+func SaveTemplate(profilesDir string, opts TemplateOptions) error {
+	if opts.ProfileName == "" || opts.Name == "" {
+		return fmt.Errorf("profile name and template name are required")
+	}
+	if err := validateProfileName(opts.Name); err != nil {
+		return fmt.Errorf("invalid template name: %w", err)
+	}
+
+	profileDir := filepath.Join(profilesDir, opts.ProfileName)
+	if _, err := os.Stat(filepath.Join(profileDir, ".envrc")); os.IsNotExist(err) {
+		return fmt.Errorf("profile '%s' does not exist at: %s", opts.ProfileName, profileDir)
+	}
+	absDir, err := filepath.Abs(profileDir)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	dir := templatesDir()
+	if dir == "" {
+		return fmt.Errorf("no templates directory configured (set one with: shell-profiler init --templates-dir <path>)")
+	}
+	templateDir := filepath.Join(dir, opts.Name)
+	if _, err := os.Stat(templateDir); err == nil && !opts.Force {
+		return fmt.Errorf("template '%s' already exists at: %s (use --force to overwrite)", opts.Name, templateDir)
+	}
+
+	// Collect the files to save, leaving out version control, project code, the profile's
+	// own metadata and everything the generated .gitignore marks sensitive, for every tool:
+	// files of a disabled tool may still be there
+	skipped := 0
+	files, err := collectFiles(profileDir, func(relPath string, info os.FileInfo) bool {
+		if snapshotSkip(relPath, info) || relPath == metadataFile {
+			return true
+		}
+		if isSensitivePath(relPath, info.IsDir()) {
+			skipped++
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read profile: %w", err)
+	}
+
+	m := templates.Manifest{
+		Description: opts.Description,
+		Parent:      profileTemplate(profileDir),
+	}
+	if m.Description == "" {
+		m.Description = fmt.Sprintf("Saved from profile %s", opts.ProfileName)
+	}
+	if m.Parent == templates.BaseTemplate {
+		m.Parent = ""
+	} else if _, err := templates.Find(dir, m.Parent); err != nil {
+		ui.PrintWarning(fmt.Sprintf("Template %s of the profile is not installed; the new template extends %s", m.Parent, templates.BaseTemplate))
+		m.Parent = ""
+	}
+
+	gitconfig := filepath.Join(profileDir, ".gitconfig")
+	placeholders := templatePlaceholders{
+		name:     opts.ProfileName,
+		template: profileTemplate(profileDir),
+		path:     absDir,
+		gitName:  getGitConfig(gitconfig, "user.name"),
+		gitEmail: getGitConfig(gitconfig, "user.email"),
+	}
+
+	type savedFile struct {
+		path    string
+		content string
+		mode    os.FileMode
+	}
+	var saved []savedFile
+	for _, f := range files {
+		if f.Dir || f.Link != "" {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(profileDir, filepath.FromSlash(f.Path)))
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", f.Path, err)
+		}
+		if bytes.IndexByte(content, 0) >= 0 {
+			ui.PrintWarning(fmt.Sprintf("Skipping binary file: %s", f.Path))
+			continue
+		}
+
+		text := placeholders.apply(string(content))
+
+		// A managed section as the tools generate it stays generated in the template
+		if f.Path == ".gitignore" {
+			if _, section, _, ok := splitManaged(string(content)); ok && section == tools.RenderGitignore(profileTools(profileDir)) {
+				if head, _, tail, ok := splitManaged(text); ok {
+					text = head + "{{.ToolGitignore}}" + tail
+				}
+			}
+		}
+		for _, d := range templateVariableDetectors {
+			if d.file != f.Path {
+				continue
+			}
+			var spec templates.VariableSpec
+			if text, spec = detectTemplateVariable(text, d.key, d.name); spec.Name == "" {
+				continue
+			}
+			spec.Help = d.help
+			if !d.keepDefault {
+				spec.Default = nil
+			}
+			m.Variables = append(m.Variables, spec)
+		}
+
+		mode := os.FileMode(0644)
+		if info, err := os.Stat(filepath.Join(profileDir, filepath.FromSlash(f.Path))); err == nil && info.Mode()&0111 != 0 {
+			mode = 0755
+		}
+		saved = append(saved, savedFile{path: f.Path, content: text, mode: mode})
+	}
+
+	ui.PrintInfo(fmt.Sprintf("Saving profile %s as template: %s", opts.ProfileName, opts.Name))
+
+	if opts.Force {
+		if err := os.RemoveAll(templateDir); err != nil {
+			return fmt.Errorf("failed to remove existing template: %w", err)
+		}
+	}
+	for _, f := range saved {
+		path := filepath.Join(templateDir, filepath.FromSlash(f.path)+templates.Suffix)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", f.path, err)
+		}
+		if err := os.WriteFile(path, []byte(f.content), f.mode); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.path, err)
+		}
+		fmt.Printf("  %s\n", f.path)
+	}
+
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(templateDir, templates.ManifestFile), append(manifest, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	// Check that the template renders
+	t, err := templates.Find(dir, opts.Name)
+	if err != nil {
+		return fmt.Errorf("saved template does not load: %w", err)
+	}
+	data, err := templateData(profileDir, CreateOptions{ProfileName: opts.ProfileName, Template: opts.Name}, t)
+	if err == nil {
+		_, err = t.Render(data)
+	}
+	if err != nil {
+		return fmt.Errorf("saved template does not render: %w", err)
+	}
+
+	fmt.Println()
+	if skipped > 0 {
+		fmt.Printf("  %d sensitive file(s) or directories left out\n", skipped)
+	}
+	for _, v := range m.Variables {
+		fmt.Printf("  Variable %s detected", v.Name)
+		if v.Default != nil {
+			fmt.Printf(" (default: %v)", v.Default)
+		}
+		fmt.Println()
+	}
+	ui.PrintSuccess(fmt.Sprintf("Template saved: %s", displayPath(templateDir)))
+	fmt.Println()
+	fmt.Println("Create a profile from it with:")
+	fmt.Printf("  shell-profiler create <name> --template %s\n", opts.Name)
+	return nil
+}
+
+// templatePlaceholders replaces the values of one profile with template placeholders
+type templatePlaceholders struct {
+	name, template, path string
+	gitName, gitEmail    string
+}
+
+var (
+	templateHeaderRe = regexp.MustCompile(`(?m)^((?:# )?Template: )(\S+)[ \t]*$`)
+	createdHeaderRe  = regexp.MustCompile(`(?m)^((?:# )?Created: )\d{4}-\d\d-\d\d \d\d:\d\d:\d\d UTC[ \t]*$`)
+)
+
+// apply returns content as a template: existing template delimiters are escaped, and the
+// profile's name, path, template, creation time and git identity become placeholders
+func (p templatePlaceholders) apply(content string) string {
+	content = strings.ReplaceAll(content, "{{", `{{"{{"}}`)
+
+	content = replacePath(content, p.path, "{{.Path}}")
+	if display := displayPath(p.path); display != p.path {
+		content = replacePath(content, display, "{{.DisplayPath}}")
+	}
+	content = rewriteProfileReferences(content, p.name, "{{.Name}}", "", "")
+
+	content = templateHeaderRe.ReplaceAllStringFunc(content, func(line string) string {
+		m := templateHeaderRe.FindStringSubmatch(line)
+		if m[2] != p.template {
+			return line
+		}
+		return m[1] + "{{.Template}}"
+	})
+	content = createdHeaderRe.ReplaceAllString(content, "${1}{{.Created}}")
+	for _, re := range []*regexp.Regexp{legacySourceRe, legacyToolsRe} {
+		for _, header := range re.FindAllString(content, -1) {
+			content = strings.ReplaceAll(content, header+"\n", "")
+		}
+	}
+
+	if p.gitEmail != "" {
+		content = strings.ReplaceAll(content, p.gitEmail, `{{or .GitEmail "your.email@example.com"}}`)
+	}
+	if p.gitName != "" {
+		nameRe := regexp.MustCompile(`(?m)^(\s*name\s*=\s*)` + regexp.QuoteMeta(p.gitName) + `[ \t]*$`)
+		content = nameRe.ReplaceAllString(content, `${1}{{or .GitName "Your Name"}}`)
+	}
+	return content
+}
+
+// detectTemplateVariable replaces the first value of an ini-style "key = value" setting,
+// and every other line setting key to the same value, with the variable name. It returns
+// the variable with the value as its default, or an empty spec if key is not set.
+func detectTemplateVariable(content, key, name string) (string, templates.VariableSpec) {
+	re := regexp.MustCompile(`(?m)^(\s*` + regexp.QuoteMeta(key) + `\s*=\s*)(\S.*?)[ \t]*$`)
+	m := re.FindStringSubmatch(content)
+	if m == nil {
+		return content, templates.VariableSpec{}
+	}
+	value := m[2]
+	content = re.ReplaceAllStringFunc(content, func(line string) string {
+		sub := re.FindStringSubmatch(line)
+		if sub[2] != value {
+			return line
+		}
+		return sub[1] + "{{.Vars." + name + "}}"
+	})
+	return content, templates.VariableSpec{Name: name, Default: value}
+}
@@ -0,0 +1,280 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/neverprepared/shell-profile-manager/internal/templates"
+	"github.com/neverprepared/shell-profile-manager/internal/ui"
+)
+
+type TemplateSourceOptions struct {
+	Name     string
+	URL      string
+	Revision string
+}
+
+// AddTemplateSource clones a git repository of templates into the templates directory
+// and pins it to a commit: the given revision, or the repository's default branch
+func AddTemplateSource(opts TemplateSourceOptions) error {
+	if opts.URL == "" {
+		return fmt.Errorf("git URL or path is required")
+	}
+	dir := templatesDir()
+	if dir == "" {
+		return fmt.Errorf("no templates directory configured (set one with: shell-profiler init --templates-dir <path>)")
+	}
+
+	// Local repositories are recorded by absolute path, so the clone does not depend on
+	// the current directory
+	url := opts.URL
+	if info, err := os.Stat(url); err == nil && info.IsDir() {
+		if abs, err := filepath.Abs(url); err == nil {
+			url = abs
+		}
+	}
+
+	name := opts.Name
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(strings.TrimRight(url, "/")), ".git")
+	}
+	if err := validateProfileName(name); err != nil {
+		return fmt.Errorf("invalid source name %q (set one with --name): %w", name, err)
+	}
+
+	sources, err := templates.LoadSources(dir)
+	if err != nil {
+		return err
+	}
+	for _, source := range sources {
+		if source.Name == name {
+			return fmt.Errorf("template source '%s' already exists (%s)", name, source.URL)
+		}
+	}
+
+	checkout := templates.SourceDir(dir, name)
+	if _, err := os.Stat(checkout); err == nil {
+		return fmt.Errorf("template source directory already exists: %s", checkout)
+	}
+	if err := os.MkdirAll(filepath.Dir(checkout), 0755); err != nil {
+		return fmt.Errorf("failed to create template sources directory: %w", err)
+	}
+
+	ui.PrintInfo(fmt.Sprintf("Adding template source %s from %s", name, url))
+	if err := runGit("", "clone", "--quiet", "--", url, checkout); err != nil {
+		return err
+	}
+	revision, err := pinTemplateSource(checkout, opts.Revision)
+	if err != nil {
+		os.RemoveAll(checkout) //nolint:errcheck // Best effort cleanup of a failed clone
+		return err
+	}
+
+	source := templates.Source{
+		Name:     name,
+		URL:      url,
+		Revision: revision,
+		Updated:  time.Now().UTC().Format(time.RFC3339),
+	}
+	if err := templates.SaveSources(dir, append(sources, source)); err != nil {
+		return err
+	}
+
+	ui.PrintSuccess(fmt.Sprintf("Added template source %s at %s", name, shortRevision(revision)))
+	return printSourceTemplates(dir, name)
+}
+
+// UpdateTemplateSources moves template sources to the latest commit of their default
+// branch, or to the given revision, and reports profiles built from older revisions
+func UpdateTemplateSources(profilesDir string, opts TemplateSourceOptions) error {
+	dir := templatesDir()
+	sources, err := templates.LoadSources(dir)
+	if err != nil {
+		return err
+	}
+	if len(sources) == 0 {
+		ui.PrintInfo("No template sources (add one with: shell-profiler template add-source <git-url>)")
+		return nil
+	}
+	if opts.Revision != "" && opts.Name == "" {
+		return fmt.Errorf("--rev needs a source name: shell-profiler template update <source> --rev <revision>")
+	}
+
+	found := false
+	for i, source := range sources {
+		if opts.Name != "" && source.Name != opts.Name {
+			continue
+		}
+		found = true
+
+		checkout := templates.SourceDir(dir, source.Name)
+		ui.PrintInfo(fmt.Sprintf("Updating template source %s...", source.Name))
+		if err := runGit(checkout, "fetch", "--quiet", "--tags", "origin"); err != nil {
+			return err
+		}
+		target := opts.Revision
+		if target == "" {
+			target = "origin/HEAD"
+		}
+		revision, err := pinTemplateSource(checkout, target)
+		if err != nil {
+			return err
+		}
+
+		if revision == source.Revision {
+			fmt.Printf("  %s is up to date at %s\n", source.Name, shortRevision(revision))
+			continue
+		}
+		fmt.Printf("  %s: %s → %s\n", source.Name, shortRevision(source.Revision), shortRevision(revision))
+		sources[i].Revision = revision
+		sources[i].Updated = time.Now().UTC().Format(time.RFC3339)
+
+		// Record each checkout as soon as it has moved, so that a later source failing
+		// never leaves the recorded revision behind the files
+		if err := templates.SaveSources(dir, sources); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return fmt.Errorf("unknown template source: %s", opts.Name)
+	}
+
+	// Profiles keep the files they were created with; point out the ones that are behind
+	profiles, err := findProfiles(profilesDir)
+	if err != nil {
+		return nil
+	}
+	var notes []string
+	for _, profile := range profiles {
+		if note := templateRevisionNote(filepath.Join(profilesDir, profile), profile, sources); note != "" {
+			notes = append(notes, note)
+		}
+	}
+	if len(notes) > 0 {
+		fmt.Println()
+		ui.PrintInfo("Profiles built from older template revisions:")
+		for _, note := range notes {
+			fmt.Printf("  %s\n", note)
+		}
+	}
+	return nil
+}
+
+// ListTemplateSources prints the registered template sources and their pinned revisions
+func ListTemplateSources() error {
+	dir := templatesDir()
+	sources, err := templates.LoadSources(dir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s=== Template Sources ===%s\n", ui.ColorBlue, ui.ColorReset)
+	fmt.Println()
+	if len(sources) == 0 {
+		fmt.Println("No template sources")
+		fmt.Println()
+		fmt.Println("Add one with:")
+		fmt.Println("  shell-profiler template add-source <git-url|path>")
+		return nil
+	}
+
+	available, err := templates.Discover(dir)
+	if err != nil {
+		return err
+	}
+	for _, source := range sources {
+		var names []string
+		for _, t := range available {
+			if t.SourceName == source.Name {
+				names = append(names, t.Name)
+			}
+		}
+
+		fmt.Printf("%s○ %s%s\n", ui.ColorCyan, source.Name, ui.ColorReset)
+		fmt.Printf("  URL:       %s\n", source.URL)
+		fmt.Printf("  Revision:  %s\n", shortRevision(source.Revision))
+		if source.Updated != "" {
+			fmt.Printf("  Updated:   %s\n", source.Updated)
+		}
+		if len(names) > 0 {
+			fmt.Printf("  Templates: %s\n", strings.Join(names, ", "))
+		}
+		fmt.Println()
+	}
+	fmt.Printf("%sTotal sources: %d%s\n", ui.ColorBlue, len(sources), ui.ColorReset)
+	return nil
+}
+
+// pinTemplateSource checks out revision in a source checkout, detached, and returns the
+// full commit hash. Branch names refer to the remote's branches, so that a pin to "main"
+// follows the fetched branch rather than the local one left over from the clone.
+func pinTemplateSource(checkout, revision string) (string, error) {
+	if revision == "" {
+		revision = "HEAD"
+	}
+	commit, err := gitOutput(checkout, "rev-parse", "--verify", "--quiet", "origin/"+revision+"^{commit}")
+	if err != nil {
+		commit, err = gitOutput(checkout, "rev-parse", "--verify", "--quiet", revision+"^{commit}")
+	}
+	if err != nil {
+		return "", fmt.Errorf("unknown revision in template source: %s", revision)
+	}
+	if err := runGit(checkout, "checkout", "--quiet", "--detach", commit); err != nil {
+		return "", fmt.Errorf("failed to check out %s: %w", revision, err)
+	}
+	return commit, nil
+}
+
+// printSourceTemplates lists the templates a source provides
+func printSourceTemplates(dir, name string) error {
+	available, err := templates.Discover(dir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, t := range available {
+		if t.SourceName == name {
+			names = append(names, t.Name)
+		}
+	}
+	if len(names) == 0 {
+		ui.PrintWarning("The source has no templates (directories at its top level or in templates/)")
+		return nil
+	}
+	fmt.Printf("  Templates: %s\n", strings.Join(names, ", "))
+	return nil
+}
+
+// shortRevision abbreviates a commit hash for display
+func shortRevision(revision string) string {
+	if len(revision) > 7 {
+		return revision[:7]
+	}
+	return revision
+}
+
+// profileTemplateSource returns the template source and revision a profile was created
+// from, if it was created from a template source
+func profileTemplateSource(profileDir string) (source, revision string) {
+	m := profileMeta(profileDir)
+	return m.TemplateSource, m.TemplateRevision
+}
+
+// templateRevisionNote describes how a profile's template revision differs from the
+// revision its template source is pinned to, or returns "" if it does not
+func templateRevisionNote(profileDir, profileName string, sources []templates.Source) string {
+	sourceName, revision := profileTemplateSource(profileDir)
+	if sourceName == "" {
+		return ""
+	}
+	for _, source := range sources {
+		if source.Name == sourceName && source.Revision != revision {
+			return fmt.Sprintf("profile %s was built from template %s rev %s, latest is %s",
+				profileName, profileTemplate(profileDir), shortRevision(revision), shortRevision(source.Revision))
+		}
+	}
+	return ""
+}
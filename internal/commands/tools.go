@@ -0,0 +1,574 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/neverprepared/shell-profile-manager/internal/tools"
+	"github.com/neverprepared/shell-profile-manager/internal/ui"
+)
+
+type ToolsOptions struct {
+	ProfileName string
+	Tool        string
+	Force       bool
+	DryRun      bool
+}
+
+// profileTools returns the tools enabled in a profile. Tools that are no longer
+// registered are left out.
+func profileTools(profileDir string) []tools.Tool {
+	var enabled []tools.Tool
+	for _, name := range profileMeta(profileDir).Tools {
+		if t, err := tools.Find(name); err == nil {
+			enabled = append(enabled, t)
+		}
+	}
+	return enabled
+}
+
+// profileToolNames returns the names of the tools enabled in a profile
+func profileToolNames(profileDir string) []string {
+	return tools.ToolNames(profileTools(profileDir))
+}
+
+// enabledTools returns the named tools, or every tool if names is nil
+func enabledTools(names []string) ([]tools.Tool, error) {
+	if names == nil {
+		return tools.All(), nil
+	}
+	return tools.Select(names)
+}
+
+// splitToolList splits a comma-separated list of tool names
+func splitToolList(list string) []string {
+	names := []string{}
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ParseToolList parses the value of --tools: a comma-separated list of tool names, or
+// "none"
+func ParseToolList(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "none" {
+		return []string{}, nil
+	}
+	names := splitToolList(list)
+	if _, err := tools.Select(names); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// writeProfileTools records the enabled tools in a profile's metadata
+func writeProfileTools(profileDir string, enabled []tools.Tool) error {
+	return editProfileMetadata(profileDir, func(m *profileMetadata) {
+		m.Tools = tools.ToolNames(enabled)
+		m.Updated = time.Now().UTC().Truncate(time.Second)
+	})
+}
+
+// hasTool reports whether the named tool is among the given tools
+func hasTool(enabled []tools.Tool, name string) bool {
+	for _, t := range enabled {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// createProfileDirs creates the directories of the given tools in a profile, with a
+// private .ssh and an empty known_hosts if SSH is among them
+func createProfileDirs(profileDir string, enabled []tools.Tool) error {
+	for _, dir := range tools.Dirs(enabled) {
+		fullPath := filepath.Join(profileDir, dir)
+		if err := os.MkdirAll(fullPath, 0755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", fullPath, err)
+		}
+	}
+
+	if !hasTool(enabled, "ssh") {
+		return nil
+	}
+	if err := os.Chmod(filepath.Join(profileDir, ".ssh"), 0700); err != nil {
+		return fmt.Errorf("failed to set SSH directory permissions: %w", err)
+	}
+	knownHostsPath := filepath.Join(profileDir, ".ssh/known_hosts")
+	if _, err := os.Stat(knownHostsPath); os.IsNotExist(err) {
+		if err := os.WriteFile(knownHostsPath, []byte{}, 0600); err != nil {
+			return fmt.Errorf("failed to create known_hosts: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListTools lists the registered tools and which of them a profile has enabled
+func ListTools(profilesDir string, opts ToolsOptions) error {
+	if opts.ProfileName == "" {
+		fmt.Printf("%s=== Tools ===%s\n", ui.ColorBlue, ui.ColorReset)
+		fmt.Println()
+		for _, t := range tools.All() {
+			fmt.Printf("  %-12s %s\n", t.Name, t.Description)
+		}
+		return nil
+	}
+
+	profileDir, err := toolsProfileDir(profilesDir, &opts, "")
+	if err != nil {
+		return err
+	}
+	enabled := profileTools(profileDir)
+
+	fmt.Printf("%s=== Tools in profile: %s ===%s\n", ui.ColorBlue, opts.ProfileName, ui.ColorReset)
+	fmt.Println()
+	for _, t := range tools.All() {
+		if hasTool(enabled, t.Name) {
+			fmt.Printf("  %s✓ %-12s%s %s\n", ui.ColorGreen, t.Name, ui.ColorReset, t.Description)
+		} else {
+			fmt.Printf("  ○ %-12s %s\n", t.Name, t.Description)
+		}
+	}
+	return nil
+}
+
+// EnableTool adds a tool to an existing profile: its directories, its .env variables,
+// its .gitignore patterns and the template files that belong to it
+func EnableTool(profilesDir string, opts ToolsOptions) error {
+	tool, err := tools.Find(opts.Tool)
+	if err != nil {
+		return err
+	}
+	profileDir, err := toolsProfileDir(profilesDir, &opts, "Select profile to enable "+tool.Name+" in:")
+	if err != nil {
+		return err
+	}
+
+	enabled := profileTools(profileDir)
+	if hasTool(enabled, tool.Name) {
+		ui.PrintInfo(fmt.Sprintf("%s is already enabled in profile '%s'", tool.Name, opts.ProfileName))
+		return nil
+	}
+	enabled, err = tools.Select(append(tools.ToolNames(enabled), tool.Name))
+	if err != nil {
+		return err
+	}
+	// The tool's files are rendered with the values the profile was created with
+	createOpts := profileCreateOptions(profileDir)
+	createOpts.Tools = tools.ToolNames(enabled)
+
+	// Template files of the tool that the profile does not have yet
+	tmpl, err := findProfileTemplate(createOpts.Template)
+	if err != nil {
+		return err
+	}
+	paths, _, err := tmpl.Layout()
+	if err != nil {
+		return err
+	}
+	var files []string
+	for _, path := range paths {
+		if !tool.Owns(path) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(profileDir, filepath.FromSlash(path))); os.IsNotExist(err) {
+			files = append(files, path)
+		}
+	}
+
+	if opts.DryRun {
+		ui.PrintInfo("DRY RUN - Nothing will be changed")
+		fmt.Println()
+		fmt.Printf("Would enable %s in profile '%s':\n", tool.Name, opts.ProfileName)
+		printToolChanges(tool, files)
+		return nil
+	}
+
+	if err := checkManagedConflicts(profileDir); err != nil {
+		return err
+	}
+	if err := createProfileDirs(profileDir, []tools.Tool{tool}); err != nil {
+		return err
+	}
+	for _, path := range files {
+		if err := createProfileFile(profileDir, createOpts, path); err != nil {
+			return fmt.Errorf("failed to create %s: %w", path, err)
+		}
+	}
+
+	if err := writeProfileTools(profileDir, enabled); err != nil {
+		return err
+	}
+	if err := updateToolFiles(profileDir, func(content string) (string, bool) {
+		return addEnvVars(content, tool.Env)
+	}, func(content string) (string, bool) {
+		return addGitignoreGroups(content, tool.Gitignore)
+	}); err != nil {
+		return err
+	}
+
+	reloadIfActive(opts.ProfileName)
+	ui.PrintSuccess(fmt.Sprintf("Enabled %s in profile '%s'", tool.Name, opts.ProfileName))
+	printToolChanges(tool, files)
+	return nil
+}
+
+// DisableTool removes a tool from an existing profile: its .env variables, its
+// .gitignore patterns and, after confirmation, its directories and files
+func DisableTool(profilesDir string, opts ToolsOptions) error {
+	tool, err := tools.Find(opts.Tool)
+	if err != nil {
+		return err
+	}
+	profileDir, err := toolsProfileDir(profilesDir, &opts, "Select profile to disable "+tool.Name+" in:")
+	if err != nil {
+		return err
+	}
+
+	enabled := profileTools(profileDir)
+	if !hasTool(enabled, tool.Name) {
+		ui.PrintInfo(fmt.Sprintf("%s is not enabled in profile '%s'", tool.Name, opts.ProfileName))
+		return nil
+	}
+	var remaining []tools.Tool
+	for _, t := range enabled {
+		if t.Name != tool.Name {
+			remaining = append(remaining, t)
+		}
+	}
+
+	// Directories and files of the tool that the profile has
+	var paths []string
+	for _, path := range append(append(append([]string{}, tool.Dirs...), tool.Files...), dotfilePaths(tool)...) {
+		if _, err := os.Lstat(filepath.Join(profileDir, filepath.FromSlash(path))); err == nil {
+			paths = append(paths, path)
+		}
+	}
+
+	if opts.DryRun {
+		ui.PrintInfo("DRY RUN - Nothing will be changed")
+		fmt.Println()
+		fmt.Printf("Would disable %s in profile '%s':\n", tool.Name, opts.ProfileName)
+		for _, v := range tool.Env {
+			fmt.Printf("  - .env: %s\n", v.Name)
+		}
+		for _, g := range tool.Gitignore {
+			fmt.Printf("  - .gitignore: %s (unless its files are kept)\n", g.Comment)
+		}
+		for _, path := range paths {
+			fmt.Printf("  - %s (after confirmation)\n", path)
+		}
+		return nil
+	}
+
+	if err := checkManagedConflicts(profileDir); err != nil {
+		return err
+	}
+
+	// Removing the tool's files deletes its configuration and credentials; ask first
+	remove := opts.Force
+	if len(paths) > 0 && !remove {
+		ui.PrintWarning(fmt.Sprintf("The profile has files of %s:", tool.Name))
+		for _, path := range paths {
+			fmt.Printf("  %s\n", path)
+		}
+		confirmed, err := ui.Confirm("Delete them?", false)
+		if err != nil {
+			return fmt.Errorf("failed to get confirmation: %w", err)
+		}
+		remove = confirmed
+	}
+
+	if _, err := createSnapshot(profilesDir, opts.ProfileName, "tools"); err != nil {
+		return fmt.Errorf("failed to snapshot profile: %w", err)
+	}
+
+	// Kept files can hold credentials, so they stay ignored: the tool's patterns are left
+	// in place, or added back outside the regenerated managed section
+	keepIgnored := len(paths) > 0 && !remove
+	if err := writeProfileTools(profileDir, remaining); err != nil {
+		return err
+	}
+	if err := updateToolFiles(profileDir, func(content string) (string, bool) {
+		return removeEnvVars(content, tool.Env)
+	}, func(content string) (string, bool) {
+		if keepIgnored {
+			return content, false
+		}
+		return removeGitignoreGroups(content, tool.Gitignore)
+	}); err != nil {
+		return err
+	}
+	if keepIgnored {
+		if err := editProfileFile(profileDir, ".gitignore", func(content string) (string, bool) {
+			return addGitignoreGroups(content, tool.Gitignore)
+		}); err != nil {
+			return err
+		}
+	}
+	if remove {
+		for _, path := range paths {
+			if err := os.RemoveAll(filepath.Join(profileDir, filepath.FromSlash(path))); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", path, err)
+			}
+		}
+	}
+
+	reloadIfActive(opts.ProfileName)
+	ui.PrintSuccess(fmt.Sprintf("Disabled %s in profile '%s'", tool.Name, opts.ProfileName))
+	if len(paths) > 0 && !remove {
+		ui.PrintInfo(fmt.Sprintf("Kept %s, still ignored in .gitignore", strings.Join(paths, ", ")))
+	}
+	return nil
+}
+
+// toolsProfileDir resolves the profile of a tools command, asking for it if it was not
+// given, and checks that it exists
+func toolsProfileDir(profilesDir string, opts *ToolsOptions, message string) (string, error) {
+	if opts.ProfileName == "" {
+		selected, err := selectProfile(profilesDir, message)
+		if err != nil {
+			return "", err
+		}
+		opts.ProfileName = selected
+	}
+
+	profileDir := filepath.Join(profilesDir, opts.ProfileName)
+	if _, err := os.Stat(filepath.Join(profileDir, ".envrc")); os.IsNotExist(err) {
+		return "", fmt.Errorf("profile '%s' does not exist at: %s", opts.ProfileName, profileDir)
+	}
+	warnProfileSchema(profileDir)
+	return profileDir, nil
+}
+
+// printToolChanges lists what enabling a tool adds to a profile
+func printToolChanges(tool tools.Tool, files []string) {
+	for _, dir := range tool.Dirs {
+		fmt.Printf("  + %s/\n", dir)
+	}
+	for _, path := range files {
+		fmt.Printf("  + %s\n", path)
+	}
+	for _, v := range tool.Env {
+		fmt.Printf("  + .env: %s\n", v.Name)
+	}
+	for _, g := range tool.Gitignore {
+		fmt.Printf("  + .gitignore: %s\n", g.Comment)
+	}
+}
+
+// dotfilePaths returns the paths of a tool's dotfiles that are outside its directories
+func dotfilePaths(tool tools.Tool) []string {
+	var paths []string
+	for _, d := range tool.Dotfiles {
+		inDir := false
+		for _, dir := range tool.Dirs {
+			if d.Path == dir || strings.HasPrefix(d.Path, dir+"/") {
+				inDir = true
+			}
+		}
+		if !inDir {
+			paths = append(paths, d.Path)
+		}
+	}
+	return paths
+}
+
+// editProfileFile rewrites a profile file with edit, if it exists and edit changes it
+func editProfileFile(profileDir, name string, edit func(string) (string, bool)) error {
+	path := filepath.Join(profileDir, name)
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	edited, changed := edit(string(content))
+	if !changed {
+		return nil
+	}
+	if err := os.WriteFile(path, []byte(edited), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// updateToolFiles brings .env and .gitignore in line with a change to the profile's tools.
+// Their managed sections are regenerated; files without one are changed with editEnv and
+// editGitignore.
+func updateToolFiles(profileDir string, editEnv, editGitignore func(string) (string, bool)) error {
+	for _, file := range []struct {
+		path string
+		edit func(string) (string, bool)
+	}{
+		{".env", editEnv},
+		{".gitignore", editGitignore},
+	} {
+		if !hasManagedSection(profileDir, file.path) {
+			if err := editProfileFile(profileDir, file.path, file.edit); err != nil {
+				return err
+			}
+			continue
+		}
+		result, err := updateManagedSection(profileDir, file.path, false)
+		if err != nil {
+			return err
+		}
+		if len(result.conflicts) > 0 {
+			printConflicts(file.path, result.conflicts)
+			ui.PrintWarning(fmt.Sprintf("Edit %s to resolve the conflicts and remove the conflict markers", file.path))
+		}
+	}
+	return nil
+}
+
+// reloadIfActive has the sp shell function reload direnv if the profile is the one it
+// has loaded
+func reloadIfActive(profileName string) {
+	if os.Getenv("WORKSPACE_PROFILE") != profileName {
+		return
+	}
+	if err := writeHookDirective("reload"); err != nil {
+		ui.PrintWarning(err.Error())
+	}
+}
+
+// addEnvVars appends the variables missing from .env content. Optional variables are
+// left for the user to enable.
+func addEnvVars(content string, vars []tools.EnvVar) (string, bool) {
+	present := make(map[string]bool)
+	for _, line := range strings.Split(content, "\n") {
+		if name, ok := envVarName(line); ok {
+			present[name] = true
+		}
+	}
+
+	updated := false
+	for _, v := range vars {
+		if v.Optional || present[v.Name] {
+			continue
+		}
+		present[v.Name] = true
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		if v.Comment != "" {
+			content += "\n"
+		}
+		content += v.Render()
+		updated = true
+	}
+	return content, updated
+}
+
+// envVarName returns the name of the variable a .env line sets, commented out or not
+func envVarName(line string) (string, bool) {
+	trimmed := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "#"))
+	trimmed = strings.TrimSpace(strings.TrimPrefix(trimmed, "export "))
+	name, _, ok := strings.Cut(trimmed, "=")
+	return strings.TrimSpace(name), ok
+}
+
+// removeEnvVars removes variables from .env content, commented out or not, together
+// with the comment lines above them
+func removeEnvVars(content string, vars []tools.EnvVar) (string, bool) {
+	names := make(map[string]bool, len(vars))
+	for _, v := range vars {
+		names[v.Name] = true
+	}
+
+	lines := strings.Split(content, "\n")
+	var kept []string
+	removed, skipNextBlank := false, false
+	for _, line := range lines {
+		if name, ok := envVarName(line); ok && names[name] {
+			for len(kept) > 0 && strings.HasPrefix(strings.TrimSpace(kept[len(kept)-1]), "#") {
+				kept = kept[:len(kept)-1]
+			}
+			removed, skipNextBlank = true, true
+			continue
+		}
+		if skipNextBlank && strings.TrimSpace(line) == "" {
+			skipNextBlank = false
+			continue
+		}
+		skipNextBlank = false
+		kept = append(kept, line)
+	}
+	if !removed {
+		return content, false
+	}
+	return strings.Join(kept, "\n"), true
+}
+
+// addGitignoreGroups adds the groups missing entirely from .gitignore content, before
+// its OS and editor sections, or at the end
+func addGitignoreGroups(content string, groups []tools.IgnoreGroup) (string, bool) {
+	updated := false
+	for _, group := range groups {
+		hasAny := false
+		for _, pattern := range group.Patterns {
+			if strings.Contains(content, strings.TrimSuffix(pattern, "/")) {
+				hasAny = true
+				break
+			}
+		}
+		if hasAny {
+			continue
+		}
+
+		insertPoint := strings.Index(content, "# OS files")
+		if insertPoint == -1 {
+			if content != "" && !strings.HasSuffix(content, "\n") {
+				content += "\n"
+			}
+			content += "\n"
+			insertPoint = len(content)
+		}
+		content = content[:insertPoint] + group.Render() + "\n" + content[insertPoint:]
+		updated = true
+	}
+	return content, updated
+}
+
+// removeGitignoreGroups removes the comments and patterns of groups from .gitignore
+// content
+func removeGitignoreGroups(content string, groups []tools.IgnoreGroup) (string, bool) {
+	drop := make(map[string]bool)
+	for _, group := range groups {
+		if group.Comment != "" {
+			drop["# "+group.Comment] = true
+		}
+		for _, pattern := range group.Patterns {
+			drop[pattern] = true
+		}
+	}
+
+	lines := strings.Split(content, "\n")
+	var kept []string
+	removed, skipNextBlank := false, false
+	for _, line := range lines {
+		if drop[strings.TrimSpace(line)] {
+			removed, skipNextBlank = true, true
+			continue
+		}
+		if skipNextBlank && strings.TrimSpace(line) == "" {
+			skipNextBlank = false
+			continue
+		}
+		skipNextBlank = false
+		kept = append(kept, line)
+	}
+	if !removed {
+		return content, false
+	}
+	return strings.Join(kept, "\n"), true
+}
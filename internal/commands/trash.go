@@ -0,0 +1,353 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neverprepared/shell-profile-manager/internal/config"
+	"github.com/neverprepared/shell-profile-manager/internal/ui"
+)
+
+const (
+	trashMetadataName = "trash.json"
+	trashProfileDir   = "profile"
+)
+
+type TrashOptions struct {
+	ProfileName string
+	NewName     string        // restore under a different name
+	OlderThan   time.Duration // empty only entries older than this
+	Force       bool
+	DryRun      bool
+}
+
+// trashEntry is the metadata stored next to a trashed profile
+type trashEntry struct {
+	ID           string    `json:"-"`
+	Path         string    `json:"-"`
+	Name         string    `json:"name"`
+	OriginalPath string    `json:"original_path"`
+	Deleted      time.Time `json:"deleted"`
+	GitRemote    string    `json:"git_remote,omitempty"`
+}
+
+// ListTrash lists the profiles in the trash
+func ListTrash(opts TrashOptions) error {
+	entries, err := listTrash()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s=== Profile Trash ===%s\n", ui.ColorBlue, ui.ColorReset)
+	fmt.Println()
+
+	if len(entries) == 0 {
+		fmt.Println("Trash is empty")
+		return nil
+	}
+
+	for _, e := range entries {
+		if opts.ProfileName != "" && e.Name != opts.ProfileName {
+			continue
+		}
+		fmt.Printf("%s○ %s%s\n", ui.ColorCyan, e.Name, ui.ColorReset)
+		fmt.Printf("  Deleted:  %s\n", e.Deleted.Local().Format("2006-01-02 15:04:05"))
+		fmt.Printf("  Location: %s\n", e.OriginalPath)
+		if e.GitRemote != "" {
+			fmt.Printf("  Remote:   %s\n", e.GitRemote)
+		}
+		fmt.Printf("  Size:     %s\n", formatFileSize(dirSize(filepath.Join(e.Path, trashProfileDir))))
+		fmt.Println()
+	}
+
+	fmt.Printf("%sTotal in trash: %d%s\n", ui.ColorBlue, len(entries), ui.ColorReset)
+	fmt.Println("Restore with:")
+	fmt.Println("  shell-profiler trash restore <profile>")
+	return nil
+}
+
+// RestoreFromTrash moves a trashed profile back into place
+func RestoreFromTrash(profilesDir string, opts TrashOptions) error {
+	entries, err := listTrash()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("trash is empty")
+	}
+
+	entry, err := chooseTrashEntry(entries, opts.ProfileName)
+	if err != nil {
+		return err
+	}
+
+	// Restore to the original location unless a new name is given
+	targetDir := entry.OriginalPath
+	targetName := entry.Name
+	if opts.NewName != "" {
+		if err := validateProfileName(opts.NewName); err != nil {
+			return err
+		}
+		targetName = opts.NewName
+		if targetDir, err = filepath.Abs(filepath.Join(profilesDir, opts.NewName)); err != nil {
+			return fmt.Errorf("failed to get absolute path: %w", err)
+		}
+	}
+	if _, err := os.Stat(targetDir); err == nil {
+		return fmt.Errorf("'%s' already exists (use --as <name> to restore under another name)", targetDir)
+	}
+
+	ui.PrintInfo(fmt.Sprintf("Restoring profile from trash: %s", entry.Name))
+	fmt.Printf("  Deleted: %s\n", entry.Deleted.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("  To:      %s\n", targetDir)
+
+	if opts.DryRun {
+		fmt.Println()
+		ui.PrintInfo("DRY RUN - Nothing will be restored")
+		return nil
+	}
+
+	if err := moveTree(filepath.Join(entry.Path, trashProfileDir), targetDir); err != nil {
+		return fmt.Errorf("failed to restore profile: %w", err)
+	}
+
+	// A profile restored under a new name gets its references rewritten, as with rename
+	if targetName != entry.Name || targetDir != entry.OriginalPath {
+		rewrites, err := planReferenceRewrites(targetDir, entry.Name, targetName, entry.OriginalPath, targetDir)
+		if err != nil {
+			return err
+		}
+		if err := applyRewrites(targetDir, rewrites); err != nil {
+			return err
+		}
+	}
+	if targetName != entry.Name {
+		if err := editProfileMetadata(targetDir, func(m *profileMetadata) {
+			m.Name = targetName
+			m.Updated = time.Now().UTC().Truncate(time.Second)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := os.RemoveAll(entry.Path); err != nil {
+		ui.PrintWarning(fmt.Sprintf("Failed to clean up trash entry: %v", err))
+	}
+
+	ui.PrintSuccess(fmt.Sprintf("Profile restored: %s", targetName))
+	fmt.Println()
+	ui.PrintInfo("Next steps:")
+	fmt.Printf("  1. cd %s\n", targetDir)
+	fmt.Println("  2. direnv allow")
+	return nil
+}
+
+// EmptyTrash permanently deletes trashed profiles
+func EmptyTrash(opts TrashOptions) error {
+	entries, err := listTrash()
+	if err != nil {
+		return err
+	}
+
+	var expired []trashEntry
+	for _, e := range entries {
+		if opts.ProfileName != "" && e.Name != opts.ProfileName {
+			continue
+		}
+		if opts.OlderThan > 0 && time.Since(e.Deleted) < opts.OlderThan {
+			continue
+		}
+		expired = append(expired, e)
+	}
+
+	if len(expired) == 0 {
+		ui.PrintInfo("Nothing to remove from the trash")
+		return nil
+	}
+
+	ui.PrintInfo("Profiles to remove permanently:")
+	for _, e := range expired {
+		fmt.Printf("  - %s (deleted %s)\n", e.Name, e.Deleted.Local().Format("2006-01-02 15:04:05"))
+	}
+
+	if opts.DryRun {
+		fmt.Println()
+		ui.PrintInfo("DRY RUN - Nothing will be removed")
+		return nil
+	}
+
+	if !opts.Force {
+		confirmed, err := ui.Confirm(fmt.Sprintf("Permanently delete %d profile(s) from the trash?", len(expired)), false)
+		if err != nil {
+			return fmt.Errorf("failed to get confirmation: %w", err)
+		}
+		if !confirmed {
+			ui.PrintInfo("Cancelled")
+			return nil
+		}
+	}
+
+	for _, e := range expired {
+		if err := os.RemoveAll(e.Path); err != nil {
+			return fmt.Errorf("failed to remove %s from trash: %w", e.Name, err)
+		}
+	}
+
+	ui.PrintSuccess(fmt.Sprintf("Removed %d profile(s) from the trash", len(expired)))
+	return nil
+}
+
+// trashRoot returns the directory holding trashed profiles
+func trashRoot() (string, error) {
+	stateDir, err := config.GetStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "trash"), nil
+}
+
+// moveToTrash moves a profile directory into the trash and records where it came from
+func moveToTrash(profilesDir, profileName string) (trashEntry, error) {
+	profileDir, err := filepath.Abs(filepath.Join(profilesDir, profileName))
+	if err != nil {
+		return trashEntry{}, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	root, err := trashRoot()
+	if err != nil {
+		return trashEntry{}, err
+	}
+
+	entry := trashEntry{
+		Name:         profileName,
+		OriginalPath: profileDir,
+		Deleted:      time.Now().UTC(),
+		GitRemote:    gitRemoteURL(profileDir),
+	}
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return trashEntry{}, fmt.Errorf("failed to create trash directory: %w", err)
+	}
+
+	// A profile deleted again in the same second gets the next sequence number rather than
+	// sharing the directory of the earlier entry
+	stamp := fmt.Sprintf("%s_%s", profileName, entry.Deleted.Local().Format(backupTimeFormat))
+	for seq := 1; ; seq++ {
+		entry.ID = stamp
+		if seq > 1 {
+			entry.ID = fmt.Sprintf("%s-%d", stamp, seq)
+		}
+		entry.Path = filepath.Join(root, entry.ID)
+		err := os.Mkdir(entry.Path, 0700)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return trashEntry{}, fmt.Errorf("failed to create trash directory: %w", err)
+		}
+	}
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return trashEntry{}, err
+	}
+	if err := os.WriteFile(filepath.Join(entry.Path, trashMetadataName), append(data, '\n'), 0600); err != nil {
+		return trashEntry{}, fmt.Errorf("failed to write trash metadata: %w", err)
+	}
+
+	if err := moveTree(profileDir, filepath.Join(entry.Path, trashProfileDir)); err != nil {
+		os.RemoveAll(entry.Path) //nolint:errcheck // Best-effort cleanup, the profile is still in place
+		return trashEntry{}, fmt.Errorf("failed to move profile to trash: %w", err)
+	}
+
+	return entry, nil
+}
+
+// listTrash returns the trashed profiles, newest first
+func listTrash() ([]trashEntry, error) {
+	root, err := trashRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	dirEntries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read trash directory: %w", err)
+	}
+
+	var entries []trashEntry
+	for _, d := range dirEntries {
+		if !d.IsDir() {
+			continue
+		}
+		path := filepath.Join(root, d.Name())
+		data, err := os.ReadFile(filepath.Join(path, trashMetadataName))
+		if err != nil {
+			continue
+		}
+		var entry trashEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			continue
+		}
+		entry.ID = d.Name()
+		entry.Path = path
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Deleted.After(entries[j].Deleted) })
+	return entries, nil
+}
+
+// chooseTrashEntry finds the newest entry for a profile name or ID, or asks the user to pick one
+func chooseTrashEntry(entries []trashEntry, name string) (trashEntry, error) {
+	if name != "" {
+		for _, e := range entries {
+			if e.Name == name || e.ID == name {
+				return e, nil
+			}
+		}
+		return trashEntry{}, fmt.Errorf("profile '%s' is not in the trash", name)
+	}
+
+	options := make([]string, len(entries))
+	for i, e := range entries {
+		options[i] = fmt.Sprintf("%s (deleted %s)", e.Name, e.Deleted.Local().Format("2006-01-02 15:04:05"))
+	}
+	selected, err := ui.SelectProfile(options, "Select profile to restore:")
+	if err != nil {
+		return trashEntry{}, err
+	}
+	for i, option := range options {
+		if option == selected {
+			return entries[i], nil
+		}
+	}
+	return trashEntry{}, fmt.Errorf("no profile selected")
+}
+
+// ParseAge parses an age such as 30d, 2w or 12h
+func ParseAge(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if strings.HasSuffix(s, suffix) {
+			n, err := strconv.Atoi(strings.TrimSuffix(s, suffix))
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid age: %s (examples: 30d, 2w, 12h)", s)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age: %s (examples: 30d, 2w, 12h)", s)
+	}
+	return d, nil
+}
@@ -3,10 +3,13 @@ package commands
 import (
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/neverprepared/shell-profile-manager/internal/templates"
+	"github.com/neverprepared/shell-profile-manager/internal/tools"
 	"github.com/neverprepared/shell-profile-manager/internal/ui"
 )
 
@@ -64,12 +67,12 @@ func UpdateProfile(profilesDir string, opts UpdateOptions) error {
 	fmt.Printf("  Location: %s\n", profileDir)
 	fmt.Println()
 
-	// Create backup unless --no-backup is specified
+	// Snapshot the profile unless --no-backup is specified
 	if !opts.NoBackup && !opts.DryRun {
-		if err := createBackup(profileDir, opts.ProfileName); err != nil {
-			ui.PrintWarning(fmt.Sprintf("Failed to create backup: %v", err))
+		if _, err := createSnapshot(profilesDir, opts.ProfileName, "update"); err != nil {
+			ui.PrintWarning(fmt.Sprintf("Failed to create snapshot: %v", err))
 			if !opts.Force {
-				confirmed, err := ui.Confirm("Continue without backup?", false)
+				confirmed, err := ui.Confirm("Continue without snapshot?", false)
 				if err != nil || !confirmed {
 					return fmt.Errorf("update cancelled")
 				}
@@ -80,20 +83,26 @@ func UpdateProfile(profilesDir string, opts UpdateOptions) error {
 	// Track what was updated
 	updates := []string{}
 
-	// Update directories
+	// Apply the migrations between the profile's schema version and this binary's
+	applied, err := migrateProfile(migrationContext{
+		profilesDir: profilesDir,
+		profileDir:  profileDir,
+		profileName: opts.ProfileName,
+		force:       opts.Force,
+		dryRun:      opts.DryRun,
+	})
+	updates = append(updates, applied...)
+	if err != nil {
+		return err
+	}
+
+	// Bring the enabled tools' directories, .env variables and .gitignore patterns up to date
 	if updated, err := updateDirectories(profileDir, opts.DryRun); err != nil {
 		return fmt.Errorf("failed to update directories: %w", err)
 	} else if len(updated) > 0 {
 		updates = append(updates, fmt.Sprintf("Created directories: %s", strings.Join(updated, ", ")))
 	}
 
-	// Update .envrc (remove tool-specific vars that belong in .env)
-	if updated, err := updateEnvrc(profileDir, opts.ProfileName, opts.DryRun, opts.Force); err != nil {
-		return fmt.Errorf("failed to update .envrc: %w", err)
-	} else if updated {
-		updates = append(updates, "Updated .envrc (moved tool-specific vars to .env)")
-	}
-
 	// Update .env with tool-specific environment variables
 	if updated, err := updateEnvFile(profileDir, opts.ProfileName, opts.DryRun); err != nil {
 		return fmt.Errorf("failed to update .env: %w", err)
@@ -108,6 +117,39 @@ func UpdateProfile(profilesDir string, opts UpdateOptions) error {
 		updates = append(updates, "Updated .gitignore with new patterns")
 	}
 
+	// Bring the managed sections up to date, keeping changes made to them in the profile
+	conflicts := make(map[string][][]string)
+	var failed []string
+	for _, path := range managedFiles {
+		result, err := updateManagedSection(profileDir, path, opts.DryRun)
+		if err != nil {
+			ui.PrintWarning(err.Error())
+			failed = append(failed, path)
+			continue
+		}
+		switch {
+		case len(result.conflicts) > 0:
+			updates = append(updates, fmt.Sprintf("Merged the managed section of %s, with conflicts", path))
+			conflicts[path] = result.conflicts
+		case result.changed && result.merged:
+			updates = append(updates, fmt.Sprintf("Merged the managed section of %s, keeping your changes", path))
+		case result.changed:
+			updates = append(updates, fmt.Sprintf("Updated the managed section of %s", path))
+		}
+		if result.changed && path == ".envrc" && !opts.DryRun {
+			reloadIfActive(opts.ProfileName)
+		}
+	}
+
+	// Record when the profile was last updated
+	if len(updates) > 0 && !opts.DryRun {
+		if err := editProfileMetadata(profileDir, func(m *profileMetadata) {
+			m.Updated = time.Now().UTC().Truncate(time.Second)
+		}); err != nil {
+			return err
+		}
+	}
+
 	// Summary
 	if opts.DryRun {
 		ui.PrintInfo("DRY RUN - No changes were made")
@@ -117,78 +159,124 @@ func UpdateProfile(profilesDir string, opts UpdateOptions) error {
 			for _, update := range updates {
 				fmt.Printf("  - %s\n", update)
 			}
-		} else {
+		} else if len(failed) == 0 {
 			fmt.Println("  Profile is already up to date")
 		}
 	} else {
 		if len(updates) > 0 {
-			ui.PrintSuccess("Profile updated successfully")
+			switch {
+			case len(conflicts) > 0:
+				ui.PrintWarning("Profile updated, with merge conflicts")
+			case len(failed) > 0:
+				ui.PrintWarning("Profile partly updated")
+			default:
+				ui.PrintSuccess("Profile updated successfully")
+			}
 			fmt.Println()
 			fmt.Println("Updates applied:")
 			for _, update := range updates {
 				fmt.Printf("  ✓ %s\n", update)
 			}
-		} else {
+		} else if len(failed) == 0 {
 			ui.PrintInfo("Profile is already up to date")
 		}
 	}
 
+	if len(failed) > 0 {
+		fmt.Println()
+		ui.PrintWarning(fmt.Sprintf("Not brought up to date: the managed section of %s", strings.Join(failed, ", ")))
+	}
+
+	if len(conflicts) > 0 {
+		fmt.Println()
+		for _, path := range managedFiles {
+			if c, ok := conflicts[path]; ok {
+				printConflicts(path, c)
+			}
+		}
+		if opts.DryRun {
+			ui.PrintWarning("The update would leave conflict markers in these files")
+		} else {
+			ui.PrintWarning("Edit the files to resolve the conflicts and remove the conflict markers")
+			if !opts.NoBackup {
+				fmt.Printf("  To undo the update: shell-profiler restore %s\n", opts.ProfileName)
+			}
+		}
+	}
+
+	// The template's other files are not re-applied; say when they have moved on
+	if sources, err := templates.LoadSources(templatesDir()); err == nil {
+		if note := templateRevisionNote(profileDir, opts.ProfileName, sources); note != "" {
+			fmt.Println()
+			ui.PrintInfo(note)
+		}
+	}
+
 	return nil
 }
 
-func createBackup(profileDir, _profileName string) error {
-	backupDir := filepath.Join(profileDir, ".backups")
-	if err := os.MkdirAll(backupDir, 0755); err != nil {
-		return fmt.Errorf("failed to create backup directory: %w", err)
+// legacyBackupSources returns the backup directory written by earlier versions inside the
+// profile (.backups), if there is one
+func legacyBackupSources(profilesDir, profileName string) []string {
+	dir := filepath.Join(profilesDir, profileName, ".backups")
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		return []string{dir}
 	}
+	return nil
+}
 
-	timestamp := time.Now().Format("2006-01-02_15-04-05")
-	backupPath := filepath.Join(backupDir, fmt.Sprintf("update_%s", timestamp))
-
-	// Copy important files
-	filesToBackup := []string{
-		".envrc",
-		".env",
-		".gitconfig",
-		".gitignore",
+// migrateLegacyBackups moves legacy backups into the state directory and removes
+// .backups from the profile's git index. Returns the destination directory.
+func migrateLegacyBackups(profileDir, profileName string, sources []string, dryRun bool) (string, error) {
+	dest, err := profileBackupsDir(profileName)
+	if err != nil {
+		return "", err
+	}
+	if dryRun {
+		return dest, nil
 	}
 
-	for _, file := range filesToBackup {
-		src := filepath.Join(profileDir, file)
-		if _, err := os.Stat(src); err == nil {
-			content, err := os.ReadFile(src)
-			if err != nil {
-				continue
+	for _, source := range sources {
+		entries, err := os.ReadDir(source)
+		if err != nil {
+			return "", err
+		}
+		for _, entry := range entries {
+			target := filepath.Join(dest, entry.Name())
+			if _, err := os.Stat(target); err == nil {
+				return "", fmt.Errorf("backup %s already exists in %s", entry.Name(), dest)
 			}
-
-			backupFile := filepath.Join(backupPath, file)
-			if err := os.MkdirAll(filepath.Dir(backupFile), 0755); err != nil {
-				continue
+			if err := moveTree(filepath.Join(source, entry.Name()), target); err != nil {
+				return "", err
 			}
+		}
+		if err := os.Remove(source); err != nil {
+			return "", err
+		}
+	}
 
-			if err := os.WriteFile(backupFile, content, 0644); err != nil {
-				continue
+	// Stop tracking backups that were committed before they were ignored
+	if _, err := os.Stat(filepath.Join(profileDir, ".git")); err == nil {
+		cmd := exec.Command("git", "ls-files", "--", ".backups")
+		cmd.Dir = profileDir
+		output, err := cmd.Output()
+		if err == nil && len(output) > 0 {
+			cmd = exec.Command("git", "rm", "-r", "--cached", "--quiet", "--", ".backups")
+			cmd.Dir = profileDir
+			if err := cmd.Run(); err != nil {
+				return "", fmt.Errorf("failed to remove .backups from the git index: %w", err)
 			}
+			ui.PrintWarning("Removed .backups from the git index (committed by the next sync push)")
+			fmt.Println("  Backups remain in earlier commits. If they contained secrets, rotate them")
+			fmt.Println("  and rewrite the history (e.g. git filter-repo --path .backups --invert-paths)")
 		}
 	}
 
-	ui.PrintInfo(fmt.Sprintf("Backup created: %s", backupPath))
-	return nil
+	return dest, nil
 }
 
 func updateDirectories(profileDir string, dryRun bool) ([]string, error) {
-	requiredDirs := []string{
-		".config/1Password",
-		".config/claude",
-		".config/gemini",
-		".ssh",
-		".aws",
-		".azure",
-		".gcloud",
-		".kube",
-		"bin",
-		"code",
-	}
+	requiredDirs := tools.Dirs(profileTools(profileDir))
 
 	var created []string
 	for _, dir := range requiredDirs {
@@ -226,20 +314,9 @@ func updateEnvrc(profileDir, _profileName string, dryRun, _force bool) (bool, er
 	updated := false
 
 	// Tool-specific variable names that belong in .env, not .envrc
-	toolVars := []string{
-		"XDG_CONFIG_HOME",
-		"SSH_AUTH_SOCK",
-		"GIT_CONFIG_GLOBAL",
-		"GIT_SSH_COMMAND",
-		"AWS_CONFIG_FILE",
-		"AWS_SHARED_CREDENTIALS_FILE",
-		"KUBECONFIG",
-		"TF_CLI_CONFIG_FILE",
-		"TF_PLUGIN_CACHE_DIR",
-		"AZURE_CONFIG_DIR",
-		"CLOUDSDK_CONFIG",
-		"CLAUDE_CONFIG_DIR",
-		"GEMINI_CONFIG_DIR",
+	var toolVars []string
+	for _, v := range tools.EnvVars(tools.All()) {
+		toolVars = append(toolVars, v.Name)
 	}
 
 	// Remove tool-specific export lines and their preceding comments from .envrc
@@ -333,64 +410,25 @@ func updateEnvrc(profileDir, _profileName string, dryRun, _force bool) (bool, er
 	return updated, nil
 }
 
-func updateEnvFile(profileDir, profileName string, dryRun bool) (bool, error) {
+func updateEnvFile(profileDir, _profileName string, dryRun bool) (bool, error) {
 	envPath := filepath.Join(profileDir, ".env")
-	var envContent string
-
-	if data, err := os.ReadFile(envPath); err == nil {
-		envContent = string(data)
-	}
-
-	updated := false
-
-	// Define tool-specific variables that should be in .env
-	requiredVars := []struct {
-		name    string
-		value   string
-		comment string
-	}{
-		{"GIT_CONFIG_GLOBAL", `"$WORKSPACE_HOME/.gitconfig"`, "# Git configuration"},
-		{"GIT_SSH_COMMAND", `"ssh -F $WORKSPACE_HOME/.ssh/config"`, "# SSH configuration\n# Use workspace-specific SSH config instead of $HOME/.ssh/config"},
-		{"XDG_CONFIG_HOME", `"$WORKSPACE_HOME/.config"`, "# XDG Base Directory specification\n# Point all XDG-compliant tools to workspace-specific config"},
-		{"SSH_AUTH_SOCK", `"$HOME/Library/Group Containers/2BUA8C4S2C.com.1password/t/agent.sock"`, "# 1Password SSH Agent\n# Point to 1Password SSH agent socket for SSH key management"},
-		{"AWS_CONFIG_FILE", `"$WORKSPACE_HOME/.aws/config"`, "# AWS configuration\n# Point AWS CLI and SDKs to workspace-specific config and credentials"},
-		{"AWS_SHARED_CREDENTIALS_FILE", `"$WORKSPACE_HOME/.aws/credentials"`, ""},
-		{"KUBECONFIG", `"$WORKSPACE_HOME/.kube/config"`, "# Kubernetes configuration\n# Point kubectl to workspace-specific kubeconfig"},
-		{"TF_CLI_CONFIG_FILE", `"$WORKSPACE_HOME/.terraformrc"`, "# Terraform configuration\n# Use workspace-specific Terraform CLI config"},
-		{"AZURE_CONFIG_DIR", `"$WORKSPACE_HOME/.azure"`, "# Azure CLI configuration\n# Point Azure CLI to workspace-specific config directory"},
-		{"CLOUDSDK_CONFIG", `"$WORKSPACE_HOME/.gcloud"`, "# Google Cloud SDK configuration\n# Point gcloud CLI to workspace-specific config directory"},
-		{"CLAUDE_CONFIG_DIR", `"$WORKSPACE_HOME/.config/claude"`, "# Claude Code configuration\n# Point Claude Code to workspace-specific config directory"},
-		{"GEMINI_CONFIG_DIR", `"$WORKSPACE_HOME/.config/gemini"`, "# Gemini CLI configuration\n# Point Gemini CLI to workspace-specific config directory"},
-	}
-
-	if envContent == "" {
-		// Create new .env file with all vars
-		envContent = fmt.Sprintf("# Environment variables for workspace profile: %s\n", profileName)
-		envContent += "# This file is loaded by direnv via dotenv_if_exists in .envrc\n"
-		envContent += "# Add tool-specific paths and secrets here (not in .envrc)\n"
-
-		for _, v := range requiredVars {
-			if v.comment != "" {
-				envContent += "\n" + v.comment + "\n"
-			}
-			envContent += v.name + "=" + v.value + "\n"
-		}
-		updated = true
-	} else {
-		// Add missing variables
-		for _, v := range requiredVars {
-			if !strings.Contains(envContent, v.name+"=") {
-				addition := ""
-				if v.comment != "" {
-					addition += "\n" + v.comment + "\n"
-				}
-				addition += v.name + "=" + v.value + "\n"
-				envContent += addition
-				updated = true
+	data, err := os.ReadFile(envPath)
+	if err != nil {
+		// .env doesn't exist, render it from the profile's template
+		if !dryRun {
+			if err := createProfileFile(profileDir, profileCreateOptions(profileDir), ".env"); err != nil {
+				return false, fmt.Errorf("failed to create .env: %w", err)
 			}
 		}
+		return true, nil
 	}
 
+	// The managed section holds the tool variables; files without one get the missing ones
+	if _, _, _, ok := splitManaged(string(data)); ok {
+		return false, nil
+	}
+	envContent, updated := addEnvVars(string(data), tools.EnvVars(profileTools(profileDir)))
+
 	if updated && !dryRun {
 		if err := os.WriteFile(envPath, []byte(envContent), 0644); err != nil {
 			return false, fmt.Errorf("failed to write .env: %w", err)
@@ -404,163 +442,20 @@ func updateGitignore(profileDir string, dryRun, _force bool) (bool, error) {
 	gitignorePath := filepath.Join(profileDir, ".gitignore")
 	content, err := os.ReadFile(gitignorePath)
 	if err != nil {
-		// .gitignore doesn't exist, create it using the same function from create.go
-		// We'll create a basic one inline
+		// .gitignore doesn't exist, render it from the profile's template
 		if !dryRun {
-			gitignoreContent := `# Workspace profile gitignore
-
-# Environment files with secrets
-.env
-.envrc.local
-
-# SSH keys and sensitive files
-.ssh/id_*
-.ssh/*.pem
-.ssh/*.key
-.ssh/known_hosts
-
-# AWS credentials and sensitive config
-.aws/credentials
-.aws/cli/cache
-.aws/sso/cache
-
-# Azure CLI credentials and sensitive config
-.azure/config
-.azure/clouds.config
-.azure/accessTokens.json
-.azure/msal_token_cache.json
-.azure/azureProfile.json
-
-# Google Cloud SDK credentials and sensitive config
-.gcloud/configurations/
-.gcloud/credentials
-.gcloud/access_tokens.db
-.gcloud/legacy_credentials/
-.gcloud/logs/
-
-# Claude Code configuration (may contain API keys and sensitive data)
-.config/claude/
-
-# Gemini CLI configuration (may contain API keys and sensitive data)
-.config/gemini/
-
-# Terraform
-.terraform/
-.terraform.lock.hcl
-*.tfstate
-*.tfstate.*
-*.tfvars
-.terraform.d/plugin-cache/
-.terraform.d/checkpoint_cache
-.terraform.d/checkpoint_signature
-
-# Terragrunt
-.terragrunt-cache/
-*.tfplan
-
-# Kubernetes
-.kube/cache
-.kube/http-cache
-
-# OS files
-.DS_Store
-Thumbs.db
-
-# Editor files
-.vscode/
-.idea/
-*.swp
-*.swo
-*~
-
-# Build artifacts
-bin/
-dist/
-build/
-*.log
-`
-			if err := os.WriteFile(gitignorePath, []byte(gitignoreContent), 0644); err != nil {
+			if err := createProfileFile(profileDir, profileCreateOptions(profileDir), ".gitignore"); err != nil {
 				return false, fmt.Errorf("failed to create .gitignore: %w", err)
 			}
 		}
 		return true, nil
 	}
 
-	gitignoreContent := string(content)
-	updated := false
-
-	// Check and add missing patterns
-	requiredPatterns := map[string]string{
-		".azure/config":              "# Azure CLI credentials and sensitive config",
-		".gcloud/configurations":     "# Google Cloud SDK credentials and sensitive config",
-		".gcloud/credentials":        "",
-		".gcloud/access_tokens.db":   "",
-		".gcloud/legacy_credentials": "",
-		".gcloud/logs":               "",
-		".config/claude/":            "# Claude Code configuration (may contain API keys and sensitive data)",
-		".config/gemini/":            "# Gemini CLI configuration (may contain API keys and sensitive data)",
-	}
-
-	// Group patterns by comment
-	patternsByComment := make(map[string][]string)
-	currentComment := ""
-	for pattern, comment := range requiredPatterns {
-		if comment != "" {
-			currentComment = comment
-		}
-		if patternsByComment[currentComment] == nil {
-			patternsByComment[currentComment] = []string{}
-		}
-		patternsByComment[currentComment] = append(patternsByComment[currentComment], pattern)
-	}
-
-	for comment, patterns := range patternsByComment {
-		// Check if any pattern from this group is missing
-		hasAny := false
-		for _, pattern := range patterns {
-			if strings.Contains(gitignoreContent, pattern) {
-				hasAny = true
-				break
-			}
-		}
-
-		if !hasAny {
-			// Find insertion point (after Azure section or at end)
-			insertPoint := strings.Index(gitignoreContent, "# Azure CLI credentials")
-			if insertPoint == -1 {
-				insertPoint = strings.Index(gitignoreContent, "# Terraform")
-				if insertPoint == -1 {
-					insertPoint = len(gitignoreContent)
-				}
-			} else {
-				// Find end of Azure section
-				insertPoint = strings.Index(gitignoreContent[insertPoint:], "\n\n#")
-				if insertPoint != -1 {
-					insertPoint += insertPoint
-				} else {
-					insertPoint = strings.Index(gitignoreContent, "# Terraform")
-					if insertPoint == -1 {
-						insertPoint = len(gitignoreContent)
-					}
-				}
-			}
-
-			before := gitignoreContent[:insertPoint]
-			after := gitignoreContent[insertPoint:]
-
-			newSection := ""
-			if comment != "" {
-				newSection = comment + "\n"
-			}
-			for _, pattern := range patterns {
-				newSection += pattern + "\n"
-			}
-			newSection += "\n"
-
-			gitignoreContent = before + newSection + after
-			updated = true
-		}
+	// The managed section holds the tool groups; files without one get those missing entirely
+	if _, _, _, ok := splitManaged(string(content)); ok {
+		return false, nil
 	}
+	gitignoreContent, updated := addGitignoreGroups(string(content), tools.Gitignore(profileTools(profileDir)))
 
 	if updated && !dryRun {
 		if err := os.WriteFile(gitignorePath, []byte(gitignoreContent), 0644); err != nil {
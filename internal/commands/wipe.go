@@ -0,0 +1,193 @@
+package commands
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/neverprepared/shell-profile-manager/internal/config"
+)
+
+// wipeMethod describes how secure delete destroys a file, recorded in receipts
+const wipeMethod = "overwrite with random data, overwrite with zeros, fsync, unlink"
+
+// wipedFile records the fate of one file in a deletion receipt
+type wipedFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Status string `json:"status"` // "overwritten" or the error
+}
+
+// deletionReceipt is written after a secure delete as a record of what was destroyed
+type deletionReceipt struct {
+	Profile           string      `json:"profile"`
+	Path              string      `json:"path"`
+	Deleted           time.Time   `json:"deleted"`
+	User              string      `json:"user,omitempty"`
+	Host              string      `json:"host,omitempty"`
+	Method            string      `json:"method"`
+	SensitiveFiles    []wipedFile `json:"sensitive_files"`
+	SnapshotFiles     []wipedFile `json:"snapshot_files,omitempty"`
+	OtherFilesRemoved int         `json:"other_files_removed"`
+	Complete          bool        `json:"complete"`
+}
+
+// sensitiveFiles returns the regular files below profileDir that match the sensitive patterns,
+// as slash-separated paths relative to profileDir
+func sensitiveFiles(profileDir string) ([]string, error) {
+	files, err := collectFiles(profileDir, func(relPath string, info os.FileInfo) bool {
+		return info.IsDir() && (relPath == ".git" || relPath == "code")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var sensitive []string
+	for _, f := range files {
+		if !f.Dir && f.Link == "" && isSensitivePath(f.Path, false) {
+			sensitive = append(sensitive, f.Path)
+		}
+	}
+	return sensitive, nil
+}
+
+// overwriteFile overwrites a file with random data and then zeros, syncing each pass, and unlinks it.
+// Copy-on-write and flash storage may keep old blocks, so this is best effort below the filesystem.
+func overwriteFile(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return os.Remove(path)
+	}
+
+	// Make sure read-only files can be overwritten
+	if info.Mode().Perm()&0200 == 0 {
+		if err := os.Chmod(path, info.Mode().Perm()|0200); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+
+	for _, source := range []io.Reader{rand.Reader, zeroReader{}} {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			file.Close()
+			return err
+		}
+		if _, err := io.CopyN(file, source, info.Size()); err != nil {
+			file.Close()
+			return err
+		}
+		if err := file.Sync(); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	if err := file.Truncate(0); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+// wipeFiles overwrites the given files below root and reports the result for each
+func wipeFiles(root string, relPaths []string) ([]wipedFile, bool) {
+	complete := true
+	results := make([]wipedFile, 0, len(relPaths))
+	for _, relPath := range relPaths {
+		path := filepath.Join(root, filepath.FromSlash(relPath))
+		result := wipedFile{Path: relPath, Status: "overwritten"}
+		if info, err := os.Lstat(path); err == nil {
+			result.Size = info.Size()
+		}
+		if err := overwriteFile(path); err != nil {
+			result.Status = err.Error()
+			complete = false
+		}
+		results = append(results, result)
+	}
+	return results, complete
+}
+
+// wipeTree overwrites every regular file below root, then removes root
+func wipeTree(root string) ([]wipedFile, bool, error) {
+	files, err := collectFiles(root, nil)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, true, nil
+		}
+		return nil, false, err
+	}
+
+	var relPaths []string
+	for _, f := range files {
+		if !f.Dir && f.Link == "" {
+			relPaths = append(relPaths, f.Path)
+		}
+	}
+	sort.Strings(relPaths)
+
+	results, complete := wipeFiles(root, relPaths)
+	if complete {
+		if err := os.RemoveAll(root); err != nil {
+			return results, false, err
+		}
+	}
+	return results, complete, nil
+}
+
+// writeDeletionReceipt stores a receipt in the state directory and returns its path
+func writeDeletionReceipt(receipt deletionReceipt) (string, error) {
+	stateDir, err := config.GetStateDir()
+	if err != nil {
+		return "", err
+	}
+	receiptsDir := filepath.Join(stateDir, "receipts")
+	if err := os.MkdirAll(receiptsDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create receipts directory: %w", err)
+	}
+
+	if u, err := user.Current(); err == nil {
+		receipt.User = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		receipt.Host = host
+	}
+	receipt.Method = wipeMethod
+
+	data, err := json.MarshalIndent(receipt, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("%s_%s.json", receipt.Profile, receipt.Deleted.Local().Format(backupTimeFormat))
+	path := filepath.Join(receiptsDir, name)
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return "", fmt.Errorf("failed to write receipt: %w", err)
+	}
+	return path, nil
+}
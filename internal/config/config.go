@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -14,6 +15,15 @@ const (
 // Config holds the profile manager configuration
 type Config struct {
 	ProfilesDir string `json:"profiles_dir"`
+
+	// TemplatesDir holds user-defined profile templates, one directory each
+	TemplatesDir string `json:"templates_dir"`
+
+	// Snapshot retention: a snapshot is kept if it is one of the newest
+	// SnapshotKeep snapshots or younger than SnapshotKeepDays days.
+	// A value of 0 disables that rule; if both are 0 nothing is pruned.
+	SnapshotKeep     int `json:"snapshot_keep"`
+	SnapshotKeepDays int `json:"snapshot_keep_days"`
 }
 
 // GetConfigPath returns the path to the config file
@@ -44,8 +54,12 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	// Parse simple key=value format
-	config := &Config{}
+	// Parse simple key=value format, starting from the defaults
+	config, err := GetDefaultConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get default config: %w", err)
+	}
+	config.ProfilesDir = ""
 	lines := strings.Split(string(content), "\n")
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
@@ -65,6 +79,22 @@ func LoadConfig() (*Config, error) {
 		case "profiles_dir":
 			// Expand ~ in path
 			config.ProfilesDir = expandPath(value)
+		case "templates_dir":
+			if value != "" {
+				config.TemplatesDir = expandPath(value)
+			}
+		case "snapshot_keep":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid snapshot_keep value: %s", value)
+			}
+			config.SnapshotKeep = n
+		case "snapshot_keep_days":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid snapshot_keep_days value: %s", value)
+			}
+			config.SnapshotKeepDays = n
 		}
 	}
 
@@ -97,6 +127,10 @@ func SaveConfig(config *Config) error {
 	if strings.HasPrefix(profilesDir, homeDir) {
 		profilesDir = "~" + profilesDir[len(homeDir):]
 	}
+	templatesDir := config.TemplatesDir
+	if strings.HasPrefix(templatesDir, homeDir) {
+		templatesDir = "~" + templatesDir[len(homeDir):]
+	}
 
 	// Write config file
 	content := fmt.Sprintf(`# Profile Manager Configuration
@@ -104,7 +138,14 @@ func SaveConfig(config *Config) error {
 # You can edit this file manually if needed
 
 profiles_dir=%s
-`, profilesDir)
+
+# User-defined profile templates (one directory per template)
+templates_dir=%s
+
+# Snapshot retention (0 disables a rule)
+snapshot_keep=%d
+snapshot_keep_days=%d
+`, profilesDir, templatesDir, config.SnapshotKeep, config.SnapshotKeepDays)
 
 	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
@@ -113,6 +154,21 @@ profiles_dir=%s
 	return nil
 }
 
+// GetStateDir returns the per-user directory for state that must not live in
+// a profile (backups, snapshots): $XDG_STATE_HOME/shell-profiler, falling back
+// to ~/.local/state/shell-profiler
+func GetStateDir() (string, error) {
+	if stateHome := os.Getenv("XDG_STATE_HOME"); stateHome != "" && filepath.IsAbs(stateHome) {
+		return filepath.Join(stateHome, "shell-profiler"), nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".local", "state", "shell-profiler"), nil
+}
+
 // GetDefaultConfig returns the default configuration
 func GetDefaultConfig() (*Config, error) {
 	homeDir, err := os.UserHomeDir()
@@ -120,8 +176,16 @@ func GetDefaultConfig() (*Config, error) {
 		return nil, fmt.Errorf("failed to get home directory: %w", err)
 	}
 
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" || !filepath.IsAbs(configHome) {
+		configHome = filepath.Join(homeDir, ".config")
+	}
+
 	return &Config{
-		ProfilesDir: filepath.Join(homeDir, "workspaces", "profiles"),
+		ProfilesDir:      filepath.Join(homeDir, "workspaces", "profiles"),
+		TemplatesDir:     filepath.Join(configHome, "shell-profiler", "templates"),
+		SnapshotKeep:     10,
+		SnapshotKeepDays: 30,
 	}, nil
 }
 
@@ -0,0 +1,93 @@
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// sourcesDir holds the checkouts of template sources, inside the templates directory.
+	// It starts with a dot, so Discover does not take it for a template.
+	sourcesDir  = ".sources"
+	sourcesFile = "sources.json"
+)
+
+// Source is a git repository of templates, pinned to a commit
+type Source struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	Revision string `json:"revision"`
+	Updated  string `json:"updated"`
+}
+
+// SourceDir returns the directory a source is checked out in
+func SourceDir(userDir, name string) string {
+	return filepath.Join(userDir, sourcesDir, name)
+}
+
+// LoadSources returns the template sources registered in userDir
+func LoadSources(userDir string) ([]Source, error) {
+	if userDir == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(filepath.Join(userDir, sourcesDir, sourcesFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template sources: %w", err)
+	}
+	var sources []Source
+	if err := json.Unmarshal(content, &sources); err != nil {
+		return nil, fmt.Errorf("invalid template sources file: %w", err)
+	}
+	return sources, nil
+}
+
+// SaveSources writes the template sources registered in userDir
+func SaveSources(userDir string, sources []Source) error {
+	if err := os.MkdirAll(filepath.Join(userDir, sourcesDir), 0755); err != nil {
+		return fmt.Errorf("failed to create template sources directory: %w", err)
+	}
+	content, err := json.MarshalIndent(sources, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(userDir, sourcesDir, sourcesFile), append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write template sources: %w", err)
+	}
+	return nil
+}
+
+// sourceRoot returns the directory holding a source's templates: its templates/
+// directory if it has one, else the top of the repository
+func sourceRoot(userDir, name string) string {
+	dir := SourceDir(userDir, name)
+	if info, err := os.Stat(filepath.Join(dir, "templates")); err == nil && info.IsDir() {
+		return filepath.Join(dir, "templates")
+	}
+	return dir
+}
+
+// loadDir loads every template directory in dir. A missing dir is not an error.
+func loadDir(dir string) ([]*Template, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read templates directory: %w", err)
+	}
+	var loaded []*Template
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name()[0] == '.' {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		t, err := load(entry.Name(), path, os.DirFS(path))
+		if err != nil {
+			return nil, err
+		}
+		loaded = append(loaded, t)
+	}
+	return loaded, nil
+}
@@ -0,0 +1,486 @@
+// Package templates discovers and renders profile templates.
+//
+// A template is a directory of files that make up a new profile. Every file is rendered
+// with text/template, and a trailing .tmpl is removed from its name (so that templates
+// can hold files such as .gitignore without them affecting the repository they live
+// in). An optional template.json describes the template, names its parent and declares
+// the variables it asks for.
+//
+// Templates extend their parent, the built-in basic template unless the manifest names
+// another: a file replaces the parent's file at the same path, or is appended or
+// prepended to it when the manifest sets that mode for the path.
+//
+// Built-in templates are embedded in the binary; user templates are directories in the
+// configured templates directory and take precedence over built-ins of the same name.
+// Templates can also be pulled from git repositories (sources), which are checked out
+// below the templates directory and pinned to a commit.
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+//go:embed all:builtin
+var builtinFS embed.FS
+
+const (
+	// BaseTemplate is the parent of templates that do not name one
+	BaseTemplate = "basic"
+
+	// ManifestFile is the name of a template's manifest
+	ManifestFile = "template.json"
+	// Suffix is removed from the names of template files
+	Suffix = ".tmpl"
+
+	builtinName = "built-in"
+)
+
+// Ways a template file is combined with the parent's file at the same path
+const (
+	ModeReplace = "replace"
+	ModeAppend  = "append"
+	ModePrepend = "prepend"
+)
+
+// Template is a discovered profile template
+type Template struct {
+	Name        string
+	Description string
+	// Parent is the template this one extends, empty for the base template
+	Parent string
+	// Source is "built-in" or the template's directory
+	Source string
+	// SourceName and Revision identify the template source and commit the template comes
+	// from, for templates pulled from a git repository
+	SourceName string
+	Revision   string
+
+	fsys   fs.FS
+	modes  map[string]string
+	vars   []Variable
+	parent *Template
+	err    error
+}
+
+// Data holds the profile variables available to template files
+type Data struct {
+	Name        string // Profile name
+	Template    string // Template name
+	Path        string // Absolute profile directory
+	DisplayPath string // Profile directory with the home directory shortened to ~
+	GitName     string
+	GitEmail    string
+	Created     string // Creation time, UTC
+	// ToolEnv and ToolGitignore are the .env variables and .gitignore patterns of the
+	// profile's tools, generated from the tool registry
+	ToolEnv       string
+	ToolGitignore string
+	// Vars holds the values of the template's variables
+	Vars map[string]any
+}
+
+// File is a rendered template file
+type File struct {
+	Path    string // Slash-separated path inside the profile
+	Content []byte
+	Mode    fs.FileMode
+	// Layers are the templates the file was built from, outermost parent first
+	Layers []Layer
+}
+
+// Layer is one template's part of a rendered file
+type Layer struct {
+	Template string
+	Mode     string
+}
+
+// String describes the layer, e.g. "work (append)"
+func (l Layer) String() string {
+	if l.Mode == ModeReplace {
+		return l.Template
+	}
+	return fmt.Sprintf("%s (%s)", l.Template, l.Mode)
+}
+
+// Manifest is the content of a template's template.json
+type Manifest struct {
+	Description string            `json:"description,omitempty"`
+	Parent      string            `json:"parent,omitempty"`
+	Files       map[string]string `json:"files,omitempty"`
+	Variables   []VariableSpec    `json:"variables,omitempty"`
+}
+
+// Discover returns all templates, built-in and from userDir, sorted by name. A missing
+// userDir is not an error.
+func Discover(userDir string) ([]*Template, error) {
+	builtins := make(map[string]*Template)
+	entries, err := fs.ReadDir(builtinFS, "builtin")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read built-in templates: %w", err)
+	}
+	for _, entry := range entries {
+		sub, err := fs.Sub(builtinFS, path.Join("builtin", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		t, err := load(entry.Name(), builtinName, sub)
+		if err != nil {
+			return nil, err
+		}
+		builtins[t.Name] = t
+	}
+
+	// Templates from sources replace built-ins, and templates in userDir replace both
+	byName := make(map[string]*Template)
+	for name, t := range builtins {
+		byName[name] = t
+	}
+	sources, err := LoadSources(userDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, source := range sources {
+		loaded, err := loadDir(sourceRoot(userDir, source.Name))
+		if err != nil {
+			return nil, fmt.Errorf("template source %s: %w", source.Name, err)
+		}
+		for _, t := range loaded {
+			t.SourceName, t.Revision = source.Name, source.Revision
+			byName[t.Name] = t
+		}
+	}
+	if userDir != "" {
+		loaded, err := loadDir(userDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range loaded {
+			byName[t.Name] = t
+		}
+	}
+
+	// Link parents. A user template extending its own name extends the built-in it
+	// replaces. Problems are reported when the template is used, so that one broken
+	// template does not hide the others.
+	for _, t := range byName {
+		if t.Parent == "" {
+			continue
+		}
+		parent := byName[t.Parent]
+		if t.Parent == t.Name {
+			parent = builtins[t.Name]
+		}
+		if parent == nil {
+			t.err = fmt.Errorf("template %s extends unknown template %s", t.Name, t.Parent)
+			continue
+		}
+		t.parent = parent
+	}
+
+	templates := make([]*Template, 0, len(byName))
+	for _, t := range byName {
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, nil
+}
+
+// Find returns the template with the given name
+func Find(userDir, name string) (*Template, error) {
+	templates, err := Discover(userDir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, t := range templates {
+		if t.Name == name {
+			return t, nil
+		}
+		names = append(names, t.Name)
+	}
+	return nil, fmt.Errorf("unknown template: %s (available: %s)", name, strings.Join(names, ", "))
+}
+
+// load reads a template's manifest
+func load(name, source string, fsys fs.FS) (*Template, error) {
+	t := &Template{Name: name, Source: source, fsys: fsys}
+
+	var m Manifest
+	content, err := fs.ReadFile(fsys, ManifestFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s manifest: %w", name, err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(content, &m); err != nil {
+			return nil, fmt.Errorf("invalid manifest for template %s: %w", name, err)
+		}
+	}
+
+	t.Description = m.Description
+	t.Parent = m.Parent
+	if t.Parent == "" && (name != BaseTemplate || source != builtinName) {
+		t.Parent = BaseTemplate
+	}
+
+	t.modes = make(map[string]string)
+	for p, mode := range m.Files {
+		switch mode {
+		case ModeReplace, ModeAppend, ModePrepend:
+			t.modes[path.Clean(p)] = mode
+		default:
+			return nil, fmt.Errorf("invalid mode %q for %s in template %s (must be: replace, append, or prepend)", mode, p, name)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, spec := range m.Variables {
+		v, err := parseVariable(spec)
+		if err != nil {
+			return nil, fmt.Errorf("template %s: %w", name, err)
+		}
+		if seen[v.Name] {
+			return nil, fmt.Errorf("template %s declares variable %s twice", name, v.Name)
+		}
+		seen[v.Name] = true
+		t.vars = append(t.vars, v)
+	}
+	return t, nil
+}
+
+// Chain returns the names of the template and its ancestors, outermost parent first
+func (t *Template) Chain() ([]string, error) {
+	chain, err := t.chain()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(chain))
+	for i, c := range chain {
+		names[i] = c.Name
+	}
+	return names, nil
+}
+
+// chain returns the template and its ancestors, outermost parent first
+func (t *Template) chain() ([]*Template, error) {
+	var chain []*Template
+	seen := make(map[*Template]bool)
+	for c := t; c != nil; c = c.parent {
+		if c.err != nil {
+			return nil, c.err
+		}
+		if seen[c] {
+			return nil, fmt.Errorf("template %s has a cycle in its parents", t.Name)
+		}
+		seen[c] = true
+		chain = append([]*Template{c}, chain...)
+	}
+	return chain, nil
+}
+
+// ReadFile returns the unrendered content of one of the template's own files
+func (t *Template) ReadFile(name string) ([]byte, error) {
+	sources, err := t.sources()
+	if err != nil {
+		return nil, err
+	}
+	src, ok := sources[path.Clean(name)]
+	if !ok {
+		return nil, fmt.Errorf("template %s has no file %s", t.Name, name)
+	}
+	return fs.ReadFile(t.fsys, src)
+}
+
+// Render renders the files of the template merged with those of its ancestors, sorted
+// by path
+func (t *Template) Render(data Data) ([]File, error) {
+	layout, err := t.layout()
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]File, 0, len(layout))
+	for _, entry := range layout {
+		file, err := entry.render(data)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// RenderFile renders the single file Render would write at path
+func (t *Template) RenderFile(path string, data Data) (File, error) {
+	layout, err := t.layout()
+	if err != nil {
+		return File{}, err
+	}
+	for _, entry := range layout {
+		if entry.path == path {
+			return entry.render(data)
+		}
+	}
+	return File{}, fmt.Errorf("template %s has no file %s", t.Name, path)
+}
+
+// Layout returns the paths Render writes, each mapped to the layers it is built from
+func (t *Template) Layout() ([]string, map[string][]Layer, error) {
+	layout, err := t.layout()
+	if err != nil {
+		return nil, nil, err
+	}
+	paths := make([]string, 0, len(layout))
+	layers := make(map[string][]Layer, len(layout))
+	for _, entry := range layout {
+		paths = append(paths, entry.path)
+		for _, part := range entry.parts {
+			layers[entry.path] = append(layers[entry.path], Layer{Template: part.template.Name, Mode: part.mode})
+		}
+	}
+	return paths, layers, nil
+}
+
+// layoutEntry is an output file and the template files it is merged from
+type layoutEntry struct {
+	path  string
+	parts []layoutPart
+}
+
+type layoutPart struct {
+	template *Template
+	src      string
+	mode     string
+}
+
+// layout resolves which template files make up each output file, sorted by path
+func (t *Template) layout() ([]layoutEntry, error) {
+	chain, err := t.chain()
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make(map[string][]layoutPart)
+	for _, c := range chain {
+		sources, err := c.sources()
+		if err != nil {
+			return nil, err
+		}
+		for out, src := range sources {
+			mode, ok := c.modes[out]
+			if !ok {
+				mode = ModeReplace
+			}
+			part := layoutPart{template: c, src: src, mode: mode}
+			if mode == ModeReplace {
+				parts[out] = []layoutPart{part}
+			} else {
+				parts[out] = append(parts[out], part)
+			}
+		}
+	}
+
+	layout := make([]layoutEntry, 0, len(parts))
+	for out, p := range parts {
+		layout = append(layout, layoutEntry{path: out, parts: p})
+	}
+	sort.Slice(layout, func(i, j int) bool { return layout[i].path < layout[j].path })
+	return layout, nil
+}
+
+// render renders each part of the entry and merges them
+func (e layoutEntry) render(data Data) (File, error) {
+	file := File{Path: e.path}
+	var content string
+	for _, part := range e.parts {
+		rendered, err := part.template.render(part.src, data)
+		if err != nil {
+			return File{}, err
+		}
+		switch part.mode {
+		case ModeAppend:
+			content = joinBlocks(content, rendered)
+		case ModePrepend:
+			content = joinBlocks(rendered, content)
+		default:
+			content = rendered
+		}
+
+		info, err := fs.Stat(part.template.fsys, part.src)
+		if err != nil {
+			return File{}, err
+		}
+		file.Mode = fileMode(e.path, info.Mode())
+		file.Layers = append(file.Layers, Layer{Template: part.template.Name, Mode: part.mode})
+	}
+	file.Content = []byte(content)
+	return file, nil
+}
+
+// joinBlocks joins two blocks of lines with a blank line between them
+func joinBlocks(first, second string) string {
+	if first == "" {
+		return second
+	}
+	if second == "" {
+		return first
+	}
+	return strings.TrimRight(first, "\n") + "\n\n" + second
+}
+
+// sources maps the output path of each of the template's files to its path in the template
+func (t *Template) sources() (map[string]string, error) {
+	sources := make(map[string]string)
+	err := fs.WalkDir(t.fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || p == ManifestFile {
+			return nil
+		}
+		sources[strings.TrimSuffix(p, Suffix)] = p
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template %s: %w", t.Name, err)
+	}
+	return sources, nil
+}
+
+// render renders a single file of the template
+func (t *Template) render(src string, data Data) (string, error) {
+	content, err := fs.ReadFile(t.fsys, src)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s from template %s: %w", src, t.Name, err)
+	}
+	tmpl, err := template.New(src).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return "", fmt.Errorf("template %s: %w", t.Name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("template %s: %w", t.Name, err)
+	}
+	return buf.String(), nil
+}
+
+// fileMode returns the permissions a rendered file is written with: scripts in bin/ and
+// executable template files are executable, SSH and 1Password files are private
+func fileMode(p string, mode fs.FileMode) fs.FileMode {
+	switch {
+	case strings.HasPrefix(p, "bin/") || mode&0111 != 0:
+		return 0755
+	case strings.HasPrefix(p, ".ssh/") || strings.HasPrefix(p, ".config/1Password/"):
+		return 0600
+	default:
+		return 0644
+	}
+}
@@ -0,0 +1,203 @@
+package templates
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Variable types
+const (
+	TypeString = "string"
+	TypeBool   = "bool"
+	TypeInt    = "int"
+)
+
+var variableNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Variable is a value a template asks for when a profile is created, available to its
+// files as {{.Vars.<name>}}
+type Variable struct {
+	Name     string
+	Type     string // string, bool or int
+	Default  string
+	Pattern  string // Regular expression a string value must match
+	Help     string
+	Required bool
+
+	pattern *regexp.Regexp
+}
+
+// VariableSpec is a variable as declared in template.json. The default may be any JSON
+// scalar, so that bool and int variables can use true or 8080.
+type VariableSpec struct {
+	Name     string `json:"name"`
+	Type     string `json:"type,omitempty"`
+	Default  any    `json:"default,omitempty"`
+	Pattern  string `json:"pattern,omitempty"`
+	Help     string `json:"help,omitempty"`
+	Required bool   `json:"required,omitempty"`
+}
+
+// parseVariable checks a declared variable, including its default
+func parseVariable(spec VariableSpec) (Variable, error) {
+	v := Variable{
+		Name:     spec.Name,
+		Type:     spec.Type,
+		Pattern:  spec.Pattern,
+		Help:     spec.Help,
+		Required: spec.Required,
+	}
+	if !variableNameRe.MatchString(v.Name) {
+		return Variable{}, fmt.Errorf("invalid variable name %q (use letters, numbers and underscores)", v.Name)
+	}
+
+	switch v.Type {
+	case "":
+		v.Type = TypeString
+	case TypeString, TypeBool, TypeInt:
+	default:
+		return Variable{}, fmt.Errorf("invalid type %q for variable %s (must be: string, bool, or int)", v.Type, v.Name)
+	}
+
+	if v.Pattern != "" {
+		re, err := regexp.Compile(v.Pattern)
+		if err != nil {
+			return Variable{}, fmt.Errorf("invalid pattern for variable %s: %w", v.Name, err)
+		}
+		v.pattern = re
+	}
+
+	if spec.Default != nil {
+		s := fmt.Sprint(spec.Default)
+		if _, err := v.Parse(s); err != nil {
+			return Variable{}, fmt.Errorf("invalid default for variable %s: %w", v.Name, err)
+		}
+		v.Default = s
+	}
+	return v, nil
+}
+
+// Prompt returns the question to ask for the variable
+func (v Variable) Prompt() string {
+	if v.Help != "" {
+		return fmt.Sprintf("%s (%s):", v.Help, v.Name)
+	}
+	return v.Name + ":"
+}
+
+// Parse checks value against the variable's type and pattern and converts it to the
+// type's Go value
+func (v Variable) Parse(value string) (any, error) {
+	switch v.Type {
+	case TypeBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false, got %q", v.Name, value)
+		}
+		return b, nil
+	case TypeInt:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a whole number, got %q", v.Name, value)
+		}
+		return n, nil
+	default:
+		if v.pattern != nil && !v.pattern.MatchString(value) {
+			return nil, fmt.Errorf("%s must match %s, got %q", v.Name, v.Pattern, value)
+		}
+		return value, nil
+	}
+}
+
+// zero returns the value of a variable that was not set and has no default
+func (v Variable) zero() any {
+	switch v.Type {
+	case TypeBool:
+		return false
+	case TypeInt:
+		return 0
+	default:
+		return ""
+	}
+}
+
+// Variables returns the variables of the template and its ancestors, parents first. A
+// template redeclaring a parent's variable replaces it.
+func (t *Template) Variables() ([]Variable, error) {
+	chain, err := t.chain()
+	if err != nil {
+		return nil, err
+	}
+
+	var vars []Variable
+	index := make(map[string]int)
+	for _, c := range chain {
+		for _, v := range c.vars {
+			if i, ok := index[v.Name]; ok {
+				vars[i] = v
+				continue
+			}
+			index[v.Name] = len(vars)
+			vars = append(vars, v)
+		}
+	}
+	return vars, nil
+}
+
+// Missing returns the required variables that have neither a value in input nor a default
+func (t *Template) Missing(input map[string]string) ([]Variable, error) {
+	vars, err := t.Variables()
+	if err != nil {
+		return nil, err
+	}
+	var missing []Variable
+	for _, v := range vars {
+		if v.Required && input[v.Name] == "" && v.Default == "" {
+			missing = append(missing, v)
+		}
+	}
+	return missing, nil
+}
+
+// Values checks input against the template's variables and returns the value of every
+// variable for rendering: the input, else the default, else the type's zero value.
+func (t *Template) Values(input map[string]string) (map[string]any, error) {
+	vars, err := t.Variables()
+	if err != nil {
+		return nil, err
+	}
+
+	declared := make(map[string]bool, len(vars))
+	values := make(map[string]any, len(vars))
+	for _, v := range vars {
+		declared[v.Name] = true
+		value, ok := input[v.Name]
+		if !ok || value == "" {
+			value = v.Default
+		}
+		if value == "" {
+			values[v.Name] = v.zero()
+			continue
+		}
+		parsed, err := v.Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("template %s: %w", t.Name, err)
+		}
+		values[v.Name] = parsed
+	}
+
+	var unknown []string
+	for name := range input {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("template %s has no variable %s", t.Name, strings.Join(unknown, ", "))
+	}
+	return values, nil
+}
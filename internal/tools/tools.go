@@ -0,0 +1,369 @@
+// Package tools is the registry of the command-line tools a profile isolates.
+//
+// Each tool declares the directories it keeps inside the profile, the .env variables
+// that point it there, the .gitignore patterns that keep its secrets out of git and the
+// dotfiles it reads. Profile creation, update, the dotfiles command and the generated
+// .env and .gitignore are all driven from this registry.
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EnvVar is a variable a tool needs in the profile's .env
+type EnvVar struct {
+	Name  string
+	Value string
+	// Comment is written above the variable, one "# " line per line
+	Comment string
+	// Optional variables are written commented out, for the user to enable
+	Optional bool
+}
+
+// IgnoreGroup is a commented group of .gitignore patterns
+type IgnoreGroup struct {
+	Comment  string
+	Patterns []string
+}
+
+// Dotfile is a configuration file or directory a tool reads
+type Dotfile struct {
+	Path        string
+	Description string
+}
+
+// Tool is an isolated tool
+type Tool struct {
+	Name        string
+	Description string
+	Dirs        []string
+	Env         []EnvVar
+	Gitignore   []IgnoreGroup
+	Dotfiles    []Dotfile
+	// Files are generated files outside Dirs that only this tool uses
+	Files []string
+}
+
+// baseDirs are created in every profile, whatever its tools
+var baseDirs = []string{"bin", "code"}
+
+// baseGitignore keeps the profile's own secrets, credential stores that no tool isolates
+// and legacy backups out of git
+var baseGitignore = []IgnoreGroup{
+	{"Environment files with secrets", []string{".env", ".envrc.local"}},
+	{"Other credential stores", []string{".netrc", ".git-credentials", ".docker/config.json"}},
+	{"Legacy backups (may contain copies of .env)", []string{".backups/"}},
+}
+
+// baseDotfiles are the profile's own configuration files
+var baseDotfiles = []Dotfile{
+	{".envrc", "direnv configuration - environment variables"},
+	{".gitignore", "Git ignore patterns"},
+	{".env", "Environment variables (secrets)"},
+	{".env.example", "Environment variables template"},
+	{".envrc.local", "Local direnv overrides"},
+	{".shell-profiler.json", "Profile metadata - template, tools, description, tags"},
+}
+
+// registry lists the tools in the order their variables appear in .env
+var registry = []Tool{
+	{
+		Name:        "git",
+		Description: "Git (global config per profile)",
+		Env: []EnvVar{
+			{Name: "GIT_CONFIG_GLOBAL", Value: `"$WORKSPACE_HOME/.gitconfig"`, Comment: "Git configuration"},
+		},
+		Dotfiles: []Dotfile{{".gitconfig", "Git configuration - user name, email, aliases"}},
+	},
+	{
+		Name:        "ssh",
+		Description: "SSH client config and keys",
+		Dirs:        []string{".ssh"},
+		Env: []EnvVar{
+			{Name: "GIT_SSH_COMMAND", Value: `"ssh -F $WORKSPACE_HOME/.ssh/config"`, Comment: "SSH configuration\nUse workspace-specific SSH config instead of $HOME/.ssh/config"},
+		},
+		Gitignore: []IgnoreGroup{
+			{"SSH keys and sensitive files", []string{".ssh/id_*", ".ssh/*.pem", ".ssh/*.key", ".ssh/known_hosts", ".ssh/known_hosts.old"}},
+		},
+		Dotfiles: []Dotfile{{".ssh/config", "SSH client configuration"}},
+		Files:    []string{"bin/ssh"},
+	},
+	{
+		Name:        "xdg",
+		Description: "XDG config directory (.config) for XDG-compliant tools",
+		Env: []EnvVar{
+			{Name: "XDG_CONFIG_HOME", Value: `"$WORKSPACE_HOME/.config"`, Comment: "XDG Base Directory specification\nPoint all XDG-compliant tools to workspace-specific config"},
+		},
+	},
+	{
+		Name:        "1password",
+		Description: "1Password SSH agent",
+		Dirs:        []string{".config/1Password"},
+		Env: []EnvVar{
+			{Name: "SSH_AUTH_SOCK", Value: `"$HOME/Library/Group Containers/2BUA8C4S2C.com.1password/t/agent.sock"`, Comment: "1Password SSH Agent\nPoint to 1Password SSH agent socket for SSH key management"},
+		},
+		Dotfiles: []Dotfile{{".config/1Password/agent.toml", "1Password SSH agent configuration"}},
+	},
+	{
+		Name:        "aws",
+		Description: "AWS CLI and SDKs",
+		Dirs:        []string{".aws"},
+		Env: []EnvVar{
+			{Name: "AWS_CONFIG_FILE", Value: `"$WORKSPACE_HOME/.aws/config"`, Comment: "AWS configuration\nPoint AWS CLI and SDKs to workspace-specific config and credentials"},
+			{Name: "AWS_SHARED_CREDENTIALS_FILE", Value: `"$WORKSPACE_HOME/.aws/credentials"`},
+		},
+		Gitignore: []IgnoreGroup{
+			{"AWS credentials and sensitive config", []string{".aws/credentials", ".aws/cli/cache", ".aws/sso/cache"}},
+		},
+		Dotfiles: []Dotfile{
+			{".aws/config", "AWS CLI configuration"},
+			{".aws/credentials", "AWS credentials (secrets)"},
+		},
+	},
+	{
+		Name:        "kube",
+		Description: "Kubernetes (kubectl)",
+		Dirs:        []string{".kube"},
+		Env: []EnvVar{
+			{Name: "KUBECONFIG", Value: `"$WORKSPACE_HOME/.kube/config"`, Comment: "Kubernetes configuration\nPoint kubectl to workspace-specific kubeconfig"},
+		},
+		Gitignore: []IgnoreGroup{
+			{"Kubernetes (kubeconfig usually embeds tokens or client keys)", []string{".kube/config", ".kube/cache", ".kube/http-cache"}},
+		},
+		Dotfiles: []Dotfile{{".kube/config", "Kubernetes configuration"}},
+	},
+	{
+		Name:        "terraform",
+		Description: "Terraform and Terragrunt",
+		Env: []EnvVar{
+			{Name: "TF_CLI_CONFIG_FILE", Value: `"$WORKSPACE_HOME/.terraformrc"`, Comment: "Terraform configuration\nUse workspace-specific Terraform CLI config"},
+			{Name: "TF_PLUGIN_CACHE_DIR", Value: `"$WORKSPACE_HOME/.terraform.d/plugin-cache"`, Comment: "Optionally set workspace-specific plugin cache", Optional: true},
+		},
+		Gitignore: []IgnoreGroup{
+			{"Terraform", []string{
+				".terraform/", ".terraform.lock.hcl", "*.tfstate", "*.tfstate.*", "*.tfvars",
+				".terraform.d/plugin-cache/", ".terraform.d/checkpoint_cache", ".terraform.d/checkpoint_signature",
+			}},
+			{"Terragrunt", []string{".terragrunt-cache/", "*.tfplan"}},
+		},
+		Dotfiles: []Dotfile{{".terraformrc", "Terraform CLI configuration"}},
+	},
+	{
+		Name:        "azure",
+		Description: "Azure CLI",
+		Dirs:        []string{".azure"},
+		Env: []EnvVar{
+			{Name: "AZURE_CONFIG_DIR", Value: `"$WORKSPACE_HOME/.azure"`, Comment: "Azure CLI configuration\nPoint Azure CLI to workspace-specific config directory"},
+		},
+		Gitignore: []IgnoreGroup{
+			{"Azure CLI credentials and sensitive config", []string{
+				".azure/config", ".azure/clouds.config", ".azure/accessTokens.json",
+				".azure/msal_token_cache.*", ".azure/azureProfile.json", ".azure/service_principal_entries.*",
+			}},
+		},
+		Dotfiles: []Dotfile{
+			{".azure/config", "Azure CLI configuration"},
+			{".azure/clouds.config", "Azure CLI cloud configuration"},
+		},
+	},
+	{
+		Name:        "gcloud",
+		Description: "Google Cloud SDK (gcloud)",
+		Dirs:        []string{".gcloud"},
+		Env: []EnvVar{
+			{Name: "CLOUDSDK_CONFIG", Value: `"$WORKSPACE_HOME/.gcloud"`, Comment: "Google Cloud SDK configuration\nPoint gcloud CLI to workspace-specific config directory"},
+		},
+		Gitignore: []IgnoreGroup{
+			{"Google Cloud SDK credentials and sensitive config", []string{
+				".gcloud/configurations/", ".gcloud/credentials", ".gcloud/credentials.db", ".gcloud/access_tokens.db",
+				".gcloud/application_default_credentials.json", ".gcloud/legacy_credentials/", ".gcloud/logs/",
+			}},
+		},
+		Dotfiles: []Dotfile{
+			{".gcloud/configurations", "Google Cloud SDK configurations"},
+			{".gcloud/credentials", "Google Cloud SDK credentials"},
+		},
+	},
+	{
+		Name:        "claude",
+		Description: "Claude Code",
+		Dirs:        []string{".config/claude"},
+		Env: []EnvVar{
+			{Name: "CLAUDE_CONFIG_DIR", Value: `"$WORKSPACE_HOME/.config/claude"`, Comment: "Claude Code configuration\nPoint Claude Code to workspace-specific config directory"},
+		},
+		Gitignore: []IgnoreGroup{
+			{"Claude Code configuration (may contain API keys and sensitive data)", []string{".config/claude/"}},
+		},
+		Dotfiles: []Dotfile{{".config/claude", "Claude Code configuration"}},
+	},
+	{
+		Name:        "gemini",
+		Description: "Gemini CLI",
+		Dirs:        []string{".config/gemini"},
+		Env: []EnvVar{
+			{Name: "GEMINI_CONFIG_DIR", Value: `"$WORKSPACE_HOME/.config/gemini"`, Comment: "Gemini CLI configuration\nPoint Gemini CLI to workspace-specific config directory"},
+		},
+		Gitignore: []IgnoreGroup{
+			{"Gemini CLI configuration (may contain API keys and sensitive data)", []string{".config/gemini/"}},
+		},
+		Dotfiles: []Dotfile{{".config/gemini", "Gemini CLI configuration"}},
+	},
+}
+
+// All returns every registered tool
+func All() []Tool {
+	return registry
+}
+
+// Names returns the names of all registered tools
+func Names() []string {
+	return ToolNames(registry)
+}
+
+// Find returns the tool with the given name
+func Find(name string) (Tool, error) {
+	for _, t := range registry {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	return Tool{}, fmt.Errorf("unknown tool: %s (available: %s)", name, strings.Join(Names(), ", "))
+}
+
+// Select returns the named tools, in registry order
+func Select(names []string) ([]Tool, error) {
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		if _, err := Find(name); err != nil {
+			return nil, err
+		}
+		wanted[name] = true
+	}
+	var selected []Tool
+	for _, t := range registry {
+		if wanted[t.Name] {
+			selected = append(selected, t)
+		}
+	}
+	return selected, nil
+}
+
+// ToolNames returns the names of the given tools
+func ToolNames(tools []Tool) []string {
+	names := make([]string, len(tools))
+	for i, t := range tools {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// Owns reports whether a profile file, by slash-separated path relative to the profile,
+// belongs to the tool: it is in one of the tool's directories, or is one of its dotfiles
+// or generated files
+func (t Tool) Owns(path string) bool {
+	for _, dir := range t.Dirs {
+		if path == dir || strings.HasPrefix(path, dir+"/") {
+			return true
+		}
+	}
+	for _, d := range t.Dotfiles {
+		if path == d.Path {
+			return true
+		}
+	}
+	for _, f := range t.Files {
+		if path == f {
+			return true
+		}
+	}
+	return false
+}
+
+// Dirs returns the directories a profile with the given tools needs
+func Dirs(tools []Tool) []string {
+	var dirs []string
+	for _, t := range tools {
+		dirs = append(dirs, t.Dirs...)
+	}
+	return append(dirs, baseDirs...)
+}
+
+// EnvVars returns the .env variables of the given tools
+func EnvVars(tools []Tool) []EnvVar {
+	var vars []EnvVar
+	for _, t := range tools {
+		vars = append(vars, t.Env...)
+	}
+	return vars
+}
+
+// Gitignore returns the .gitignore groups a profile with the given tools needs
+func Gitignore(tools []Tool) []IgnoreGroup {
+	groups := []IgnoreGroup{baseGitignore[0]}
+	for _, t := range tools {
+		groups = append(groups, t.Gitignore...)
+	}
+	return append(groups, baseGitignore[1:]...)
+}
+
+// Dotfiles returns the known dotfiles of a profile with the given tools
+func Dotfiles(tools []Tool) []Dotfile {
+	dotfiles := append([]Dotfile(nil), baseDotfiles...)
+	for _, t := range tools {
+		dotfiles = append(dotfiles, t.Dotfiles...)
+	}
+	return dotfiles
+}
+
+// RenderEnv returns the .env lines for the tools' variables: a block per tool, each
+// starting with a blank line
+func RenderEnv(tools []Tool) string {
+	var b strings.Builder
+	for _, t := range tools {
+		for i, v := range t.Env {
+			if i == 0 {
+				b.WriteString("\n")
+			}
+			b.WriteString(v.Render())
+		}
+	}
+	return b.String()
+}
+
+// Render returns the variable's .env lines, including its comment
+func (v EnvVar) Render() string {
+	var b strings.Builder
+	if v.Comment != "" {
+		for _, line := range strings.Split(v.Comment, "\n") {
+			b.WriteString("# " + line + "\n")
+		}
+	}
+	if v.Optional {
+		b.WriteString("# ")
+	}
+	b.WriteString(v.Name + "=" + v.Value + "\n")
+	return b.String()
+}
+
+// RenderGitignore returns the .gitignore groups for the tools, each preceded by a blank line
+func RenderGitignore(tools []Tool) string {
+	var b strings.Builder
+	for _, g := range Gitignore(tools) {
+		b.WriteString("\n")
+		b.WriteString(g.Render())
+	}
+	return b.String()
+}
+
+// Render returns the group's comment and patterns
+func (g IgnoreGroup) Render() string {
+	var b strings.Builder
+	if g.Comment != "" {
+		b.WriteString("# " + g.Comment + "\n")
+	}
+	for _, p := range g.Patterns {
+		b.WriteString(p + "\n")
+	}
+	return b.String()
+}
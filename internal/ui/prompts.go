@@ -26,18 +26,33 @@ func SelectProfile(profiles []string, message string) (string, error) {
 	return selected, nil
 }
 
-// SelectTemplate prompts the user to select a template
-func SelectTemplate() (string, error) {
-	var selected string
+// SelectTemplate prompts the user to select a template from names, shown with their
+// descriptions
+func SelectTemplate(names, descriptions []string, defaultName string) (string, error) {
+	if len(names) == 0 {
+		return "", fmt.Errorf("no templates available")
+	}
+
+	options := make([]string, len(names))
+	defaultOption := ""
+	for i, name := range names {
+		options[i] = name
+		if descriptions[i] != "" {
+			options[i] = name + " - " + descriptions[i]
+		}
+		if name == defaultName {
+			defaultOption = options[i]
+		}
+	}
+	if defaultOption == "" {
+		defaultOption = options[0]
+	}
+
+	var selected int
 	prompt := &survey.Select{
 		Message: "Select template:",
-		Options: []string{
-			"basic - Minimal configuration",
-			"personal - Personal projects",
-			"work - Work projects",
-			"client - Client projects",
-		},
-		Default: "basic - Minimal configuration",
+		Options: options,
+		Default: defaultOption,
 	}
 
 	err := survey.AskOne(prompt, &selected)
@@ -45,17 +60,7 @@ func SelectTemplate() (string, error) {
 		return "", err
 	}
 
-	// Extract template name from selection
-	switch selected {
-	case "personal - Personal projects":
-		return "personal", nil
-	case "work - Work projects":
-		return "work", nil
-	case "client - Client projects":
-		return "client", nil
-	default:
-		return "basic", nil
-	}
+	return names[selected], nil
 }
 
 // Input prompts the user for text input
@@ -90,13 +95,16 @@ func Confirm(message string, defaultVal bool) (bool, error) {
 	return result, nil
 }
 
-// MultiSelect prompts the user to select multiple options
-func MultiSelect(message string, options []string) ([]string, error) {
-	var selected []string
+// MultiSelect prompts the user to select multiple options, starting with defaults selected
+func MultiSelect(message string, options, defaults []string) ([]string, error) {
+	selected := []string{}
 	prompt := &survey.MultiSelect{
 		Message: message,
 		Options: options,
 	}
+	if len(defaults) > 0 {
+		prompt.Default = defaults
+	}
 
 	err := survey.AskOne(prompt, &selected)
 	if err != nil {
@@ -105,3 +113,18 @@ func MultiSelect(message string, options []string) ([]string, error) {
 
 	return selected, nil
 }
+
+// Password prompts the user for input without echoing it
+func Password(message string) (string, error) {
+	var result string
+	prompt := &survey.Password{
+		Message: message,
+	}
+
+	err := survey.AskOne(prompt, &result)
+	if err != nil {
+		return "", err
+	}
+
+	return result, nil
+}